@@ -0,0 +1,95 @@
+// 通行密钥: 服务端选项中的二进制字段为base64url, 需与ArrayBuffer互转
+(function (window) {
+    function decode(s) {
+        s = s.replace(/-/g, '+').replace(/_/g, '/');
+        while (s.length % 4) {
+            s += '=';
+        }
+        var bin = atob(s), buf = new Uint8Array(bin.length);
+        for (var i = 0; i < bin.length; i++) {
+            buf[i] = bin.charCodeAt(i);
+        }
+        return buf.buffer;
+    }
+
+    function encode(buf) {
+        var bytes = new Uint8Array(buf), bin = '';
+        for (var i = 0; i < bytes.length; i++) {
+            bin += String.fromCharCode(bytes[i]);
+        }
+        return btoa(bin).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+    }
+
+    function request(url, body) {
+        var headers = {'Content-Type': 'application/json'},
+            meta = document.querySelector('meta[name=csrf-token]');
+        if (meta) {
+            headers['X-CSRF-Token'] = meta.getAttribute('content');
+        }
+        return fetch(url, {
+            method: 'POST',
+            credentials: 'same-origin',
+            headers: headers,
+            body: body ? JSON.stringify(body) : null
+        }).then(function (resp) {
+            return resp.json().then(function (data) {
+                if (!resp.ok) {
+                    throw new Error(data.error || resp.statusText);
+                }
+                return data;
+            });
+        });
+    }
+
+    window.EiWebAuthn = {
+        supported: !!(window.PublicKeyCredential && navigator.credentials),
+
+        // register 注册通行密钥
+        register: function (name) {
+            return request('/admin/api/passkey-register-begin').then(function (options) {
+                var pk = options.publicKey;
+                pk.challenge = decode(pk.challenge);
+                pk.user.id = decode(pk.user.id);
+                (pk.excludeCredentials || []).forEach(function (c) {
+                    c.id = decode(c.id);
+                });
+                return navigator.credentials.create({publicKey: pk});
+            }).then(function (cred) {
+                return request('/admin/api/passkey-register-finish?name=' + encodeURIComponent(name), {
+                    id: cred.id,
+                    rawId: encode(cred.rawId),
+                    type: cred.type,
+                    response: {
+                        attestationObject: encode(cred.response.attestationObject),
+                        clientDataJSON: encode(cred.response.clientDataJSON),
+                        transports: cred.response.getTransports ? cred.response.getTransports() : []
+                    }
+                });
+            });
+        },
+
+        // login 使用通行密钥登录
+        login: function () {
+            return request('/admin/webauthn/login-begin').then(function (options) {
+                var pk = options.publicKey;
+                pk.challenge = decode(pk.challenge);
+                (pk.allowCredentials || []).forEach(function (c) {
+                    c.id = decode(c.id);
+                });
+                return navigator.credentials.get({publicKey: pk});
+            }).then(function (cred) {
+                return request('/admin/webauthn/login-finish', {
+                    id: cred.id,
+                    rawId: encode(cred.rawId),
+                    type: cred.type,
+                    response: {
+                        authenticatorData: encode(cred.response.authenticatorData),
+                        clientDataJSON: encode(cred.response.clientDataJSON),
+                        signature: encode(cred.response.signature),
+                        userHandle: cred.response.userHandle ? encode(cred.response.userHandle) : null
+                    }
+                });
+            });
+        }
+    };
+})(window);
@@ -2,17 +2,30 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/core/backup/ping"
 	"github.com/eiblog/eiblog/pkg/core/backup/swag"
 	"github.com/eiblog/eiblog/pkg/core/backup/timer"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/pkg/mid"
+	"github.com/eiblog/eiblog/pkg/server"
+	"github.com/eiblog/eiblog/pkg/trace"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout 关闭时等待请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
 var restore bool
 
 func init() {
@@ -20,32 +33,82 @@ func init() {
 }
 
 func main() {
-	fmt.Println("Hi, it's App " + config.Conf.BackupApp.Name)
+	logrus.Info("Backup start, app name " + config.Conf.BackupApp.Name)
 	flag.Parse()
+	flushTrace, err := trace.Init(config.Conf.Trace, config.Conf.BackupApp.Name)
+	if err != nil {
+		logrus.Error("trace.Init: ", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	endRun := make(chan error, 3)
 
-	endRun := make(chan error, 1)
+	done := runCommand(ctx, restore, endRun)
 
-	runCommand(restore, endRun)
+	servers := runHTTPServer(endRun)
+	metricsSrv := runMetricsServer(endRun)
+	select {
+	case err := <-endRun:
+		logrus.Error("Backup exit: ", err)
+	case <-ctx.Done():
+		logrus.Info("Backup shutting down")
+	}
+	shutdown(cancel, done, flushTrace, append(servers, metricsSrv)...)
+}
 
-	runHTTPServer(endRun)
-	fmt.Println(<-endRun)
+// shutdown 停止接收请求, 等待备份任务结束: 进行中的备份被终止并清理临时文件, 最后导出剩余的span
+func shutdown(cancel context.CancelFunc, done chan struct{},
+	flushTrace func(context.Context) error, servers ...*http.Server) {
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelTimeout()
+	for _, srv := range servers {
+		if srv == nil {
+			continue
+		}
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			logrus.Error("shutdown.Shutdown: ", err)
+		}
+	}
+	cancel()
+	<-done
+	err := flushTrace(ctx)
+	if err != nil {
+		logrus.Error("shutdown.flushTrace: ", err)
+	}
+	logrus.Info("Backup stopped")
 }
 
-func runCommand(restore bool, endRun chan error) {
+func runCommand(ctx context.Context, restore bool, endRun chan error) chan struct{} {
+	done := make(chan struct{})
 	go func() {
-		endRun <- timer.Start(restore)
+		defer close(done)
+		err := timer.Start(ctx, restore)
+		if err != nil {
+			endRun <- err
+		}
 	}()
+	return done
 }
 
-func runHTTPServer(endRun chan error) {
+func runHTTPServer(endRun chan error) []*http.Server {
 	if !config.Conf.BackupApp.EnableHTTP {
-		return
+		return nil
 	}
 
 	if config.Conf.RunMode == config.ModeProd {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	e := gin.Default()
+	e := newEngine()
+	// metrics
+	if conf := config.Conf.BackupApp; conf.Metrics.Enable {
+		e.Use(metrics.Middleware())
+		if conf.Metrics.Port == 0 || conf.Metrics.Port == conf.HTTPPort {
+			e.GET("/metrics", gin.WrapH(metrics.Handler()))
+		}
+	}
 
 	// swag
 	swag.RegisterRoutes(e)
@@ -54,9 +117,42 @@ func runHTTPServer(endRun chan error) {
 	ping.RegisterRoutes(e)
 
 	// start
-	address := fmt.Sprintf(":%d", config.Conf.BackupApp.HTTPPort)
-	go func() {
-		endRun <- e.Run(address)
-	}()
-	fmt.Println("HTTP server running on: " + address)
+	servers, err := server.Run(config.Conf.BackupApp.Mode, e, endRun)
+	if err != nil {
+		endRun <- err
+	}
+	return servers
+}
+
+// newEngine 替代gin.Default, 使用请求ID, 访问日志及panic恢复中间件
+func newEngine() *gin.Engine {
+	e := gin.New()
+	// 仅信任配置的代理传递的客户端IP, 避免伪造登录及访问IP
+	err := e.SetTrustedProxies(config.Conf.BackupApp.TrustedProxies)
+	if err != nil {
+		logrus.Error("newEngine.SetTrustedProxies: ", err)
+	}
+	e.Use(mid.RequestIDMiddleware())
+	if config.Conf.Trace.Enable {
+		e.Use(trace.Middleware(config.Conf.BackupApp.Name)...)
+	}
+	if conf := config.Conf.Log; conf.Access != mid.AccessFormatOff {
+		e.Use(mid.AccessLogMiddleware(mid.AccessLogOpts{
+			Format: conf.Access,
+			JSON:   conf.Format == "json",
+			Skip:   []string{"/healthz", "/readyz", "/metrics"},
+		}))
+	}
+	e.Use(mid.RecoveryMiddleware())
+	return e
+}
+
+// runMetricsServer 在单独的端口提供 /metrics
+func runMetricsServer(endRun chan error) *http.Server {
+	conf := config.Conf.BackupApp
+	if !conf.Metrics.Enable || conf.Metrics.Port == 0 || conf.Metrics.Port == conf.HTTPPort {
+		return nil
+	}
+	logrus.Infof("Metrics server running on: :%d", conf.Metrics.Port)
+	return metrics.Serve(conf.Metrics.Port, endRun)
 }
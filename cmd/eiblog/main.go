@@ -2,59 +2,134 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/core/eiblog"
 	"github.com/eiblog/eiblog/pkg/core/eiblog/admin"
 	"github.com/eiblog/eiblog/pkg/core/eiblog/file"
 	"github.com/eiblog/eiblog/pkg/core/eiblog/page"
+	"github.com/eiblog/eiblog/pkg/core/eiblog/ping"
+	"github.com/eiblog/eiblog/pkg/core/eiblog/rpc"
 	"github.com/eiblog/eiblog/pkg/core/eiblog/swag"
+	"github.com/eiblog/eiblog/pkg/metrics"
 	"github.com/eiblog/eiblog/pkg/mid"
+	"github.com/eiblog/eiblog/pkg/server"
+	"github.com/eiblog/eiblog/pkg/trace"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/zh-five/xdaemon"
-	"path/filepath"
+	"google.golang.org/grpc"
 )
 
+// shutdownTimeout 关闭时等待请求及后台任务完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	d := flag.Bool("d", false, "run in background")
-	logFile := flag.String("l", "eiblog.log", "log path")
+	logFile := flag.String("l", "eiblog.log", "stdout and stderr path when run in background, see log.file in app.yml")
 	flag.Parse()
 
 	if *d {
 		_, err := xdaemon.Background(*logFile, true)
 		if err != nil {
-			fmt.Println(err)
+			logrus.Error("xdaemon.Background: ", err)
 			return
 		}
 	}
 
 	logrus.Info("EiBlog start, app name " + config.Conf.EiBlogApp.Name)
+	flushTrace, err := trace.Init(config.Conf.Trace, config.Conf.EiBlogApp.Name)
+	if err != nil {
+		logrus.Error("trace.Init: ", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	endRun := make(chan error, 3)
+
+	runReloader()
+	servers := runHTTPServer(endRun)
+	grpcSrv := runGRPCServer(endRun)
+	metricsSrv := runMetricsServer(endRun)
+	select {
+	case err := <-endRun:
+		logrus.Error("EiBlog exit: ", err)
+	case <-ctx.Done():
+		logrus.Info("EiBlog shutting down")
+	}
+	shutdown(flushTrace, grpcSrv, append(servers, metricsSrv)...)
+}
 
-	endRun := make(chan error, 1)
+// shutdown 停止接收请求并等待处理中的请求完成, 停止定时任务, 等待后台任务完成后关闭存储,
+// 最后导出剩余的span
+func shutdown(flushTrace func(context.Context) error, grpcSrv *grpc.Server, servers ...*http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	runHTTPServer(endRun)
-	fmt.Println("final2")
-	fmt.Println(<-endRun)
-	fmt.Println("final3")
+	server.StopGRPC(ctx, grpcSrv)
+	for _, srv := range servers {
+		if srv == nil {
+			continue
+		}
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			logrus.Error("shutdown.Shutdown: ", err)
+		}
+	}
+	file.Stop()
+	err := cache.Ei.Close(ctx)
+	if err != nil {
+		logrus.Error("shutdown.Close: ", err)
+	}
+	err = flushTrace(ctx)
+	if err != nil {
+		logrus.Error("shutdown.flushTrace: ", err)
+	}
+	logrus.Info("EiBlog stopped")
 }
 
-func runHTTPServer(endRun chan error) {
+func runHTTPServer(endRun chan error) []*http.Server {
 	if !config.Conf.EiBlogApp.EnableHTTP {
-		return
+		return nil
 	}
 
 	if config.Conf.RunMode == config.ModeProd {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	e := gin.Default()
+	e := newEngine()
+	// compress, 后台页面含CSRF令牌, 不压缩
+	if encodings := eiblog.CompressEncodings(); len(encodings) > 0 {
+		e.Use(mid.CompressMiddleware(mid.CompressOpts{
+			Encodings: encodings,
+			Skip:      []string{"/admin"},
+		}))
+	}
+	// metrics
+	if conf := config.Conf.EiBlogApp; conf.Metrics.Enable {
+		e.Use(metrics.Middleware())
+		if conf.Metrics.Port == 0 || conf.Metrics.Port == conf.HTTPPort {
+			e.GET("/metrics", gin.WrapH(metrics.Handler()))
+		}
+	}
+	// health check, 无需会话
+	ping.RegisterRoutes(e)
 	// middleware
 	e.Use(mid.UserMiddleware())
 	e.Use(mid.SessionMiddleware(mid.SessionOpts{
 		Name:   "su",
-		Secure: config.Conf.RunMode == config.ModeProd,
-		Secret: []byte("ZGlzvcmUoMTAsICI="),
+		Secure: config.Conf.RunMode == config.ModeProd || config.Conf.EiBlogApp.TLS.Enable,
+		Secret: cache.Ei.SessionSecret(),
+		MaxAge: int(cache.SessionMaxAge().Seconds()),
 	}))
 
 	// swag
@@ -62,7 +137,7 @@ func runHTTPServer(endRun chan error) {
 
 	// static files, page
 	root := filepath.Join(config.WorkDir, "assets")
-	e.Static("/static", root)
+	e.Group("/static", eiblog.CacheStatic()).Static("/", root)
 
 	// static files
 	file.RegisterRoutes(e)
@@ -72,16 +147,65 @@ func runHTTPServer(endRun chan error) {
 	admin.RegisterRoutes(e)
 
 	// admin router
-	group := e.Group("/admin", eiblog.AuthFilter)
+	group := e.Group("/admin", eiblog.AuthFilter, mid.CSRFMiddleware(mid.CSRFOpts{}))
 	{
 		page.RegisterRoutesAuthz(group)
 		admin.RegisterRoutesAuthz(group)
 	}
 
 	// start
-	address := fmt.Sprintf(":%d", config.Conf.EiBlogApp.HTTPPort)
-	go func() {
-		endRun <- e.Run(address)
-	}()
-	fmt.Println("HTTP server running on: " + address)
+	servers, err := server.Run(config.Conf.EiBlogApp.Mode, e, endRun)
+	if err != nil {
+		endRun <- err
+	}
+	return servers
+}
+
+// runGRPCServer 内容管理gRPC API
+func runGRPCServer(endRun chan error) *grpc.Server {
+	if !config.Conf.EiBlogApp.EnableGRPC {
+		return nil
+	}
+	srv, err := server.RunGRPC(config.Conf.EiBlogApp.Mode, server.GRPCOpts{
+		Tokens:    func() map[string]string { return config.Current().EiBlogApp.GRPC.Tokens },
+		Register:  rpc.Register,
+		AccessLog: config.Conf.Log.Access != mid.AccessFormatOff,
+	}, endRun)
+	if err != nil {
+		endRun <- err
+	}
+	return srv
+}
+
+// newEngine 替代gin.Default, 使用请求ID, 访问日志及panic恢复中间件
+func newEngine() *gin.Engine {
+	e := gin.New()
+	// 仅信任配置的代理传递的客户端IP, 避免伪造登录及访问IP
+	err := e.SetTrustedProxies(config.Conf.EiBlogApp.TrustedProxies)
+	if err != nil {
+		logrus.Error("newEngine.SetTrustedProxies: ", err)
+	}
+	e.Use(mid.RequestIDMiddleware())
+	if config.Conf.Trace.Enable {
+		e.Use(trace.Middleware(config.Conf.EiBlogApp.Name)...)
+	}
+	if conf := config.Conf.Log; conf.Access != mid.AccessFormatOff {
+		e.Use(mid.AccessLogMiddleware(mid.AccessLogOpts{
+			Format: conf.Access,
+			JSON:   conf.Format == "json",
+			Skip:   []string{"/healthz", "/readyz", "/metrics"},
+		}))
+	}
+	e.Use(mid.RecoveryMiddleware())
+	return e
+}
+
+// runMetricsServer 在单独的端口提供 /metrics
+func runMetricsServer(endRun chan error) *http.Server {
+	conf := config.Conf.EiBlogApp
+	if !conf.Metrics.Enable || conf.Metrics.Port == 0 || conf.Metrics.Port == conf.HTTPPort {
+		return nil
+	}
+	logrus.Infof("Metrics server running on: :%d", conf.Metrics.Port)
+	return metrics.Serve(conf.Metrics.Port, endRun)
 }
@@ -0,0 +1,91 @@
+// Package main provides ...
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/core/eiblog/file"
+	"github.com/eiblog/eiblog/pkg/core/eiblog/page"
+
+	"github.com/sirupsen/logrus"
+)
+
+// watchInterval 开发模式下检查文件变化的间隔
+const watchInterval = time.Second
+
+// runReloader 收到SIGHUP时重新加载配置及模版, 开发模式下文件变化时自动重新加载
+func runReloader() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			reload("SIGHUP")
+		}
+	}()
+	if config.Conf.RunMode == config.ModeDev {
+		go watchFiles()
+	}
+}
+
+// reload 重新加载配置及模版, 校验或解析失败时保留原配置及模版
+func reload(reason string) {
+	logrus.Info("reload: ", reason)
+	restart, err := config.Reload()
+	if err != nil {
+		logrus.Error("reload: config.Reload: ", err)
+	} else if len(restart) > 0 {
+		logrus.Warn("reload: restart required for ", strings.Join(restart, ", "))
+	}
+	err = page.ReloadTemplates()
+	if err != nil {
+		logrus.Error("reload: page.ReloadTemplates: ", err)
+	}
+	err = file.ReloadTemplates()
+	if err != nil {
+		logrus.Error("reload: file.ReloadTemplates: ", err)
+	}
+	// 使前台页面的ETag及页面缓存失效
+	cache.Ei.Touch()
+	cache.Ei.Pages.PurgeAll()
+}
+
+// watchFiles 轮询配置文件及website目录, 有变化时重新加载
+func watchFiles() {
+	last := snapshot()
+	for range time.Tick(watchInterval) {
+		now := snapshot()
+		if now != last {
+			last = now
+			reload("file changed")
+		}
+	}
+}
+
+// snapshot 配置文件及模版的修改时间和大小
+func snapshot() string {
+	var b strings.Builder
+	stat := func(path string) {
+		fi, err := os.Stat(path)
+		if err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", path, fi.ModTime().UnixNano(), fi.Size())
+		}
+	}
+	stat(config.File)
+	root := filepath.Join(config.WorkDir, "website")
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			stat(path)
+		}
+		return nil
+	})
+	return b.String()
+}
@@ -0,0 +1,186 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 审计操作
+const (
+	AuditLogin            = "login"
+	AuditLoginFailed      = "login.failed"
+	AuditArticleCreate    = "article.create"
+	AuditArticleUpdate    = "article.update"
+	AuditArticlePublish   = "article.publish"
+	AuditArticleTrash     = "article.trash"
+	AuditArticleRestore   = "article.restore"
+	AuditArticlePurge     = "article.purge"
+	AuditSerieCreate      = "serie.create"
+	AuditSerieUpdate      = "serie.update"
+	AuditSerieDelete      = "serie.delete"
+	AuditBloggerUpdate    = "blogger.update"
+	AuditAccountUpdate    = "account.update"
+	AuditAccountPassword  = "account.password"
+	AuditAccountTOTP      = "account.totp"
+	AuditPasswordReset    = "account.reset"
+	AuditSessionRevoke    = "session.revoke"
+	AuditPasskeyCreate    = "passkey.create"
+	AuditPasskeyUpdate    = "passkey.update"
+	AuditPasskeyDelete    = "passkey.delete"
+	AuditFileUpload       = "file.upload"
+	AuditFileDelete       = "file.delete"
+	AuditWebhookCreate    = "webhook.create"
+	AuditWebhookUpdate    = "webhook.update"
+	AuditWebhookDelete    = "webhook.delete"
+	AuditSubscriberDelete = "subscriber.delete"
+)
+
+// AuditActions 所有审计操作, 用于后台筛选
+var AuditActions = []string{
+	AuditLogin,
+	AuditLoginFailed,
+	AuditArticleCreate,
+	AuditArticleUpdate,
+	AuditArticlePublish,
+	AuditArticleTrash,
+	AuditArticleRestore,
+	AuditArticlePurge,
+	AuditSerieCreate,
+	AuditSerieUpdate,
+	AuditSerieDelete,
+	AuditBloggerUpdate,
+	AuditAccountUpdate,
+	AuditAccountPassword,
+	AuditAccountTOTP,
+	AuditPasswordReset,
+	AuditSessionRevoke,
+	AuditPasskeyCreate,
+	AuditPasskeyUpdate,
+	AuditPasskeyDelete,
+	AuditFileUpload,
+	AuditFileDelete,
+	AuditWebhookCreate,
+	AuditWebhookUpdate,
+	AuditWebhookDelete,
+	AuditSubscriberDelete,
+}
+
+// auditSummaryLen 摘要最大长度
+const auditSummaryLen = 1024
+
+// Audit 记录审计日志, 失败只记录错误不影响操作
+func (c *Cache) Audit(ctx context.Context, log *model.AuditLog) {
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	err := c.InsertAuditLog(ctx, log)
+	if err != nil {
+		logrus.Error("cache.Audit.InsertAuditLog: ", err)
+	}
+}
+
+// AuditSummary 审计摘要, nil为空, 字符串原样保留, 其它序列化为json
+func AuditSummary(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err.Error()
+		}
+		if s = string(data); s == "null" {
+			return ""
+		}
+	}
+	if r := []rune(s); len(r) > auditSummaryLen {
+		s = string(r[:auditSummaryLen]) + "..."
+	}
+	return s
+}
+
+// AuditArticle 文章审计摘要, 不包含正文
+func AuditArticle(article *model.Article) map[string]interface{} {
+	if article == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"title":    article.Title,
+		"slug":     article.Slug,
+		"serie_id": article.SerieID,
+		"tags":     article.Tags,
+		"is_draft": article.IsDraft,
+	}
+}
+
+// AuditSerie 专题审计摘要
+func AuditSerie(serie *model.Serie) map[string]interface{} {
+	if serie == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"slug": serie.Slug,
+		"name": serie.Name,
+		"desc": serie.Desc,
+	}
+}
+
+// AuditBlogger 博客信息审计摘要
+func AuditBlogger(blogger *model.Blogger) map[string]interface{} {
+	return map[string]interface{}{
+		"blog_name":    blogger.BlogName,
+		"b_title":      blogger.BTitle,
+		"sub_title":    blogger.SubTitle,
+		"bei_an":       blogger.BeiAn,
+		"series_say":   blogger.SeriesSay,
+		"archives_say": blogger.ArchivesSay,
+	}
+}
+
+// PageAuditLogs 审计日志分页
+func (c *Cache) PageAuditLogs(ctx context.Context, fields map[string]interface{}, p, n int) ([]*model.AuditLog, int) {
+	search := store.SearchAuditLogs{
+		Page:   p,
+		Limit:  n,
+		Fields: fields,
+	}
+	logs, count, err := c.LoadAuditLogList(ctx, search)
+	if err != nil {
+		logrus.Error("cache.PageAuditLogs.LoadAuditLogList: ", err)
+		return nil, 0
+	}
+	maxCount := count / n
+	if count%n > 0 {
+		maxCount++
+	}
+	return logs, maxCount
+}
+
+// cleanAuditLogs 按保留时长清理审计日志及登录记录
+func (c *Cache) cleanAuditLogs(now time.Time) error {
+	retention := config.Conf.EiBlogApp.Audit.Retention
+	if retention == "" {
+		return nil
+	}
+	d, err := tools.ParseDuration(retention)
+	if err != nil {
+		return err
+	}
+	err = c.CleanAuditLogs(context.Background(), now.Add(-d))
+	if err != nil {
+		return err
+	}
+	return c.CleanLoginAttempts(context.Background(), now.Add(-d))
+}
@@ -10,12 +10,14 @@ import (
 	"golang.org/x/text/language"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/eiblog/eiblog/pkg/cache/render"
 	"github.com/eiblog/eiblog/pkg/cache/store"
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/metrics"
 	"github.com/eiblog/eiblog/pkg/model"
 	"github.com/eiblog/eiblog/tools"
 
@@ -55,26 +57,58 @@ func init() {
 		panic(err)
 	}
 	// Ei init
+	ctx, cancel := context.WithCancel(context.Background())
 	Ei = &Cache{
 		lock:        sync.Mutex{},
+		ctx:         ctx,
+		cancel:      cancel,
 		Store:       stores,
+		Sessions:    stores,
 		TagArticles: make(map[string]model.SortedArticles),
 		ArticlesMap: make(map[string]*model.Article),
 	}
+	// 会话保存到redis
+	if conf := config.Conf.EiBlogApp.Session; conf.RedisAddr != "" {
+		Ei.Sessions, err = store.NewRedisSessionStore(conf.RedisAddr,
+			conf.RedisPwd, conf.RedisDB)
+		if err != nil {
+			panic(err)
+		}
+	}
+	// 前台页面缓存
+	if conf := config.Conf.EiBlogApp.PageCache; conf.Enable {
+		Ei.Pages, err = newPageCache(conf)
+		if err != nil {
+			panic(err)
+		}
+	}
 	err = Ei.loadOrInit()
 	if err != nil {
 		panic(err)
 	}
-	go Ei.regeneratePages()
-	go Ei.timerClean()
-	//go Ei.timerDisqus()
-	go Ei.timerRemark42()
+	Ei.Touch()
+	Ei.registerMetrics()
+	Ei.goWorker("regeneratePages", Ei.regeneratePages)
+	Ei.goWorker("dispatchEvents", Ei.dispatchEvents)
+	Ei.goWorker("timerClean", Ei.timerClean)
+	if conf := config.Conf.EiBlogApp.Newsletter; conf.Enable && conf.Mode == NewsletterDigest {
+		Ei.goWorker("timerNewsletter", Ei.timerNewsletter)
+	}
+	//Ei.goWorker("timerDisqus", Ei.timerDisqus)
+	Ei.goWorker("timerRemark42", Ei.timerRemark42)
 }
 
 // Cache 整站缓存
 type Cache struct {
-	lock sync.Mutex
+	lock     sync.Mutex
+	hookLock sync.RWMutex
+	ctx      context.Context    // Close时取消, 停止后台任务
+	cancel   context.CancelFunc // 取消ctx
+	jobs     sync.WaitGroup     // 进行中的后台任务
+	workers  sync.Map           // 常驻任务名:是否存活
 	store.Store
+	Sessions store.SessionStore     // 登录会话存储
+	Mail     internal.MailTransport // 邮件发送方式, 为空时使用smtp
 
 	// load from db
 	Blogger  *model.Blogger
@@ -88,15 +122,44 @@ type Cache struct {
 	Archives     model.SortedArchives
 	TagArticles  map[string]model.SortedArticles // tagname:articles
 	ArticlesMap  map[string]*model.Article       // slug:article
+	Webhooks     []*model.Webhook                // webhooks
+	Pages        *PageCache                      // 前台页面渲染结果, 未开启时为nil
+
+	modified   atomic.Int64 // 前台页面内容最后修改时间, unix纳秒
+	seriesAt   atomic.Int64 // 专题页最后生成时间
+	archivesAt atomic.Int64 // 归档页最后生成时间
+}
+
+// Touch 标记前台页面内容已修改, 如文章, 专题, 博主信息, 评论数, 配置及模版
+func (c *Cache) Touch() {
+	c.modified.Store(time.Now().UnixNano())
+}
+
+// Modified 前台页面内容最后修改时间, 用于Last-Modified及ETag
+func (c *Cache) Modified() time.Time {
+	return time.Unix(0, c.modified.Load())
+}
+
+// PageModified 专题或归档页的最后修改时间, 页面异步生成, 取生成时间与Modified的较晚者
+func (c *Cache) PageModified(page string) time.Time {
+	at := &c.seriesAt
+	if page == PageArchive {
+		at = &c.archivesAt
+	}
+	generated := time.Unix(0, at.Load())
+	if modified := c.Modified(); modified.After(generated) {
+		return modified
+	}
+	return generated
 }
 
 // AddArticle 添加文章
-func (c *Cache) AddArticle(article *model.Article) error {
+func (c *Cache) AddArticle(ctx context.Context, article *model.Article) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	// store
-	err := c.InsertArticle(context.Background(), article, ArticleStartID)
+	err := c.InsertArticle(ctx, article, ArticleStartID)
 	if err != nil {
 		return err
 	}
@@ -106,6 +169,7 @@ func (c *Cache) AddArticle(article *model.Article) error {
 	}
 	// 正式发布文章
 	c.refreshCache(article, false)
+	c.FireEvent(EventArticlePublished, EventArticle(article))
 	return nil
 }
 
@@ -114,8 +178,19 @@ func (c *Cache) RepArticle(oldArticle, newArticle *model.Article) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	c.Touch()
+	// 关于及友链页不在文章列表中, 直接清除
+	if oldArticle != nil {
+		defer c.Pages.Purge(PageKeyArticle(oldArticle.Slug))
+	}
+	defer c.Pages.Purge(PageKeyArticle(newArticle.Slug))
 	c.ArticlesMap[newArticle.Slug] = newArticle
 	render.GenerateExcerptMarkdown(newArticle)
+	if oldArticle == nil {
+		c.FireEvent(EventArticlePublished, EventArticle(newArticle))
+	} else {
+		c.FireEvent(EventArticleUpdated, EventArticle(newArticle))
+	}
 	if newArticle.ID < ArticleStartID {
 		return
 	}
@@ -126,7 +201,7 @@ func (c *Cache) RepArticle(oldArticle, newArticle *model.Article) {
 }
 
 // DelArticle 删除文章
-func (c *Cache) DelArticle(id int) error {
+func (c *Cache) DelArticle(ctx context.Context, id int) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -135,7 +210,7 @@ func (c *Cache) DelArticle(id int) error {
 		return nil
 	}
 	// set delete
-	err := c.UpdateArticle(context.Background(), id, map[string]interface{}{
+	err := c.UpdateArticle(ctx, id, map[string]interface{}{
 		"deleted_at": time.Now(),
 	})
 	if err != nil {
@@ -143,25 +218,29 @@ func (c *Cache) DelArticle(id int) error {
 	}
 	// drop from tags,series,archives
 	c.refreshCache(article, true)
+	c.FireEvent(EventArticleTrashed, EventArticle(article))
 	return nil
 }
 
 // AddSerie 添加专题
-func (c *Cache) AddSerie(serie *model.Serie) error {
+func (c *Cache) AddSerie(ctx context.Context, serie *model.Serie) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	err := c.InsertSerie(context.Background(), serie)
+	err := c.InsertSerie(ctx, serie)
 	if err != nil {
 		return err
 	}
 	c.Series = append(c.Series, serie)
+	c.Touch()
+	c.Pages.Purge(PageKeySeries)
 	PagesCh <- PageSeries
+	c.FireEvent(EventSerieCreated, EventSerie(serie))
 	return nil
 }
 
 // DelSerie 删除专题
-func (c *Cache) DelSerie(id int) error {
+func (c *Cache) DelSerie(ctx context.Context, id int) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -170,19 +249,93 @@ func (c *Cache) DelSerie(id int) error {
 			if len(serie.Articles) > 0 {
 				return errors.New("请删除该专题下的所有文章")
 			}
-			err := c.RemoveSerie(context.Background(), id)
+			err := c.RemoveSerie(ctx, id)
 			if err != nil {
 				return err
 			}
 			c.Series[i] = nil
 			c.Series = append(c.Series[:i], c.Series[i+1:]...)
+			c.Touch()
+			c.Pages.Purge(PageKeySeries)
 			PagesCh <- PageSeries
+			c.FireEvent(EventSerieDeleted, EventSerie(serie))
 			break
 		}
 	}
 	return nil
 }
 
+// EditSerie 更新专题
+func (c *Cache) EditSerie(ctx context.Context, serie *model.Serie, slug, name, desc string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := c.UpdateSerie(ctx, serie.ID, map[string]interface{}{
+		"slug": slug,
+		"name": name,
+		"desc": desc,
+	})
+	if err != nil {
+		return err
+	}
+	serie.Slug = slug
+	serie.Name = name
+	serie.Desc = desc
+	c.Touch()
+	c.PurgeSerie(serie)
+	PagesCh <- PageSeries
+	c.FireEvent(EventSerieUpdated, EventSerie(serie))
+	return nil
+}
+
+// EditBlogger 更新博客信息, 不包括版权声明
+func (c *Cache) EditBlogger(ctx context.Context, blogger *model.Blogger) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := c.UpdateBlogger(ctx, map[string]interface{}{
+		"blog_name":    blogger.BlogName,
+		"b_title":      blogger.BTitle,
+		"sub_title":    blogger.SubTitle,
+		"bei_an":       blogger.BeiAn,
+		"series_say":   blogger.SeriesSay,
+		"archives_say": blogger.ArchivesSay,
+	})
+	if err != nil {
+		return err
+	}
+	c.Blogger.BlogName = blogger.BlogName
+	c.Blogger.BTitle = blogger.BTitle
+	c.Blogger.BeiAn = blogger.BeiAn
+	c.Blogger.SubTitle = blogger.SubTitle
+	c.Blogger.SeriesSay = blogger.SeriesSay
+	c.Blogger.ArchivesSay = blogger.ArchivesSay
+	c.Touch()
+	c.Pages.PurgeAll()
+	PagesCh <- PageSeries
+	PagesCh <- PageArchive
+	c.FireEvent(EventBloggerUpdated, EventBlogger(c.Blogger))
+	return nil
+}
+
+// SyncArticle 文章发布或更新后异步更新搜索索引, 通知rss订阅, created为true时创建disqus thread
+func (c *Cache) SyncArticle(article *model.Article, created bool) {
+	btitle := c.Blogger.BTitle
+	c.goJob(func() {
+		err := internal.ElasticAddIndex(article)
+		if err != nil {
+			logrus.Error("cache.SyncArticle.ElasticAddIndex: ", err)
+		}
+		internal.PingFunc(btitle, article.Slug)
+		if created {
+			err = internal.ThreadCreate(article, btitle)
+			if err != nil {
+				logrus.Error("cache.SyncArticle.ThreadCreate: ", err)
+			}
+		}
+	})
+}
+
 // PageArticleFE 文章翻页
 func (c *Cache) PageArticleFE(page int, pageSize int) (prev,
 	next int, articles []*model.Article) {
@@ -219,7 +372,7 @@ func (c *Cache) PageArticleFE(page int, pageSize int) (prev,
 }
 
 // PageArticleBE 后台文章分页
-func (c *Cache) PageArticleBE(se int, kw string, draft, del bool, p,
+func (c *Cache) PageArticleBE(ctx context.Context, se int, kw string, draft, del bool, p,
 	n int) ([]*model.Article, int) {
 
 	search := store.SearchArticles{
@@ -240,7 +393,7 @@ func (c *Cache) PageArticleBE(se int, kw string, draft, del bool, p,
 			search.Fields[store.SearchArticleTitle] = kw
 		}
 	}
-	articles, count, err := c.LoadArticleList(context.Background(), search)
+	articles, count, err := c.LoadArticleList(ctx, search)
 	if err != nil {
 		return nil, 0
 	}
@@ -251,6 +404,58 @@ func (c *Cache) PageArticleBE(se int, kw string, draft, del bool, p,
 	return articles, maxCount
 }
 
+// PublishedArticles 已发布文章列表的副本, tag不为空时为该标签下的文章, 可在锁外遍历
+func (c *Cache) PublishedArticles(tag string) model.SortedArticles {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	articles := c.Articles
+	if tag != "" {
+		articles = c.TagArticles[tag]
+	}
+	return append(model.SortedArticles(nil), articles...)
+}
+
+// PublishedArticle 按ID或slug查找已发布的文章, slug可为关于及友链页, 均为空时返回nil
+func (c *Cache) PublishedArticle(id int, slug string) *model.Article {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if slug != "" {
+		return c.ArticlesMap[slug]
+	}
+	article, _ := c.FindArticleByID(id)
+	return article
+}
+
+// SeriesList 专题列表的副本
+func (c *Cache) SeriesList() model.SortedSeries {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append(model.SortedSeries(nil), c.Series...)
+}
+
+// TagCounts 各标签下的文章数量
+func (c *Cache) TagCounts() map[string]int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	counts := make(map[string]int, len(c.TagArticles))
+	for name, articles := range c.TagArticles {
+		counts[name] = len(articles)
+	}
+	return counts
+}
+
+// BloggerInfo 博客信息的副本
+func (c *Cache) BloggerInfo() model.Blogger {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return *c.Blogger
+}
+
 // FindArticleByID 通过ID查找文章
 func (c *Cache) FindArticleByID(id int) (*model.Article, int) {
 	for i, article := range c.Articles {
@@ -263,7 +468,10 @@ func (c *Cache) FindArticleByID(id int) (*model.Article, int) {
 
 // refreshCache 刷新缓存
 func (c *Cache) refreshCache(article *model.Article, del bool) {
+	c.Touch()
 	if del {
+		// 受影响的页面在移除前取得, 移除后清除
+		defer c.purgePages(c.articlePages(article))
 		_, idx := c.FindArticleByID(article.ID)
 
 		delete(c.ArticlesMap, article.Slug)
@@ -274,7 +482,8 @@ func (c *Cache) refreshCache(article *model.Article, del bool) {
 		c.redelArticle(article)
 		return
 	}
-	// 添加文章
+	// 添加文章, 生成摘要后清除受影响的页面
+	defer func() { c.purgePages(c.articlePages(article)) }()
 	defer render.GenerateExcerptMarkdown(article)
 
 	c.ArticlesMap[article.Slug] = article
@@ -286,6 +495,41 @@ func (c *Cache) refreshCache(article *model.Article, del bool) {
 	c.readdArticle(article, true)
 }
 
+// articlePages 文章变化时受影响的页面: 文章, 上下篇, 同专题的文章, 专题及归档页
+func (c *Cache) articlePages(article *model.Article) []string {
+	keys := []string{PageKeyArticle(article.Slug), PageKeySeries, PageKeyArchives}
+	if article.Prev != nil {
+		keys = append(keys, PageKeyArticle(article.Prev.Slug))
+	}
+	if article.Next != nil {
+		keys = append(keys, PageKeyArticle(article.Next.Slug))
+	}
+	for _, serie := range c.Series {
+		if serie.ID != article.SerieID {
+			continue
+		}
+		for _, v := range serie.Articles {
+			keys = append(keys, PageKeyArticle(v.Slug))
+		}
+	}
+	return keys
+}
+
+// purgePages 清除页面及所有首页
+func (c *Cache) purgePages(keys []string) {
+	c.Pages.Purge(keys...)
+	c.Pages.PurgePrefix(pageKeyHome)
+}
+
+// PurgeSerie 专题修改后清除专题页及专题下的文章页
+func (c *Cache) PurgeSerie(serie *model.Serie) {
+	keys := []string{PageKeySeries}
+	for _, v := range serie.Articles {
+		keys = append(keys, PageKeyArticle(v.Slug))
+	}
+	c.Pages.Purge(keys...)
+}
+
 // recalcLinkedList 重算文章链表
 func (c *Cache) recalcLinkedList(article *model.Article, del bool) {
 	// 删除操作
@@ -447,25 +691,44 @@ func (c *Cache) loadOrInit() error {
 			return err
 		}
 	}
-	// account
-	pwd := tools.EncryptPasswd(blogApp.Account.Username,
-		blogApp.Account.Password)
-
+	// account, 仅首次创建时使用配置的密码, 之后以数据库为准
+	pwd := blogApp.Account.Password
+	if !tools.IsPasswordHash(pwd) {
+		pwd, err = tools.HashPassword(pwd)
+		if err != nil {
+			return err
+		}
+	}
 	account := &model.Account{
 		Username: blogApp.Account.Username,
 		Password: pwd,
+		Email:    blogApp.Account.Email,
 	}
 	_, err = c.LoadInsertAccount(context.Background(), account)
 	if err != nil {
 		return err
 	}
 	c.Account = account
+	if account.SessionSecret == "" {
+		account.SessionSecret = newSessionSecret()
+		err = c.UpdateAccount(context.Background(), account.Username,
+			map[string]interface{}{"session_secret": account.SessionSecret})
+		if err != nil {
+			return err
+		}
+	}
 	// series
 	series, err := c.LoadAllSerie(context.Background())
 	if err != nil {
 		return err
 	}
 	c.Series = series
+	// webhooks
+	hooks, err := c.LoadAllWebhook(context.Background())
+	if err != nil {
+		return err
+	}
+	c.Webhooks = hooks
 	// all articles
 	search := store.SearchArticles{
 		Page:   1,
@@ -504,7 +767,13 @@ func (c *Cache) loadOrInit() error {
 // regeneratePages 重新生成series,archive页面
 func (c *Cache) regeneratePages() {
 	for {
-		switch page := <-PagesCh; page {
+		var page string
+		select {
+		case page = <-PagesCh:
+		case <-c.ctx.Done():
+			return
+		}
+		switch page {
 		case PageSeries:
 			sort.Sort(c.Series)
 			buf := bytes.Buffer{}
@@ -524,6 +793,8 @@ func (c *Cache) regeneratePages() {
 				buf.WriteString("\n")
 			}
 			c.PageSeries = string(render.PageRender(buf.Bytes()))
+			c.seriesAt.Store(time.Now().UnixNano())
+			c.Pages.Purge(PageKeySeries)
 		case PageArchive:
 			sort.Sort(c.Archives)
 			buf := bytes.Buffer{}
@@ -557,46 +828,107 @@ func (c *Cache) regeneratePages() {
 				}
 			}
 			c.PageArchives = string(render.PageRender(buf.Bytes()))
+			c.archivesAt.Store(time.Now().UnixNano())
+			c.Pages.Purge(PageKeyArchives)
+		}
+	}
+}
+
+// registerMetrics 缓存大小
+func (c *Cache) registerMetrics() {
+	size := func(f func() int) func() float64 {
+		return func() float64 {
+			c.lock.Lock()
+			defer c.lock.Unlock()
+			return float64(f())
 		}
 	}
+	metrics.CacheSize("articles", size(func() int { return len(c.Articles) }))
+	metrics.CacheSize("tags", size(func() int { return len(c.TagArticles) }))
+	metrics.CacheSize("series", size(func() int { return len(c.Series) }))
+	if c.Pages != nil {
+		metrics.CacheSize("pages", func() float64 { return float64(c.Pages.Stats().Entries) })
+		metrics.PageCache(
+			func() float64 { return float64(c.Pages.Stats().Hits) },
+			func() float64 { return float64(c.Pages.Stats().Misses) },
+			func() float64 { return float64(c.Pages.Stats().Bytes) },
+		)
+	}
 }
 
 // timerClean 定时清理文章
 func (c *Cache) timerClean() {
 	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
 
-	for now := range ticker.C {
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+		var errs []error
 		exp := now.Add(TrashArticleExp)
-		err := c.CleanArticles(context.Background(), exp)
-		if err != nil {
+		if err := c.CleanArticles(context.Background(), exp); err != nil {
 			logrus.Error("cache.timerClean.CleanArticles: ", err)
+			errs = append(errs, err)
+		}
+		if err := c.Sessions.CleanSessions(context.Background(), now); err != nil {
+			logrus.Error("cache.timerClean.CleanSessions: ", err)
+			errs = append(errs, err)
 		}
+		if err := c.cleanAuditLogs(now); err != nil {
+			logrus.Error("cache.timerClean.cleanAuditLogs: ", err)
+			errs = append(errs, err)
+		}
+		c.NotifyJob("timerClean", errors.Join(errs...))
 	}
 }
 
 // timerDisqus disqus定时操作
 func (c *Cache) timerDisqus() {
 	ticker := time.NewTicker(5 * time.Hour)
+	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
 		err := internal.PostsCount(c.ArticlesMap)
 		if err != nil {
 			logrus.Error("cache.timerDisqus.PostsCount: ", err)
 		}
+		c.Touch()
+		c.Pages.PurgeAll()
 	}
 }
 
 func (c *Cache) timerRemark42() {
 	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
 	time.Sleep(2000)
 	err := internal.PostRemark42Count(c.ArticlesMap)
 	if err != nil {
 		logrus.Error("cache.timerRemark42.PostRemark42Count: ", err)
 	}
-	for range ticker.C {
+	c.Touch()
+	c.Pages.PurgeAll()
+	c.NotifyJob("timerRemark42", err)
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
 		err := internal.PostRemark42Count(c.ArticlesMap)
 		if err != nil {
 			logrus.Error("cache.timerRemark42.PostRemark42Count: ", err)
 		}
+		c.Touch()
+		c.Pages.PurgeAll()
+		c.NotifyJob("timerRemark42", err)
 	}
 }
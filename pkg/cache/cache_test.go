@@ -0,0 +1,91 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/model"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// 包初始化时按默认配置创建的数据库
+	os.Remove("db.sqlite")
+	os.Exit(code)
+}
+
+// newTestCache 使用临时sqlite数据库的缓存, 测试间互不影响
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+	stores, err := store.NewStore("sqlite", filepath.Join(t.TempDir(), "db.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cache{
+		ctx:         ctx,
+		cancel:      cancel,
+		Store:       stores,
+		Sessions:    stores,
+		Blogger:     &model.Blogger{BTitle: "EiBlog"},
+		Account:     &model.Account{Username: "deepzz", Email: "admin@example.com"},
+		TagArticles: make(map[string]model.SortedArticles),
+		ArticlesMap: make(map[string]*model.Article),
+	}
+	t.Cleanup(func() {
+		cancel()
+		c.jobs.Wait()
+	})
+	return c
+}
+
+// setConfig 修改配置, 测试结束后恢复
+func setConfig(t *testing.T, fn func(app *config.EiBlogApp)) {
+	t.Helper()
+	old := config.Conf.EiBlogApp
+	fn(&config.Conf.EiBlogApp)
+	t.Cleanup(func() { config.Conf.EiBlogApp = old })
+}
+
+// mailRecorder 记录邮件的 MailTransport
+type mailRecorder struct {
+	lock sync.Mutex
+	to   []string
+}
+
+// Send 记录收件人
+func (r *mailRecorder) Send(from string, to []string, msg []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.to = append(r.to, to...)
+	return nil
+}
+
+// sent 已发送的收件人
+func (r *mailRecorder) sent() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.to...)
+}
+
+// enableMail 开启smtp配置, 邮件由mailRecorder记录
+func enableMail(t *testing.T, c *Cache) *mailRecorder {
+	t.Helper()
+	setConfig(t, func(app *config.EiBlogApp) {
+		app.SMTP = config.SMTP{
+			Enable: true,
+			Host:   "smtp.example.com",
+			Port:   25,
+			From:   "EiBlog <noreply@example.com>",
+		}
+	})
+	r := &mailRecorder{}
+	c.Mail = r
+	return r
+}
@@ -0,0 +1,271 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/model"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// 内容变更事件
+const (
+	EventArticlePublished = "article.published"
+	EventArticleUpdated   = "article.updated"
+	EventArticleTrashed   = "article.trashed"
+	EventArticleRestored  = "article.restored"
+	EventSerieCreated     = "serie.created"
+	EventSerieUpdated     = "serie.updated"
+	EventSerieDeleted     = "serie.deleted"
+	EventBloggerUpdated   = "blogger.updated"
+)
+
+// Events 所有可订阅的事件
+var Events = []string{
+	EventArticlePublished,
+	EventArticleUpdated,
+	EventArticleTrashed,
+	EventArticleRestored,
+	EventSerieCreated,
+	EventSerieUpdated,
+	EventSerieDeleted,
+	EventBloggerUpdated,
+}
+
+var (
+	// EventsCh content events chan
+	EventsCh = make(chan Event, 64)
+
+	// webhookMaxAttempts webhook最多尝试次数
+	webhookMaxAttempts = 5
+	// webhookBackoff webhook首次重试间隔, 之后翻倍
+	webhookBackoff = 2 * time.Second
+)
+
+// Event 内容变更事件
+type Event struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// FireEvent 触发内容变更事件, 调用时可能持有c.lock, 队列已满时丢弃事件, 不阻塞
+func (c *Cache) FireEvent(name string, data interface{}) {
+	event := Event{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	select {
+	case EventsCh <- event:
+	default:
+		logrus.Errorf("cache.FireEvent: events chan is full, drop %s %s", event.Name, event.ID)
+	}
+}
+
+// EventArticle 文章事件数据, 避免序列化上下篇文章
+func EventArticle(article *model.Article) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         article.ID,
+		"slug":       article.Slug,
+		"title":      article.Title,
+		"author":     article.Author,
+		"tags":       article.Tags,
+		"serie_id":   article.SerieID,
+		"excerpt":    article.Excerpt,
+		"url":        config.Conf.EiBlogApp.SiteURL("", "/post/"+article.Slug+".html"),
+		"created_at": article.CreatedAt,
+		"updated_at": article.UpdatedAt,
+	}
+}
+
+// EventSerie 专题事件数据
+func EventSerie(serie *model.Serie) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         serie.ID,
+		"slug":       serie.Slug,
+		"name":       serie.Name,
+		"desc":       serie.Desc,
+		"url":        config.Conf.EiBlogApp.SiteURL("", fmt.Sprintf("/series.html#toc-%d", serie.ID)),
+		"created_at": serie.CreatedAt,
+	}
+}
+
+// EventBlogger 博客事件数据
+func EventBlogger(blogger *model.Blogger) map[string]interface{} {
+	return map[string]interface{}{
+		"blog_name": blogger.BlogName,
+		"sub_title": blogger.SubTitle,
+		"b_title":   blogger.BTitle,
+		"bei_an":    blogger.BeiAn,
+	}
+}
+
+// AddWebhook 添加webhook
+func (c *Cache) AddWebhook(ctx context.Context, hook *model.Webhook) error {
+	c.hookLock.Lock()
+	defer c.hookLock.Unlock()
+
+	err := c.InsertWebhook(ctx, hook)
+	if err != nil {
+		return err
+	}
+	c.Webhooks = append(c.Webhooks, hook)
+	return nil
+}
+
+// RepWebhook 更新webhook
+func (c *Cache) RepWebhook(ctx context.Context, hook *model.Webhook) error {
+	c.hookLock.Lock()
+	defer c.hookLock.Unlock()
+
+	err := c.UpdateWebhook(ctx, hook.ID, map[string]interface{}{
+		"name":   hook.Name,
+		"url":    hook.URL,
+		"secret": hook.Secret,
+		"events": hook.Events,
+		"active": hook.Active,
+	})
+	if err != nil {
+		return err
+	}
+	for i, v := range c.Webhooks {
+		if v.ID == hook.ID {
+			c.Webhooks[i] = hook
+			break
+		}
+	}
+	return nil
+}
+
+// DelWebhook 删除webhook及推送记录
+func (c *Cache) DelWebhook(ctx context.Context, id int) error {
+	c.hookLock.Lock()
+	defer c.hookLock.Unlock()
+
+	for i, hook := range c.Webhooks {
+		if hook.ID == id {
+			err := c.RemoveWebhook(ctx, id)
+			if err != nil {
+				return err
+			}
+			c.Webhooks = append(c.Webhooks[:i], c.Webhooks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// FindWebhookByID 通过ID查找webhook
+func (c *Cache) FindWebhookByID(id int) *model.Webhook {
+	c.hookLock.RLock()
+	defer c.hookLock.RUnlock()
+
+	for _, hook := range c.Webhooks {
+		if hook.ID == id {
+			return hook
+		}
+	}
+	return nil
+}
+
+// dispatchEvents 分发内容变更事件, 关闭时处理完剩余事件后退出
+func (c *Cache) dispatchEvents() {
+	for {
+		select {
+		case event := <-EventsCh:
+			c.dispatchEvent(event)
+		case <-c.ctx.Done():
+			for {
+				select {
+				case event := <-EventsCh:
+					c.dispatchEvent(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// dispatchEvent 推送newsletter及webhook
+func (c *Cache) dispatchEvent(event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logrus.Error("cache.dispatchEvents.Marshal: ", err)
+		return
+	}
+	if event.Name == EventArticlePublished {
+		c.goJob(func() { c.newsletterPost(event) })
+	}
+	c.hookLock.RLock()
+	for _, hook := range c.Webhooks {
+		if hook.Active && hook.Subscribed(event.Name) {
+			hook := hook
+			c.goJob(func() { c.deliverWebhook(hook, event, payload) })
+		}
+	}
+	c.hookLock.RUnlock()
+}
+
+// deliverWebhook 推送webhook, 失败按指数退避重试, 并记录推送日志
+func (c *Cache) deliverWebhook(hook *model.Webhook, event Event, payload []byte) {
+	params := internal.WebhookParams{
+		URL:      hook.URL,
+		Secret:   hook.Secret,
+		Event:    event.Name,
+		Delivery: event.ID,
+		Payload:  payload,
+	}
+	delivery := &model.WebhookDelivery{
+		WebhookID:  hook.ID,
+		DeliveryID: event.ID,
+		Event:      event.Name,
+		Payload:    string(payload),
+	}
+	backoff := webhookBackoff
+retry:
+	for delivery.Attempts < webhookMaxAttempts {
+		delivery.Attempts++
+		code, resp, err := internal.WebhookPost(params)
+		delivery.StatusCode = code
+		delivery.Response = resp
+		if err == nil {
+			delivery.Error = ""
+			delivery.Success = true
+			break
+		}
+		delivery.Error = err.Error()
+		logrus.Warnf("cache.deliverWebhook: %s attempt %d: %v", hook.URL,
+			delivery.Attempts, err)
+		if delivery.Attempts < webhookMaxAttempts {
+			// 关闭时不再重试
+			select {
+			case <-time.After(backoff):
+			case <-c.ctx.Done():
+				break retry
+			}
+			backoff *= 2
+		}
+	}
+	if delivery.Success {
+		c.NotifyJob("webhook "+hook.Name, nil)
+	} else {
+		c.NotifyJob("webhook "+hook.Name, errors.New(delivery.Error))
+	}
+	delivery.CreatedAt = time.Now()
+	err := c.InsertWebhookDelivery(context.Background(), delivery)
+	if err != nil {
+		logrus.Error("cache.deliverWebhook.InsertWebhookDelivery: ", err)
+	}
+}
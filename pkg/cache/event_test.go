@@ -0,0 +1,199 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/model"
+)
+
+// setWebhookRetry 修改重试次数及间隔, 测试结束后恢复
+func setWebhookRetry(t *testing.T, attempts int, backoff time.Duration) {
+	oldAttempts, oldBackoff := webhookMaxAttempts, webhookBackoff
+	webhookMaxAttempts, webhookBackoff = attempts, backoff
+	t.Cleanup(func() { webhookMaxAttempts, webhookBackoff = oldAttempts, oldBackoff })
+}
+
+// hookServer 前fails次返回500的webhook接收端, 记录请求时间
+type hookServer struct {
+	*httptest.Server
+
+	lock  sync.Mutex
+	fails int
+	times []time.Time
+}
+
+func newHookServer(t *testing.T, fails int) *hookServer {
+	s := &hookServer{fails: fails}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.times = append(s.times, time.Now())
+		if len(s.times) <= s.fails {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("busy"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *hookServer) requests() []time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]time.Time(nil), s.times...)
+}
+
+// addHook 添加订阅专题事件的webhook
+func addHook(t *testing.T, c *Cache, url string, active bool) *model.Webhook {
+	hook := &model.Webhook{
+		Name:   "test",
+		URL:    url,
+		Secret: "secret",
+		Events: []string{EventSerieCreated},
+		Active: active,
+	}
+	if err := c.AddWebhook(context.Background(), hook); err != nil {
+		t.Fatal(err)
+	}
+	return hook
+}
+
+// deliveries webhook的推送记录
+func deliveries(t *testing.T, c *Cache, hook *model.Webhook) []*model.WebhookDelivery {
+	list, err := c.LoadWebhookDeliveries(context.Background(), hook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestWebhookRetry(t *testing.T) {
+	backoff := 20 * time.Millisecond
+	setWebhookRetry(t, 3, backoff)
+	c := newTestCache(t)
+	srv := newHookServer(t, 2)
+	hook := addHook(t, c, srv.URL, true)
+
+	c.dispatchEvent(Event{ID: "d1", Name: EventSerieCreated, CreatedAt: time.Now()})
+	c.jobs.Wait()
+
+	// 失败两次后成功, 重试间隔翻倍
+	times := srv.requests()
+	if len(times) != 3 {
+		t.Fatalf("requests = %d, want 3", len(times))
+	}
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if d := times[i+1].Sub(times[i]); d < want {
+			t.Errorf("retry %d after %s, want at least %s", i+1, d, want)
+		}
+	}
+	list := deliveries(t, c, hook)
+	if len(list) != 1 {
+		t.Fatalf("deliveries = %d, want 1", len(list))
+	}
+	d := list[0]
+	if d.DeliveryID != "d1" || d.Event != EventSerieCreated || d.Attempts != 3 ||
+		!d.Success || d.StatusCode != http.StatusOK || d.Response != "ok" || d.Error != "" {
+		t.Errorf("delivery = %+v, want successful after 3 attempts", d)
+	}
+	if !strings.Contains(d.Payload, `"id":"d1"`) {
+		t.Errorf("delivery payload = %s", d.Payload)
+	}
+}
+
+func TestWebhookFailed(t *testing.T) {
+	setWebhookRetry(t, 3, time.Millisecond)
+	c := newTestCache(t)
+	srv := newHookServer(t, 10)
+	hook := addHook(t, c, srv.URL, true)
+
+	c.dispatchEvent(Event{ID: "d1", Name: EventSerieCreated, CreatedAt: time.Now()})
+	c.jobs.Wait()
+
+	// 达到最多尝试次数后放弃, 记录最后一次的结果
+	if n := len(srv.requests()); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+	list := deliveries(t, c, hook)
+	if len(list) != 1 {
+		t.Fatalf("deliveries = %d, want 1", len(list))
+	}
+	d := list[0]
+	if d.Success || d.Attempts != 3 || d.StatusCode != http.StatusInternalServerError ||
+		d.Response != "busy" || !strings.Contains(d.Error, "500") {
+		t.Errorf("delivery = %+v, want failed after 3 attempts", d)
+	}
+}
+
+func TestWebhookShutdown(t *testing.T) {
+	setWebhookRetry(t, 3, time.Hour)
+	c := newTestCache(t)
+	srv := newHookServer(t, 10)
+	hook := addHook(t, c, srv.URL, true)
+
+	// 关闭时不再等待重试, 仍记录推送结果
+	c.cancel()
+	c.dispatchEvent(Event{ID: "d1", Name: EventSerieCreated, CreatedAt: time.Now()})
+	c.jobs.Wait()
+
+	if n := len(srv.requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+	if list := deliveries(t, c, hook); len(list) != 1 || list[0].Attempts != 1 || list[0].Success {
+		t.Errorf("deliveries = %+v, want one failed attempt", list)
+	}
+}
+
+func TestWebhookSubscribed(t *testing.T) {
+	c := newTestCache(t)
+	srv := newHookServer(t, 0)
+	inactive := addHook(t, c, srv.URL, false)
+	active := addHook(t, c, srv.URL, true)
+
+	// 未订阅的事件及停用的webhook不推送
+	c.dispatchEvent(Event{ID: "d1", Name: EventSerieDeleted, CreatedAt: time.Now()})
+	c.dispatchEvent(Event{ID: "d2", Name: EventSerieCreated, CreatedAt: time.Now()})
+	c.jobs.Wait()
+
+	if n := len(srv.requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+	if list := deliveries(t, c, inactive); len(list) != 0 {
+		t.Errorf("inactive deliveries = %d, want 0", len(list))
+	}
+	if list := deliveries(t, c, active); len(list) != 1 || list[0].DeliveryID != "d2" {
+		t.Errorf("active deliveries = %+v, want d2", list)
+	}
+}
+
+func TestFireEventFull(t *testing.T) {
+	c := newTestCache(t)
+	// 阻塞Ei的事件分发, 使队列填满
+	Ei.hookLock.Lock()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < cap(EventsCh)+2; i++ {
+			c.FireEvent(EventBloggerUpdated, nil)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("FireEvent() blocked on a full events chan")
+	}
+	if len(EventsCh) != cap(EventsCh) {
+		t.Errorf("events queued = %d, want %d", len(EventsCh), cap(EventsCh))
+	}
+	Ei.hookLock.Unlock()
+	<-done
+}
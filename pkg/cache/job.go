@@ -0,0 +1,61 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// goJob 异步执行后台任务, 关闭时等待其完成
+func (c *Cache) goJob(f func()) {
+	c.jobs.Add(1)
+	go func() {
+		defer c.jobs.Done()
+		f()
+	}()
+}
+
+// goWorker 异步执行常驻任务, 记录其是否存活
+func (c *Cache) goWorker(name string, f func()) {
+	c.workers.Store(name, true)
+	c.goJob(func() {
+		defer c.workers.Store(name, false)
+		f()
+	})
+}
+
+// Workers 常驻任务是否存活, 异常退出时为false
+func (c *Cache) Workers() map[string]bool {
+	workers := make(map[string]bool)
+	c.workers.Range(func(k, v interface{}) bool {
+		workers[k.(string)] = v.(bool)
+		return true
+	})
+	return workers
+}
+
+// Close 停止定时任务, 等待进行中的任务(事件推送、邮件等)完成后关闭存储连接,
+// ctx 到期时不再等待
+func (c *Cache) Close(ctx context.Context) error {
+	c.cancel()
+	done := make(chan struct{})
+	go func() {
+		c.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logrus.Warn("cache.Close: jobs not finished: ", ctx.Err())
+	}
+	if c.Sessions != store.SessionStore(c.Store) {
+		err := c.Sessions.Close(ctx)
+		if err != nil {
+			logrus.Error("cache.Close.Sessions: ", err)
+		}
+	}
+	return c.Store.Close(ctx)
+}
@@ -0,0 +1,308 @@
+// Package cache provides ...
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxLoginRecords 最多保留的失败计数, 超过时淘汰最久未失败的
+const maxLoginRecords = 4096
+
+// loginRecord 登录失败计数
+type loginRecord struct {
+	key       string    // ip:xxx 或 user:xxx
+	failures  int       // 当前窗口失败次数
+	total     int       // 累计失败次数, 用于告警
+	lockouts  int       // 锁定次数, 用于计算锁定时长
+	windowAt  time.Time // 窗口开始时间
+	lockUntil time.Time // 锁定截止时间
+	lastAt    time.Time // 最近失败时间
+}
+
+var (
+	loginLock    sync.Mutex
+	loginRecords = make(map[string]*list.Element) // key:*loginRecord
+	loginLRU     = list.New()                     // 最近失败的在前
+)
+
+// loginLimit 登录限制配置, 未配置时使用默认值
+type loginLimit struct {
+	maxAttempts int
+	window      time.Duration
+	lockout     time.Duration
+	maxLockout  time.Duration
+	alertAt     int
+}
+
+// loginLimitConf 解析登录限制配置
+func loginLimitConf() loginLimit {
+	conf := config.Conf.EiBlogApp.LoginLimit
+	limit := loginLimit{
+		maxAttempts: conf.MaxAttempts,
+		window:      15 * time.Minute,
+		lockout:     time.Minute,
+		maxLockout:  24 * time.Hour,
+		alertAt:     conf.AlertAt,
+	}
+	if limit.maxAttempts <= 0 {
+		limit.maxAttempts = 5
+	}
+	for _, v := range []struct {
+		s string
+		d *time.Duration
+	}{
+		{conf.Window, &limit.window},
+		{conf.Lockout, &limit.lockout},
+		{conf.MaxLockout, &limit.maxLockout},
+	} {
+		if v.s == "" {
+			continue
+		}
+		d, err := tools.ParseDuration(v.s)
+		if err != nil {
+			logrus.Error("cache.loginLimitConf.ParseDuration: ", err)
+			continue
+		}
+		*v.d = d
+	}
+	return limit
+}
+
+// loginKeys IP与账号分别计数, 只有博主账号按账号计数
+func (c *Cache) loginKeys(ip, username string) []string {
+	if username != c.Account.Username {
+		return []string{"ip:" + ip}
+	}
+	return []string{"ip:" + ip, "user:" + username}
+}
+
+// loginRecordOf 失败计数, 不存在时返回nil
+func loginRecordOf(key string) *loginRecord {
+	elem := loginRecords[key]
+	if elem == nil {
+		return nil
+	}
+	return elem.Value.(*loginRecord)
+}
+
+// removeLoginRecord 删除失败计数
+func removeLoginRecord(key string) {
+	if elem := loginRecords[key]; elem != nil {
+		loginLRU.Remove(elem)
+		delete(loginRecords, key)
+	}
+}
+
+// LoginLocked 登录是否被锁定, 返回剩余锁定时长
+func (c *Cache) LoginLocked(ip, username string) time.Duration {
+	if !config.Conf.EiBlogApp.LoginLimit.Enable {
+		return 0
+	}
+	loginLock.Lock()
+	defer loginLock.Unlock()
+
+	var wait time.Duration
+	now := time.Now()
+	for _, key := range c.loginKeys(ip, username) {
+		record := loginRecordOf(key)
+		if record != nil && record.lockUntil.After(now) {
+			if d := record.lockUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// LoginFailed 记录登录失败, 超过次数锁定, 达到告警阈值时邮件通知.
+// IP锁定时长按指数增长, 账号锁定固定为首次锁定时长, 避免他人将博主长时间锁在外面
+func (c *Cache) LoginFailed(ctx context.Context, ip, username, ua, reason string) {
+	// 只记录博主账号的失败, 不存在的账号不写入存储
+	if username == c.Account.Username {
+		attempt := &model.LoginAttempt{
+			Username:  username,
+			IP:        ip,
+			UA:        ua,
+			Reason:    reason,
+			CreatedAt: time.Now(),
+		}
+		err := c.InsertLoginAttempt(ctx, attempt)
+		if err != nil {
+			logrus.Error("cache.LoginFailed.InsertLoginAttempt: ", err)
+		}
+	}
+	if !config.Conf.EiBlogApp.LoginLimit.Enable {
+		return
+	}
+	limit := loginLimitConf()
+
+	loginLock.Lock()
+	defer loginLock.Unlock()
+
+	var alerts []string
+	now := time.Now()
+	for _, key := range c.loginKeys(ip, username) {
+		record := loginRecordOf(key)
+		// 长时间无失败, 重新计数
+		if record == nil || now.Sub(record.lastAt) > limit.maxLockout {
+			removeLoginRecord(key)
+			record = &loginRecord{key: key, windowAt: now}
+			loginRecords[key] = loginLRU.PushFront(record)
+		} else {
+			loginLRU.MoveToFront(loginRecords[key])
+		}
+		if now.Sub(record.windowAt) > limit.window {
+			record.failures = 0
+			record.windowAt = now
+		}
+		record.failures++
+		record.total++
+		record.lastAt = now
+
+		if record.failures >= limit.maxAttempts {
+			lockout := limit.lockout << record.lockouts
+			if lockout <= 0 || lockout > limit.maxLockout {
+				lockout = limit.maxLockout
+			}
+			if strings.HasPrefix(key, "user:") && lockout > limit.lockout {
+				lockout = limit.lockout
+			}
+			record.lockouts++
+			record.failures = 0
+			record.lockUntil = now.Add(lockout)
+			logrus.Warnf("cache.LoginFailed: %s locked for %s", key, lockout)
+		}
+		if limit.alertAt > 0 && record.total == limit.alertAt {
+			alerts = append(alerts, key)
+		}
+	}
+	// IP与账号同时达到阈值只告警一次
+	if len(alerts) > 0 {
+		c.NotifyMail(internal.MailLoginAlert, map[string]interface{}{
+			"Key":      strings.Join(alerts, ", "),
+			"Username": username,
+			"Count":    limit.alertAt,
+			"IP":       ip,
+			"UA":       ua,
+			"Time":     now.In(tools.TimeLocation).Format("2006-01-02 15:04:05"),
+		})
+	}
+	// 超过上限时淘汰最久未失败的记录
+	for loginLRU.Len() > maxLoginRecords {
+		removeLoginRecord(loginLRU.Back().Value.(*loginRecord).key)
+	}
+}
+
+// LoginSucceeded 登录成功, 清除失败计数
+func (c *Cache) LoginSucceeded(ip, username string) {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+
+	for _, key := range c.loginKeys(ip, username) {
+		removeLoginRecord(key)
+	}
+}
+
+// RecordLogin 记录登录成功, 返回是否为新设备, 即未成功登录过的IP与UA组合
+func (c *Cache) RecordLogin(ctx context.Context, username, ip, ua, sessionID string) bool {
+	newDevice := false
+	_, total, err := c.LoadLoginAttemptList(ctx, store.SearchLoginAttempts{
+		Page:  1,
+		Limit: 1,
+		Fields: map[string]interface{}{
+			store.SearchLoginUsername: username,
+			store.SearchLoginSuccess:  true,
+		},
+	})
+	if err != nil {
+		logrus.Error("cache.RecordLogin.LoadLoginAttemptList: ", err)
+	} else if total == 0 {
+		// 尚无登录记录, 与最近登录信息比较
+		newDevice = c.Account.LoginIP != "" &&
+			(c.Account.LoginIP != ip || c.Account.LoginUA != ua)
+	} else {
+		_, count, err := c.LoadLoginAttemptList(ctx, store.SearchLoginAttempts{
+			Page:  1,
+			Limit: 1,
+			Fields: map[string]interface{}{
+				store.SearchLoginUsername: username,
+				store.SearchLoginSuccess:  true,
+				store.SearchLoginIP:       ip,
+				store.SearchLoginUA:       ua,
+			},
+		})
+		if err != nil {
+			logrus.Error("cache.RecordLogin.LoadLoginAttemptList: ", err)
+		}
+		newDevice = err == nil && count == 0
+	}
+	attempt := &model.LoginAttempt{
+		Username:  username,
+		IP:        ip,
+		UA:        ua,
+		Success:   true,
+		SessionID: sessionID,
+		NewDevice: newDevice,
+		CreatedAt: time.Now(),
+	}
+	err = c.InsertLoginAttempt(ctx, attempt)
+	if err != nil {
+		logrus.Error("cache.RecordLogin.InsertLoginAttempt: ", err)
+	}
+	return newDevice
+}
+
+// LoginHistory 最近的登录记录, 包括失败记录
+func (c *Cache) LoginHistory(ctx context.Context, username string, n int) []*model.LoginAttempt {
+	attempts, _, err := c.LoadLoginAttemptList(ctx, store.SearchLoginAttempts{
+		Page:   1,
+		Limit:  n,
+		Fields: map[string]interface{}{store.SearchLoginUsername: username},
+	})
+	if err != nil {
+		logrus.Error("cache.LoginHistory.LoadLoginAttemptList: ", err)
+	}
+	return attempts
+}
+
+// LoginAlerts 未读的新设备登录提醒
+func (c *Cache) LoginAlerts(ctx context.Context, n int) []*model.LoginAttempt {
+	attempts, _, err := c.LoadLoginAttemptList(ctx, store.SearchLoginAttempts{
+		Page:  1,
+		Limit: n,
+		Fields: map[string]interface{}{
+			store.SearchLoginUsername:  c.Account.Username,
+			store.SearchLoginSuccess:   true,
+			store.SearchLoginNewDevice: true,
+			store.SearchLoginSince:     c.Account.AlertAt,
+		},
+	})
+	if err != nil {
+		logrus.Error("cache.LoginAlerts.LoadLoginAttemptList: ", err)
+	}
+	return attempts
+}
+
+// ReadLoginAlerts 新设备登录提醒标记为已读
+func (c *Cache) ReadLoginAlerts(ctx context.Context) error {
+	now := time.Now()
+	err := c.UpdateAccount(ctx, c.Account.Username,
+		map[string]interface{}{"alert_at": now})
+	if err != nil {
+		return err
+	}
+	c.Account.AlertAt = now
+	return nil
+}
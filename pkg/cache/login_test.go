@@ -0,0 +1,190 @@
+// Package cache provides ...
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+)
+
+// setLoginLimit 开启登录限制, 清除失败计数
+func setLoginLimit(t *testing.T, alertAt int) {
+	setConfig(t, func(app *config.EiBlogApp) {
+		app.LoginLimit = config.LoginLimit{
+			Enable:      true,
+			MaxAttempts: 3,
+			Window:      "15m",
+			Lockout:     "1m",
+			MaxLockout:  "4m",
+			AlertAt:     alertAt,
+		}
+	})
+	resetLoginRecords()
+	t.Cleanup(resetLoginRecords)
+}
+
+func resetLoginRecords() {
+	loginLock.Lock()
+	loginRecords = make(map[string]*list.Element)
+	loginLRU.Init()
+	loginLock.Unlock()
+}
+
+func TestLoginWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts [][2]string // ip, username
+		ip       string
+		user     string
+		locked   bool
+	}{
+		{"under limit", [][2]string{{"1.1.1.1", "a"}, {"1.1.1.1", "a"}}, "1.1.1.1", "a", false},
+		{"same ip and user", [][2]string{{"1.1.1.1", "a"}, {"1.1.1.1", "a"}, {"1.1.1.1", "a"}}, "1.1.1.1", "a", true},
+		{"per ip", [][2]string{{"1.1.1.1", "a"}, {"1.1.1.1", "b"}, {"1.1.1.1", "c"}}, "1.1.1.1", "d", true},
+		{"per user", [][2]string{{"1.1.1.1", "deepzz"}, {"2.2.2.2", "deepzz"}, {"3.3.3.3", "deepzz"}}, "4.4.4.4", "deepzz", true},
+		// 只有博主账号按账号计数
+		{"unknown user", [][2]string{{"1.1.1.1", "a"}, {"2.2.2.2", "a"}, {"3.3.3.3", "a"}}, "4.4.4.4", "a", false},
+		{"other ip and user", [][2]string{{"1.1.1.1", "a"}, {"1.1.1.1", "a"}, {"1.1.1.1", "a"}}, "2.2.2.2", "b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setLoginLimit(t, 0)
+			c := newTestCache(t)
+			for _, v := range tt.attempts {
+				c.LoginFailed(context.Background(), v[0], v[1], "ua", "password")
+			}
+			if locked := c.LoginLocked(tt.ip, tt.user) > 0; locked != tt.locked {
+				t.Errorf("LoginLocked(%s, %s) = %v, want %v", tt.ip, tt.user, locked, tt.locked)
+			}
+		})
+	}
+
+	// 窗口过后重新计数
+	setLoginLimit(t, 0)
+	c := newTestCache(t)
+	c.LoginFailed(context.Background(), "1.1.1.1", "a", "ua", "password")
+	c.LoginFailed(context.Background(), "1.1.1.1", "a", "ua", "password")
+	loginLock.Lock()
+	for _, elem := range loginRecords {
+		record := elem.Value.(*loginRecord)
+		record.windowAt = record.windowAt.Add(-16 * time.Minute)
+	}
+	loginLock.Unlock()
+	c.LoginFailed(context.Background(), "1.1.1.1", "a", "ua", "password")
+	if wait := c.LoginLocked("1.1.1.1", "a"); wait > 0 {
+		t.Errorf("LoginLocked() after window = %s, want 0", wait)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	setLoginLimit(t, 0)
+	c := newTestCache(t)
+
+	// 锁定时长翻倍, 不超过maxlockout
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		for j := 0; j < 3; j++ {
+			c.LoginFailed(context.Background(), "1.1.1.1", "a", "ua", "password")
+		}
+		wait := c.LoginLocked("1.1.1.1", "a")
+		if wait <= want-time.Second || wait > want {
+			t.Errorf("lockout %d = %s, want %s", i+1, wait, want)
+		}
+	}
+
+	// 登录成功后清除
+	c.LoginSucceeded("1.1.1.1", "a")
+	if wait := c.LoginLocked("1.1.1.1", "a"); wait > 0 {
+		t.Errorf("LoginLocked() after success = %s, want 0", wait)
+	}
+	c.LoginFailed(context.Background(), "1.1.1.1", "a", "ua", "password")
+	loginLock.Lock()
+	record := loginRecordOf("ip:1.1.1.1")
+	loginLock.Unlock()
+	if record.failures != 1 || record.lockouts != 0 {
+		t.Errorf("record after success = %+v, want 1 failure and no lockouts", record)
+	}
+
+	// 关闭时不锁定
+	setConfig(t, func(app *config.EiBlogApp) { app.LoginLimit.Enable = false })
+	if wait := c.LoginLocked("1.1.1.1", "a"); wait > 0 {
+		t.Errorf("LoginLocked() disabled = %s, want 0", wait)
+	}
+}
+
+func TestLoginAccountLockout(t *testing.T) {
+	setLoginLimit(t, 0)
+	c := newTestCache(t)
+
+	// 账号锁定不翻倍, 不同IP的失败最多锁定账号lockout时长
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 3; j++ {
+			c.LoginFailed(context.Background(), fmt.Sprintf("10.0.%d.%d", i, j), "deepzz", "ua", "password")
+		}
+		wait := c.LoginLocked("10.1.0.1", "deepzz")
+		if wait <= time.Minute-time.Second || wait > time.Minute {
+			t.Errorf("account lockout %d = %s, want %s", i+1, wait, time.Minute)
+		}
+	}
+}
+
+func TestLoginRecords(t *testing.T) {
+	setLoginLimit(t, 0)
+	c := newTestCache(t)
+
+	// 不存在的账号不写入存储
+	c.LoginFailed(context.Background(), "1.1.1.1", "a", "ua", "password")
+	c.LoginFailed(context.Background(), "1.1.1.1", "deepzz", "ua", "password")
+	attempts, total, err := c.LoadLoginAttemptList(context.Background(), store.SearchLoginAttempts{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(attempts) != 1 || attempts[0].Username != "deepzz" {
+		t.Errorf("LoadLoginAttemptList() = %d, %v, want only deepzz", total, attempts)
+	}
+
+	// 超过上限时淘汰最久未失败的记录
+	for i := 0; i <= maxLoginRecords; i++ {
+		c.LoginFailed(context.Background(), fmt.Sprintf("ip-%d", i), "a", "ua", "password")
+	}
+	loginLock.Lock()
+	n, first, last := len(loginRecords), loginRecordOf("ip:ip-0"), loginRecordOf(fmt.Sprintf("ip:ip-%d", maxLoginRecords))
+	loginLock.Unlock()
+	if n != maxLoginRecords || loginLRU.Len() != maxLoginRecords {
+		t.Errorf("login records = %d, want %d", n, maxLoginRecords)
+	}
+	if first != nil || last == nil {
+		t.Errorf("oldest record kept = %v, newest record kept = %v", first != nil, last != nil)
+	}
+}
+
+func TestLoginAlert(t *testing.T) {
+	tests := []struct {
+		alertAt  int
+		failures int
+		alerts   int
+	}{
+		{0, 10, 0},
+		{5, 4, 0},
+		{5, 5, 1},
+		{5, 10, 1}, // 只在达到阈值时告警一次
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.failures, tt.alertAt), func(t *testing.T) {
+			setLoginLimit(t, tt.alertAt)
+			c := newTestCache(t)
+			mails := enableMail(t, c)
+			for i := 0; i < tt.failures; i++ {
+				c.LoginFailed(context.Background(), "1.1.1.1", "a", "ua", "password")
+			}
+			c.jobs.Wait()
+			if got := len(mails.sent()); got != tt.alerts {
+				t.Errorf("sent %d alerts, want %d", got, tt.alerts)
+			}
+		})
+	}
+}
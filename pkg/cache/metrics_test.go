@@ -0,0 +1,81 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// storeOps 存储操作直方图中method及result的样本数
+func storeOps(t *testing.T, method, result string) uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "eiblog_store_operation_duration_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["method"] == method && labels["result"] == result {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func TestStoreMetrics(t *testing.T) {
+	c := newTestCache(t)
+
+	tests := []struct {
+		method string
+		result string
+		call   func() error
+	}{
+		{"LoadAllSerie", "success", func() error {
+			_, err := c.LoadAllSerie(context.Background())
+			return err
+		}},
+		// 记录不存在不算失败
+		{"LoadSubscriber", "success", func() error {
+			_, err := c.LoadSubscriber(context.Background(), "none@example.com")
+			if err != store.ErrNotFound {
+				t.Errorf("LoadSubscriber() error = %v, want ErrNotFound", err)
+			}
+			return nil
+		}},
+		// 缩略名重复
+		{"InsertSerie", "error", func() error {
+			err := c.InsertSerie(context.Background(), &model.Serie{Slug: "dup", Name: "dup"})
+			if err != nil {
+				return err
+			}
+			if err = c.InsertSerie(context.Background(), &model.Serie{Slug: "dup", Name: "dup"}); err == nil {
+				t.Error("InsertSerie() duplicate slug succeeded")
+			}
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			before := storeOps(t, tt.method, tt.result)
+			if err := tt.call(); err != nil {
+				t.Fatal(err)
+			}
+			if got := storeOps(t, tt.method, tt.result) - before; got != 1 {
+				t.Errorf("%s{result=%s} count += %d, want 1", tt.method, tt.result, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 推送方式
+const (
+	NewsletterPost   = "post"   // 发布即推送
+	NewsletterDigest = "digest" // 定期推送摘要
+)
+
+// 订阅限制, 避免被用于向他人大量发送邮件
+const (
+	subscribeWindow   = time.Hour        // 计数窗口
+	subscribeMaxIP    = 5                // 每个IP窗口内最多请求次数
+	subscribeMaxEmail = 3                // 每个地址窗口内最多发送确认邮件次数
+	subscribeResend   = 10 * time.Minute // 未确认时, 间隔内不重复发送
+	subscribeTokenTTL = 48 * time.Hour   // 确认链接有效期
+)
+
+var (
+	// ErrInvalidToken 订阅token无效或已过期
+	ErrInvalidToken = errors.New("newsletter: invalid token")
+	// ErrSubscribeThrottled 订阅请求过于频繁
+	ErrSubscribeThrottled = errors.New("newsletter: too many requests")
+)
+
+var (
+	subscribeLock sync.Mutex
+	subscribeHits = make(map[string][]time.Time) // ip或邮件地址:窗口内请求时间
+)
+
+// Subscribe 订阅, 发送确认邮件. 已订阅或刚发送过时直接返回
+func (c *Cache) Subscribe(ctx context.Context, email, ip string) error {
+	if !subscribeAllow("ip:"+ip, subscribeMaxIP) {
+		return ErrSubscribeThrottled
+	}
+	sub, err := c.LoadSubscriber(ctx, email)
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+	if sub != nil && sub.Status == model.SubscriberActive {
+		return nil
+	}
+	now := time.Now()
+	if sub != nil && sub.Status == model.SubscriberPending && now.Sub(sub.SentAt) < subscribeResend {
+		return nil
+	}
+	if !subscribeAllow("email:"+email, subscribeMaxEmail) {
+		return ErrSubscribeThrottled
+	}
+	token := newsletterToken()
+	if sub == nil {
+		sub = &model.Subscriber{
+			Email:     email,
+			Token:     token,
+			Status:    model.SubscriberPending,
+			IP:        ip,
+			SentAt:    now,
+			CreatedAt: now,
+		}
+		err = c.InsertSubscriber(ctx, sub)
+	} else {
+		sub.Token = token
+		sub.Status = model.SubscriberPending
+		err = c.UpdateSubscriber(ctx, email, map[string]interface{}{
+			"token":   token,
+			"status":  model.SubscriberPending,
+			"ip":      ip,
+			"sent_at": now,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	subject, body, err := internal.MailRender(internal.MailSubscribe, map[string]interface{}{
+		"BTitle":     c.Blogger.BTitle,
+		"Email":      email,
+		"Home":       newsletterURL("/"),
+		"ConfirmURL": newsletterURL("/newsletter/confirm?token=" + token),
+	})
+	if err != nil {
+		return err
+	}
+	return internal.MailSend(internal.MailParams{
+		To:        []string{email},
+		Subject:   subject,
+		Body:      body,
+		Conf:      config.Conf.EiBlogApp.SMTP,
+		Transport: c.Mail,
+	})
+}
+
+// subscribeAllow 窗口内请求次数未达到max时记录本次请求
+func subscribeAllow(key string, max int) bool {
+	subscribeLock.Lock()
+	defer subscribeLock.Unlock()
+
+	now := time.Now()
+	var hits []time.Time
+	for _, t := range subscribeHits[key] {
+		if now.Sub(t) < subscribeWindow {
+			hits = append(hits, t)
+		}
+	}
+	if len(hits) >= max {
+		subscribeHits[key] = hits
+		return false
+	}
+	subscribeHits[key] = append(hits, now)
+	// 清理过期记录
+	if len(subscribeHits) > 1024 {
+		for k, v := range subscribeHits {
+			if now.Sub(v[len(v)-1]) >= subscribeWindow {
+				delete(subscribeHits, k)
+			}
+		}
+	}
+	return true
+}
+
+// ConfirmSubscriber 确认订阅
+func (c *Cache) ConfirmSubscriber(ctx context.Context, token string) (*model.Subscriber, error) {
+	sub, err := c.findSubscriber(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == model.SubscriberUnsubscribed {
+		return nil, ErrInvalidToken
+	}
+	if sub.Status == model.SubscriberActive {
+		return sub, nil
+	}
+	if time.Since(sub.SentAt) > subscribeTokenTTL {
+		return nil, ErrInvalidToken
+	}
+	sub.Status = model.SubscriberActive
+	sub.ConfirmedAt = time.Now()
+	err = c.UpdateSubscriber(ctx, sub.Email, map[string]interface{}{
+		"status":       sub.Status,
+		"confirmed_at": sub.ConfirmedAt,
+	})
+	return sub, err
+}
+
+// Unsubscribe 退订
+func (c *Cache) Unsubscribe(ctx context.Context, token string) (*model.Subscriber, error) {
+	sub, err := c.findSubscriber(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == model.SubscriberUnsubscribed {
+		return sub, nil
+	}
+	sub.Status = model.SubscriberUnsubscribed
+	err = c.UpdateSubscriber(ctx, sub.Email, map[string]interface{}{
+		"status": sub.Status,
+	})
+	return sub, err
+}
+
+// findSubscriber 通过token查找订阅者
+func (c *Cache) findSubscriber(ctx context.Context, token string) (*model.Subscriber, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+	sub, err := c.LoadSubscriberByToken(ctx, token)
+	if err == store.ErrNotFound {
+		return nil, ErrInvalidToken
+	}
+	return sub, err
+}
+
+// newsletterPost 新文章发布后推送给订阅者
+func (c *Cache) newsletterPost(event Event) {
+	conf := config.Conf.EiBlogApp.Newsletter
+	if !conf.Enable || conf.Mode != NewsletterPost {
+		return
+	}
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	// 专题页、友链等不推送
+	if id, _ := data["id"].(int); id < ArticleStartID {
+		return
+	}
+	err := c.sendNewsletter(internal.MailPost, map[string]interface{}{
+		"Title":   data["title"],
+		"URL":     data["url"],
+		"Excerpt": data["excerpt"],
+	})
+	if err != nil {
+		logrus.Error("cache.newsletterPost.sendNewsletter: ", err)
+	}
+	c.NotifyJob("newsletter", err)
+}
+
+// timerNewsletter 定期推送文章摘要
+func (c *Cache) timerNewsletter() {
+	conf := config.Conf.EiBlogApp.Newsletter
+	if !conf.Enable || conf.Mode != NewsletterDigest {
+		return
+	}
+	interval := 7 * 24 * time.Hour
+	if conf.Interval != "" {
+		d, err := tools.ParseDuration(conf.Interval)
+		if err != nil {
+			logrus.Error("cache.timerNewsletter.ParseDuration: ", err)
+			return
+		}
+		interval = d
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+		since := now.Add(-interval)
+		var articles []map[string]interface{}
+		c.lock.Lock()
+		for _, article := range c.Articles {
+			if article.CreatedAt.After(since) {
+				articles = append(articles, EventArticle(article))
+			}
+		}
+		c.lock.Unlock()
+		if len(articles) == 0 {
+			continue
+		}
+		err := c.sendNewsletter(internal.MailDigest, map[string]interface{}{
+			"Articles": articles,
+		})
+		if err != nil {
+			logrus.Error("cache.timerNewsletter.sendNewsletter: ", err)
+		}
+		c.NotifyJob("newsletter", err)
+	}
+}
+
+// sendNewsletter 逐个发送给已确认的订阅者, 每封邮件带各自的退订链接
+func (c *Cache) sendNewsletter(name string, data map[string]interface{}) error {
+	subs, err := c.LoadAllSubscriber(context.Background())
+	if err != nil {
+		return err
+	}
+	data["BTitle"] = c.Blogger.BTitle
+	data["Home"] = newsletterURL("/")
+
+	var failed int
+	for _, sub := range subs {
+		if sub.Status != model.SubscriberActive {
+			continue
+		}
+		unsubscribe := newsletterURL("/newsletter/unsubscribe?token=" + sub.Token)
+		data["UnsubscribeURL"] = unsubscribe
+		subject, body, err := internal.MailRender(name, data)
+		if err != nil {
+			return err
+		}
+		err = internal.MailSend(internal.MailParams{
+			To:      []string{sub.Email},
+			Subject: subject,
+			Body:    body,
+			// RFC 8058 一键退订
+			Header: map[string]string{
+				"List-Unsubscribe":      "<" + unsubscribe + ">",
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			},
+			Conf:      config.Conf.EiBlogApp.SMTP,
+			Transport: c.Mail,
+		})
+		if err != nil {
+			logrus.Errorf("cache.sendNewsletter.MailSend: %s: %v", sub.Email, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("newsletter: %d mails failed", failed)
+	}
+	return nil
+}
+
+// newsletterURL 站点链接
+func newsletterURL(path string) string {
+	return config.Conf.EiBlogApp.SiteURL("", path)
+}
+
+// newsletterToken 生成确认及退订token
+func newsletterToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,198 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/model"
+)
+
+// resetSubscribeHits 清除订阅计数
+func resetSubscribeHits(t *testing.T) {
+	subscribeLock.Lock()
+	subscribeHits = make(map[string][]time.Time)
+	subscribeLock.Unlock()
+	t.Cleanup(func() {
+		subscribeLock.Lock()
+		subscribeHits = make(map[string][]time.Time)
+		subscribeLock.Unlock()
+	})
+}
+
+func TestSubscribeConfirm(t *testing.T) {
+	resetSubscribeHits(t)
+	c := newTestCache(t)
+	mails := enableMail(t, c)
+	ctx := context.Background()
+
+	err := c.Subscribe(ctx, "a@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatal("Subscribe() error: ", err)
+	}
+	sub, err := c.LoadSubscriber(ctx, "a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Status != model.SubscriberPending || sub.SentAt.IsZero() {
+		t.Errorf("subscriber = %+v, want pending with sent_at", sub)
+	}
+	// 未确认时不重复发送, token不变
+	err = c.Subscribe(ctx, "a@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatal("Subscribe() again error: ", err)
+	}
+	if got := mails.sent(); !reflect.DeepEqual(got, []string{"a@example.com"}) {
+		t.Errorf("sent = %v, want one confirmation", got)
+	}
+	again, _ := c.LoadSubscriber(ctx, "a@example.com")
+	if again.Token != sub.Token {
+		t.Error("Subscribe() again changed the token")
+	}
+
+	if _, err = c.ConfirmSubscriber(ctx, "wrong"); err != ErrInvalidToken {
+		t.Errorf("ConfirmSubscriber(wrong) error = %v, want ErrInvalidToken", err)
+	}
+	confirmed, err := c.ConfirmSubscriber(ctx, sub.Token)
+	if err != nil || confirmed.Status != model.SubscriberActive {
+		t.Fatalf("ConfirmSubscriber() = %+v, %v", confirmed, err)
+	}
+	// 已订阅时不再发送
+	err = c.Subscribe(ctx, "a@example.com", "10.0.0.1")
+	if err != nil || len(mails.sent()) != 1 {
+		t.Errorf("Subscribe() active = %v, sent %v", err, mails.sent())
+	}
+}
+
+func TestConfirmExpired(t *testing.T) {
+	resetSubscribeHits(t)
+	c := newTestCache(t)
+	enableMail(t, c)
+	ctx := context.Background()
+
+	err := c.Subscribe(ctx, "a@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, _ := c.LoadSubscriber(ctx, "a@example.com")
+	err = c.UpdateSubscriber(ctx, sub.Email, map[string]interface{}{
+		"sent_at": time.Now().Add(-subscribeTokenTTL - time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ConfirmSubscriber(ctx, sub.Token); err != ErrInvalidToken {
+		t.Errorf("ConfirmSubscriber() expired error = %v, want ErrInvalidToken", err)
+	}
+	// 过期后可重新发送
+	err = c.Subscribe(ctx, "a@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resent, _ := c.LoadSubscriber(ctx, "a@example.com")
+	if resent.Token == sub.Token {
+		t.Error("Subscribe() after expiry kept the old token")
+	}
+	if _, err = c.ConfirmSubscriber(ctx, resent.Token); err != nil {
+		t.Error("ConfirmSubscriber() new token error: ", err)
+	}
+}
+
+func TestSubscribeThrottle(t *testing.T) {
+	resetSubscribeHits(t)
+	c := newTestCache(t)
+	mails := enableMail(t, c)
+	ctx := context.Background()
+
+	// 每个IP窗口内最多 subscribeMaxIP 次
+	for i := 0; i < subscribeMaxIP; i++ {
+		err := c.Subscribe(ctx, fmt.Sprintf("u%d@example.com", i), "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Subscribe() %d error: %v", i, err)
+		}
+	}
+	if err := c.Subscribe(ctx, "other@example.com", "10.0.0.1"); err != ErrSubscribeThrottled {
+		t.Errorf("Subscribe() over ip limit error = %v, want ErrSubscribeThrottled", err)
+	}
+	if len(mails.sent()) != subscribeMaxIP {
+		t.Errorf("sent %d mails, want %d", len(mails.sent()), subscribeMaxIP)
+	}
+
+	// 每个地址窗口内最多 subscribeMaxEmail 封, 不同IP也不行
+	resetSubscribeHits(t)
+	mails.to = nil
+	for i := 0; i < subscribeMaxEmail+1; i++ {
+		// 跳过重复发送间隔
+		c.UpdateSubscriber(ctx, "victim@example.com", map[string]interface{}{
+			"sent_at": time.Now().Add(-subscribeResend),
+		})
+		err := c.Subscribe(ctx, "victim@example.com", fmt.Sprintf("10.0.1.%d", i))
+		if i < subscribeMaxEmail && err != nil {
+			t.Fatalf("Subscribe() %d error: %v", i, err)
+		}
+		if i == subscribeMaxEmail && err != ErrSubscribeThrottled {
+			t.Errorf("Subscribe() over email limit error = %v, want ErrSubscribeThrottled", err)
+		}
+	}
+	if len(mails.sent()) != subscribeMaxEmail {
+		t.Errorf("sent %d mails to victim, want %d", len(mails.sent()), subscribeMaxEmail)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	resetSubscribeHits(t)
+	c := newTestCache(t)
+	enableMail(t, c)
+	ctx := context.Background()
+
+	c.Subscribe(ctx, "a@example.com", "10.0.0.1")
+	sub, _ := c.LoadSubscriber(ctx, "a@example.com")
+	c.ConfirmSubscriber(ctx, sub.Token)
+
+	if _, err := c.Unsubscribe(ctx, ""); err != ErrInvalidToken {
+		t.Errorf("Unsubscribe(empty) error = %v, want ErrInvalidToken", err)
+	}
+	got, err := c.Unsubscribe(ctx, sub.Token)
+	if err != nil || got.Status != model.SubscriberUnsubscribed {
+		t.Fatalf("Unsubscribe() = %+v, %v", got, err)
+	}
+	// 退订后确认链接失效
+	if _, err = c.ConfirmSubscriber(ctx, sub.Token); err != ErrInvalidToken {
+		t.Errorf("ConfirmSubscriber() after unsubscribe error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestSendNewsletter(t *testing.T) {
+	resetSubscribeHits(t)
+	c := newTestCache(t)
+	mails := enableMail(t, c)
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		c.Subscribe(ctx, email, "10.0.0.1")
+	}
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		sub, _ := c.LoadSubscriber(ctx, email)
+		c.ConfirmSubscriber(ctx, sub.Token)
+	}
+	sub, _ := c.LoadSubscriber(ctx, "b@example.com")
+	c.Unsubscribe(ctx, sub.Token)
+	mails.to = nil
+
+	err := c.sendNewsletter(internal.MailPost, map[string]interface{}{
+		"Title":   "Hello",
+		"URL":     "https://example.com/post/hello.html",
+		"Excerpt": "world",
+	})
+	if err != nil {
+		t.Fatal("sendNewsletter() error: ", err)
+	}
+	// 只发送给已确认且未退订的订阅者
+	if got := mails.sent(); !reflect.DeepEqual(got, []string{"a@example.com"}) {
+		t.Errorf("sent = %v, want [a@example.com]", got)
+	}
+}
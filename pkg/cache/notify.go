@@ -0,0 +1,67 @@
+// Package cache provides ...
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/sirupsen/logrus"
+)
+
+// failedJobs 处于失败状态的后台任务, 恢复前不重复通知
+var failedJobs sync.Map
+
+// NotifyMail 发送通知邮件给博主, 异步执行
+func (c *Cache) NotifyMail(name string, data map[string]interface{}) {
+	conf := config.Conf.EiBlogApp.SMTP
+	if !conf.Enable {
+		return
+	}
+	to := c.Account.Email
+	if to == "" {
+		to = config.Conf.EiBlogApp.Account.Email
+	}
+	if to == "" {
+		logrus.Warn("cache.NotifyMail: account email is empty, skip ", name)
+		return
+	}
+	data["BTitle"] = c.Blogger.BTitle
+	data["App"] = config.Conf.EiBlogApp.Name
+	c.goJob(func() {
+		subject, body, err := internal.MailRender(name, data)
+		if err == nil {
+			err = internal.MailSend(internal.MailParams{
+				To:        []string{to},
+				Subject:   subject,
+				Body:      body,
+				Conf:      conf,
+				Transport: c.Mail,
+			})
+		}
+		if err != nil {
+			logrus.Error("cache.NotifyMail.MailSend: ", err)
+		}
+	})
+}
+
+// NotifyJob 记录后台任务执行结果, 任务由成功变为失败时邮件通知
+func (c *Cache) NotifyJob(job string, err error) {
+	metrics.ObserveJob(job, err)
+	if err == nil {
+		failedJobs.Delete(job)
+		return
+	}
+	if _, loaded := failedJobs.LoadOrStore(job, err); loaded {
+		return
+	}
+	c.NotifyMail(internal.MailJobFailed, map[string]interface{}{
+		"Job":   job,
+		"Time":  time.Now().In(tools.TimeLocation).Format("2006-01-02 15:04:05"),
+		"Error": err.Error(),
+	})
+}
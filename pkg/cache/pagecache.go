@@ -0,0 +1,231 @@
+// Package cache provides ...
+package cache
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/tools"
+)
+
+// 页面缓存默认大小及时长
+const (
+	defaultPageCacheSize = 32 // MB
+	defaultPageCacheTTL  = time.Hour
+)
+
+// 页面缓存键前缀
+const (
+	pageKeyHome    = "home:"
+	pageKeyArticle = "post:"
+)
+
+// 专题及归档页的缓存键
+const (
+	PageKeySeries   = "series"
+	PageKeyArchives = "archives"
+)
+
+// PageKeyHome 首页第pn页的缓存键
+func PageKeyHome(pn int) string {
+	return pageKeyHome + strconv.Itoa(pn)
+}
+
+// PageKeyArticle 文章页的缓存键
+func PageKeyArticle(slug string) string {
+	return pageKeyArticle + slug
+}
+
+// PageCache 前台页面渲染结果缓存, 按大小淘汰最久未使用的页面, 内容变化时由Cache清除.
+// 方法对nil安全, 未开启时为nil
+type PageCache struct {
+	lock    sync.Mutex
+	maxSize int                      // 缓存大小上限, 单位字节
+	ttl     time.Duration            // 缓存时长
+	size    int                      // 当前大小
+	gen     uint64                   // 每次清除时递增
+	items   map[string]*list.Element // key:*pageEntry
+	lru     *list.List               // 最近使用的在前
+	hits    int64                    // 命中次数
+	misses  int64                    // 未命中次数
+}
+
+// pageEntry 缓存的页面
+type pageEntry struct {
+	key    string
+	body   []byte
+	expire time.Time
+}
+
+// PageCacheStats 页面缓存统计
+type PageCacheStats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+	Bytes   int
+}
+
+// HitRatio 命中率, 无请求时为0
+func (s PageCacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// NewPageCache maxSize 单位字节
+func NewPageCache(maxSize int, ttl time.Duration) *PageCache {
+	return &PageCache{
+		maxSize: maxSize,
+		ttl:     ttl,
+		items:   make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// newPageCache 按配置创建页面缓存
+func newPageCache(conf config.PageCache) (*PageCache, error) {
+	size := conf.MaxSize
+	if size == 0 {
+		size = defaultPageCacheSize
+	}
+	ttl := defaultPageCacheTTL
+	if conf.TTL != "" {
+		d, err := tools.ParseDuration(conf.TTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl = d
+	}
+	return NewPageCache(size<<20, ttl), nil
+}
+
+// Get 读取未过期的页面
+func (p *PageCache) Get(key string) ([]byte, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	elem, ok := p.items[key]
+	if ok && time.Now().After(elem.Value.(*pageEntry).expire) {
+		p.remove(elem)
+		ok = false
+	}
+	if !ok {
+		p.misses++
+		return nil, false
+	}
+	p.hits++
+	p.lru.MoveToFront(elem)
+	return elem.Value.(*pageEntry).body, true
+}
+
+// Gen 渲染前读取, 传给Set
+func (p *PageCache) Gen() uint64 {
+	if p == nil {
+		return 0
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.gen
+}
+
+// Set 缓存页面. 渲染期间发生过清除时(gen不一致)不缓存, 避免缓存旧内容
+func (p *PageCache) Set(key string, gen uint64, body []byte) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	size := len(key) + len(body)
+	if gen != p.gen || size > p.maxSize {
+		return
+	}
+	if elem, ok := p.items[key]; ok {
+		p.remove(elem)
+	}
+	p.items[key] = p.lru.PushFront(&pageEntry{
+		key:    key,
+		body:   body,
+		expire: time.Now().Add(p.ttl),
+	})
+	p.size += size
+	for p.size > p.maxSize {
+		p.remove(p.lru.Back())
+	}
+}
+
+// Purge 清除指定页面
+func (p *PageCache) Purge(keys ...string) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.gen++
+	for _, key := range keys {
+		if elem, ok := p.items[key]; ok {
+			p.remove(elem)
+		}
+	}
+}
+
+// PurgePrefix 清除指定前缀的页面, 如所有首页
+func (p *PageCache) PurgePrefix(prefix string) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.gen++
+	for key, elem := range p.items {
+		if strings.HasPrefix(key, prefix) {
+			p.remove(elem)
+		}
+	}
+}
+
+// PurgeAll 清除所有页面, 如博主信息, 评论数, 配置及模版变化时
+func (p *PageCache) PurgeAll() {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.gen++
+	p.items = make(map[string]*list.Element)
+	p.lru.Init()
+	p.size = 0
+}
+
+// Stats 命中次数及缓存大小
+func (p *PageCache) Stats() PageCacheStats {
+	if p == nil {
+		return PageCacheStats{}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return PageCacheStats{
+		Hits:    p.hits,
+		Misses:  p.misses,
+		Entries: len(p.items),
+		Bytes:   p.size,
+	}
+}
+
+// remove 需持有锁
+func (p *PageCache) remove(elem *list.Element) {
+	entry := p.lru.Remove(elem).(*pageEntry)
+	delete(p.items, entry.key)
+	p.size -= len(entry.key) + len(entry.body)
+}
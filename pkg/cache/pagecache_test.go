@@ -0,0 +1,265 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/model"
+)
+
+func TestPageCacheLRU(t *testing.T) {
+	body := []byte(strings.Repeat("x", 97))
+	// 每个页面100字节, 最多缓存3个
+	p := NewPageCache(300, time.Hour)
+	gen := p.Gen()
+	for _, key := range []string{"p:a", "p:b", "p:c"} {
+		p.Set(key, gen, body)
+	}
+	// 访问a后, b为最久未使用
+	if _, ok := p.Get("p:a"); !ok {
+		t.Fatal("Get(p:a) missed")
+	}
+	p.Set("p:d", gen, body)
+	for key, want := range map[string]bool{"p:a": true, "p:b": false, "p:c": true, "p:d": true} {
+		if _, ok := p.Get(key); ok != want {
+			t.Errorf("Get(%s) = %v, want %v", key, ok, want)
+		}
+	}
+	if stats := p.Stats(); stats.Entries != 3 || stats.Bytes != 300 {
+		t.Errorf("Stats() = %+v, want 3 entries and 300 bytes", stats)
+	}
+
+	// 超过上限的页面不缓存
+	p.Set("p:big", gen, make([]byte, 300))
+	if _, ok := p.Get("p:big"); ok {
+		t.Error("Get(p:big) hit, want oversized page skipped")
+	}
+}
+
+func TestPageCacheTTL(t *testing.T) {
+	p := NewPageCache(1<<20, time.Hour)
+	p.Set("p:a", p.Gen(), []byte("a"))
+	if _, ok := p.Get("p:a"); !ok {
+		t.Fatal("Get(p:a) missed before expiry")
+	}
+	p.lock.Lock()
+	p.items["p:a"].Value.(*pageEntry).expire = time.Now().Add(-time.Second)
+	p.lock.Unlock()
+	if _, ok := p.Get("p:a"); ok {
+		t.Error("Get(p:a) hit after expiry")
+	}
+	if stats := p.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Stats() after expiry = %+v, want empty", stats)
+	}
+}
+
+func TestPageCacheGen(t *testing.T) {
+	purges := map[string]func(p *PageCache){
+		"Purge":       func(p *PageCache) { p.Purge("p:other") },
+		"PurgePrefix": func(p *PageCache) { p.PurgePrefix(pageKeyHome) },
+		"PurgeAll":    func(p *PageCache) { p.PurgeAll() },
+	}
+	for name, purge := range purges {
+		t.Run(name, func(t *testing.T) {
+			p := NewPageCache(1<<20, time.Hour)
+			// 渲染期间发生清除, 旧内容不缓存
+			gen := p.Gen()
+			purge(p)
+			p.Set("p:a", gen, []byte("old"))
+			if _, ok := p.Get("p:a"); ok {
+				t.Error("Set() with stale gen cached the page")
+			}
+			p.Set("p:a", p.Gen(), []byte("new"))
+			if body, ok := p.Get("p:a"); !ok || string(body) != "new" {
+				t.Errorf("Get(p:a) = %q, %v, want new", body, ok)
+			}
+		})
+	}
+}
+
+// drainPages 丢弃页面重建请求及事件, 避免阻塞
+func drainPages(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-PagesCh:
+			case <-EventsCh:
+			case <-done:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(done) })
+}
+
+// newPageTestCache 含专题1下文章a, b的缓存, b较新
+func newPageTestCache(t *testing.T) *Cache {
+	drainPages(t)
+	c := newTestCache(t)
+	c.Pages = NewPageCache(1<<20, time.Hour)
+	now := time.Now()
+	// 关于页, 文章列表末尾
+	c.Articles = model.SortedArticles{{ID: 1, Slug: "about", CreatedAt: now.Add(-time.Hour)}}
+	c.Series = model.SortedSeries{{ID: 1, Slug: "s"}}
+	for i, slug := range []string{"a", "b"} {
+		c.refreshCache(&model.Article{
+			ID:        ArticleStartID + i,
+			Slug:      slug,
+			SerieID:   1,
+			Tags:      []string{"go"},
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}, false)
+	}
+	return c
+}
+
+// fillPages 缓存所有页面
+func fillPages(c *Cache) []string {
+	keys := []string{PageKeyHome(1), PageKeyHome(2), PageKeySeries, PageKeyArchives,
+		PageKeyArticle("a"), PageKeyArticle("b"), PageKeyArticle("about")}
+	gen := c.Pages.Gen()
+	for _, key := range keys {
+		c.Pages.Set(key, gen, []byte(key))
+	}
+	return keys
+}
+
+// cachedPages 仍在缓存中的页面
+func cachedPages(c *Cache, keys []string) map[string]bool {
+	cached := make(map[string]bool)
+	for _, key := range keys {
+		if _, ok := c.Pages.Get(key); ok {
+			cached[key] = true
+		}
+	}
+	return cached
+}
+
+func TestPageCachePurgeEvents(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		event  func(t *testing.T, c *Cache)
+		cached []string // 未受影响的页面
+	}{
+		{"article updated", func(t *testing.T, c *Cache) {
+			article := c.ArticlesMap["a"]
+			edited := *article
+			edited.Title = "edited"
+			c.RepArticle(article, &edited)
+		}, []string{PageKeyArticle("about")}},
+		{"tags changed", func(t *testing.T, c *Cache) {
+			article := c.ArticlesMap["b"]
+			edited := *article
+			edited.Tags = []string{"rust"}
+			c.RepArticle(article, &edited)
+		}, []string{PageKeyArticle("about")}},
+		{"article trashed", func(t *testing.T, c *Cache) {
+			if err := c.DelArticle(ctx, ArticleStartID); err != nil {
+				t.Fatal(err)
+			}
+		}, []string{PageKeyArticle("about")}},
+		{"about updated", func(t *testing.T, c *Cache) {
+			c.RepArticle(nil, &model.Article{ID: 1, Slug: "about"})
+		}, []string{PageKeyHome(1), PageKeyHome(2), PageKeySeries, PageKeyArchives,
+			PageKeyArticle("a"), PageKeyArticle("b")}},
+		{"serie created", func(t *testing.T, c *Cache) {
+			if err := c.AddSerie(ctx, &model.Serie{Slug: "new", Name: "new"}); err != nil {
+				t.Fatal(err)
+			}
+		}, []string{PageKeyHome(1), PageKeyHome(2), PageKeyArchives,
+			PageKeyArticle("a"), PageKeyArticle("b"), PageKeyArticle("about")}},
+		{"serie updated", func(t *testing.T, c *Cache) {
+			if err := c.EditSerie(ctx, c.Series[0], "s", "renamed", ""); err != nil {
+				t.Fatal(err)
+			}
+		}, []string{PageKeyHome(1), PageKeyHome(2), PageKeyArchives, PageKeyArticle("about")}},
+		{"blogger updated", func(t *testing.T, c *Cache) {
+			if err := c.EditBlogger(ctx, &model.Blogger{BTitle: "new"}); err != nil {
+				t.Fatal(err)
+			}
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newPageTestCache(t)
+			keys := fillPages(c)
+			gen := c.Pages.Gen()
+			tt.event(t, c)
+
+			want := make(map[string]bool)
+			for _, key := range tt.cached {
+				want[key] = true
+			}
+			got := cachedPages(c, keys)
+			for _, key := range keys {
+				if got[key] != want[key] {
+					t.Errorf("%s cached = %v, want %v", key, got[key], want[key])
+				}
+			}
+			// 事件前开始渲染的页面不缓存
+			c.Pages.Set(PageKeyArticle("a"), gen, []byte("stale"))
+			if body, ok := c.Pages.Get(PageKeyArticle("a")); ok && string(body) == "stale" {
+				t.Error("page rendered before the event was cached")
+			}
+		})
+	}
+}
+
+func TestPublishedArticles(t *testing.T) {
+	c := newPageTestCache(t)
+
+	// 返回副本, 修改不影响缓存
+	all := c.PublishedArticles("")
+	if len(all) != 3 || len(c.PublishedArticles("go")) != 2 || len(c.PublishedArticles("none")) != 0 {
+		t.Fatalf("PublishedArticles() = %d articles, want 3", len(all))
+	}
+	all[0] = nil
+	if c.Articles[0] == nil {
+		t.Error("PublishedArticles() shares the cached slice")
+	}
+	if article := c.PublishedArticle(ArticleStartID, ""); article == nil || article.Slug != "a" {
+		t.Errorf("PublishedArticle(%d) = %v, want a", ArticleStartID, article)
+	}
+	if article := c.PublishedArticle(0, "b"); article == nil || article.ID != ArticleStartID+1 {
+		t.Errorf("PublishedArticle(b) = %v, want %d", article, ArticleStartID+1)
+	}
+
+	// 与发布及更新博客信息并发读取, 配合 go test -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			c.RepArticle(nil, &model.Article{
+				ID:        ArticleStartID + 10 + i,
+				Slug:      fmt.Sprint("c", i),
+				Tags:      []string{"go"},
+				CreatedAt: time.Now(),
+			})
+			c.EditBlogger(context.Background(), &model.Blogger{BTitle: fmt.Sprint("EiBlog ", i)})
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			if n := len(c.PublishedArticles("go")); n != 22 {
+				t.Errorf("PublishedArticles(go) = %d, want 22", n)
+			}
+			if blogger := c.BloggerInfo(); blogger.BTitle != "EiBlog 19" {
+				t.Errorf("BloggerInfo() = %q, want EiBlog 19", blogger.BTitle)
+			}
+			return
+		default:
+			for _, article := range c.PublishedArticles("go") {
+				_ = article.Slug
+			}
+			_ = c.BloggerInfo().BTitle
+			_ = c.TagCounts()["go"]
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package render provides ...
+package render
+
+import (
+	"sync"
+
+	"github.com/eiblog/blackfriday"
+)
+
+// blackfriday 配置
+const (
+	commonHTMLFlags = 0 |
+		blackfriday.HTML_TOC |
+		blackfriday.HTML_USE_XHTML |
+		blackfriday.HTML_USE_SMARTYPANTS |
+		blackfriday.HTML_SMARTYPANTS_FRACTIONS |
+		blackfriday.HTML_SMARTYPANTS_DASHES |
+		blackfriday.HTML_SMARTYPANTS_LATEX_DASHES |
+		blackfriday.HTML_NOFOLLOW_LINKS
+
+	commonExtensions = 0 |
+		blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+		blackfriday.EXTENSION_TABLES |
+		blackfriday.EXTENSION_FENCED_CODE |
+		blackfriday.EXTENSION_AUTOLINK |
+		blackfriday.EXTENSION_STRIKETHROUGH |
+		blackfriday.EXTENSION_SPACE_HEADERS |
+		blackfriday.EXTENSION_HEADER_IDS |
+		blackfriday.EXTENSION_BACKSLASH_LINE_BREAK |
+		blackfriday.EXTENSION_DEFINITION_LISTS
+)
+
+// blackfridayRenderer eiblog/blackfriday, 标题ID为toc_N
+type blackfridayRenderer struct {
+	// 目录生成使用包级变量记录层级, 需串行渲染
+	lock sync.Mutex
+}
+
+// NewBlackfriday blackfriday渲染器, 默认
+func NewBlackfriday() Renderer {
+	return &blackfridayRenderer{}
+}
+
+// Render 有标题时输出以目录开头
+func (r *blackfridayRenderer) Render(md []byte) []byte {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	renderer := blackfriday.HtmlRenderer(commonHTMLFlags, "", "")
+	return blackfriday.Markdown(md, renderer, commonExtensions)
+}
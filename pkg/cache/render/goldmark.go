@@ -0,0 +1,247 @@
+// Package render provides ...
+package render
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// goldmarkRenderer CommonMark及GFM(表格, 删除线, 自动链接, 任务列表), 脚注, 定义列表,
+// 标题ID, 目录及懒加载图片格式与blackfriday相同
+type goldmarkRenderer struct {
+	md goldmark.Markdown
+}
+
+// NewGoldmark goldmark渲染器
+func NewGoldmark() Renderer {
+	return &goldmarkRenderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(
+				extension.GFM,
+				extension.Footnote,
+				extension.DefinitionList,
+				extension.Typographer,
+			),
+			goldmark.WithParserOptions(
+				parser.WithAutoHeadingID(),
+				parser.WithASTTransformers(util.Prioritized(nofollowTransformer{}, 100)),
+				// 先于链接解析
+				parser.WithInlineParsers(util.Prioritized(lazyImageParser{}, 199)),
+			),
+			goldmark.WithRendererOptions(
+				renderer.WithNodeRenderers(util.Prioritized(lazyImageRenderer{}, 100)),
+				html.WithXHTML(),
+				html.WithUnsafe(), // 保留html, 如摘要标识 <!--more-->
+			),
+		),
+	}
+}
+
+// Render 有标题时输出以目录开头
+func (r *goldmarkRenderer) Render(md []byte) []byte {
+	ctx := parser.NewContext(parser.WithIDs(&headingIDs{}))
+	doc := r.md.Parser().Parse(text.NewReader(md), parser.WithContext(ctx))
+
+	var content bytes.Buffer
+	err := r.md.Renderer().Render(&content, md, doc)
+	if err != nil {
+		// 写入bytes.Buffer不会出错
+		panic(err)
+	}
+	toc := r.toc(doc, md)
+	if len(toc) == 0 {
+		return content.Bytes()
+	}
+	var out bytes.Buffer
+	out.WriteString(tocBegin)
+	out.Write(toc)
+	out.WriteString(tocEnd)
+	out.WriteByte('\n')
+	out.Write(content.Bytes())
+	return out.Bytes()
+}
+
+// toc 按标题层级生成目录, 以第一个标题的层级为顶层, 同blackfriday
+func (r *goldmarkRenderer) toc(doc ast.Node, source []byte) []byte {
+	var (
+		toc   bytes.Buffer
+		level int // 当前层级, 相对于off
+		off   int
+	)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		if level == 0 {
+			off = heading.Level - 1
+		}
+		target := heading.Level
+		// 比第一个标题层级高时作为顶层
+		if target <= off {
+			target = off + 1
+		}
+		for target > level+off {
+			switch {
+			case bytes.HasSuffix(toc.Bytes(), []byte("</li>\n")):
+				toc.Truncate(toc.Len() - len("</li>\n"))
+			case level > 0:
+				toc.WriteString("<li>")
+			}
+			if toc.Len() > 0 {
+				toc.WriteByte('\n')
+			}
+			toc.WriteString("<ul>\n")
+			level++
+		}
+		for target < level+off {
+			toc.WriteString("</ul>")
+			if level > 1 {
+				toc.WriteString("</li>\n")
+			}
+			level--
+		}
+		id, _ := heading.AttributeString("id")
+		toc.WriteString(`<li><a href="#`)
+		if b, ok := id.([]byte); ok {
+			toc.Write(util.EscapeHTML(b))
+		}
+		toc.WriteString(`">`)
+		for c := heading.FirstChild(); c != nil; c = c.NextSibling() {
+			r.md.Renderer().Render(&toc, source, c)
+		}
+		toc.WriteString("</a></li>\n")
+		return ast.WalkSkipChildren, nil
+	})
+	for ; level > 1; level-- {
+		toc.WriteString("</ul></li>\n")
+	}
+	if level > 0 {
+		toc.WriteString("</ul>\n")
+	}
+	return toc.Bytes()
+}
+
+// headingIDs 标题ID同blackfriday, 按出现顺序为toc_N, 切换渲染器后站外引用的锚点不变
+type headingIDs struct {
+	next int
+}
+
+// Generate 生成下一个ID
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	id := "toc_" + strconv.Itoa(s.next)
+	s.next++
+	return []byte(id)
+}
+
+// Put 未开启标题属性, 不会指定ID
+func (s *headingIDs) Put(value []byte) {}
+
+// nofollowTransformer 站外链接添加 rel="nofollow", 同blackfriday
+type nofollowTransformer struct{}
+
+// Transform 处理链接及自动链接
+func (nofollowTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch link := n.(type) {
+		case *ast.Link:
+			if !isRelativeLink(link.Destination) {
+				link.SetAttributeString("rel", []byte("nofollow"))
+			}
+		case *ast.AutoLink:
+			if link.AutoLinkType == ast.AutoLinkURL && !isRelativeLink(link.URL(source)) {
+				link.SetAttributeString("rel", []byte("nofollow"))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// isRelativeLink 站内链接: 锚点, 绝对路径及相对路径, 同blackfriday
+func isRelativeLink(link []byte) bool {
+	switch {
+	case len(link) == 0:
+		return true
+	case link[0] == '#':
+		return true
+	case link[0] == '/':
+		return len(link) == 1 || link[1] != '/'
+	}
+	return bytes.HasPrefix(link, []byte("./")) || bytes.HasPrefix(link, []byte("../"))
+}
+
+// regLazyImage 懒加载图片 ![alt](src =widthxheight)
+var regLazyImage = regexp.MustCompile(`^!\[([^\]]*)\]\((\S+) =(\d+)x(\d+)\)`)
+
+// kindLazyImage 懒加载图片节点类型
+var kindLazyImage = ast.NewNodeKind("LazyImage")
+
+// lazyImage 懒加载图片, 页面加载时按宽高占位
+type lazyImage struct {
+	ast.BaseInline
+	src, alt      []byte
+	width, height []byte
+}
+
+// Kind 节点类型
+func (n *lazyImage) Kind() ast.NodeKind { return kindLazyImage }
+
+// Dump 调试输出
+func (n *lazyImage) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Src": string(n.src)}, nil)
+}
+
+// lazyImageParser 解析懒加载图片, 不匹配时交由链接解析
+type lazyImageParser struct{}
+
+// Trigger 触发字符
+func (lazyImageParser) Trigger() []byte { return []byte{'!'} }
+
+// Parse 匹配整个图片语法
+func (lazyImageParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	m := regLazyImage.FindSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	block.Advance(len(m[0]))
+	return &lazyImage{src: m[2], alt: m[1], width: m[3], height: m[4]}
+}
+
+// lazyImageRenderer 输出同blackfriday, 由前端脚本加载data-src
+type lazyImageRenderer struct{}
+
+// RegisterFuncs 注册渲染函数
+func (lazyImageRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindLazyImage, func(w util.BufWriter, source []byte, n ast.Node,
+		entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		img := n.(*lazyImage)
+		w.WriteString(`<img width="`)
+		w.Write(img.width)
+		w.WriteString(`" height="`)
+		w.Write(img.height)
+		w.WriteString(`" data-src="`)
+		w.Write(util.EscapeHTML(util.URLEscape(img.src, true)))
+		w.WriteString(`" alt="`)
+		w.Write(util.EscapeHTML(img.alt))
+		w.WriteString(`" />`)
+		return ast.WalkSkipChildren, nil
+	})
+}
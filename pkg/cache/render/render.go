@@ -2,50 +2,56 @@
 package render
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/model"
 	"github.com/eiblog/eiblog/tools"
-
-	"github.com/eiblog/blackfriday"
 )
 
-// blackfriday 配置
+// 可选的渲染器, 见 eiblogapp.general.markdown
 const (
-	commonHTMLFlags = 0 |
-		blackfriday.HTML_TOC |
-		blackfriday.HTML_USE_XHTML |
-		blackfriday.HTML_USE_SMARTYPANTS |
-		blackfriday.HTML_SMARTYPANTS_FRACTIONS |
-		blackfriday.HTML_SMARTYPANTS_DASHES |
-		blackfriday.HTML_SMARTYPANTS_LATEX_DASHES |
-		blackfriday.HTML_NOFOLLOW_LINKS
+	Blackfriday = "blackfriday"
+	Goldmark    = "goldmark"
+)
 
-	commonExtensions = 0 |
-		blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
-		blackfriday.EXTENSION_TABLES |
-		blackfriday.EXTENSION_FENCED_CODE |
-		blackfriday.EXTENSION_AUTOLINK |
-		blackfriday.EXTENSION_STRIKETHROUGH |
-		blackfriday.EXTENSION_SPACE_HEADERS |
-		blackfriday.EXTENSION_HEADER_IDS |
-		blackfriday.EXTENSION_BACKSLASH_LINE_BREAK |
-		blackfriday.EXTENSION_DEFINITION_LISTS
+// 目录, 位于输出开头, 见 GenerateExcerptMarkdown
+const (
+	tocBegin = "<div id=\"toc-container\"><nav id=\"toc\"><p><strong>预览目录</strong></p>\n"
+	tocEnd   = "</nav></div>\n"
 )
 
+// Renderer markdown渲染器, 有标题时输出以目录开头, 需可并发使用
+type Renderer interface {
+	Render(md []byte) []byte
+}
+
+// New 按名称创建渲染器, 为空时为blackfriday
+func New(name string) (Renderer, error) {
+	switch name {
+	case "", Blackfriday:
+		return NewBlackfriday(), nil
+	case Goldmark:
+		return NewGoldmark(), nil
+	}
+	return nil, fmt.Errorf("render: unknown markdown renderer %q", name)
+}
+
 var (
 	// 渲染markdown操作和截取摘要操作
 	regIdentifier = regexp.MustCompile(config.Conf.EiBlogApp.General.Identifier)
 	// header
 	regHeader = regexp.MustCompile("</nav></div>")
+
+	// current 配置的渲染器, 配置已校验
+	current, _ = New(config.Conf.EiBlogApp.General.Markdown)
 )
 
-// PageRender 渲染markdown
+// PageRender 使用配置的渲染器渲染markdown
 func PageRender(md []byte) []byte {
-	renderer := blackfriday.HtmlRenderer(commonHTMLFlags, "", "")
-	return blackfriday.Markdown(md, renderer, commonExtensions)
+	return current.Render(md)
 }
 
 // GenerateExcerptMarkdown 生成预览和描述
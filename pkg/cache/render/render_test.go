@@ -0,0 +1,169 @@
+// Package render provides ...
+package render
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update testdata/compat golden files")
+
+var (
+	// regTOCEntry 目录条目的链接文字
+	regTOCEntry = regexp.MustCompile(`<li><a href="#[^"]*">(.*?)</a>`)
+	// regTOCHref 目录条目的锚点
+	regTOCHref = regexp.MustCompile(`<li><a href="#([^"]*)">`)
+	// regHeadingID 标题ID
+	regHeadingID = regexp.MustCompile(`<h[1-6] id="([^"]*)"`)
+)
+
+// TestCompat 渲染 testdata/compat 下的文章, 与各渲染器的输出比较, 并检查两者的目录一致,
+// 输出差异见 go test -run TestCompat -v
+func TestCompat(t *testing.T) {
+	files, err := filepath.Glob("testdata/compat/*.md")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no compat corpus: ", err)
+	}
+	renderers := []string{Blackfriday, Goldmark}
+	for _, file := range files {
+		md, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.TrimSuffix(file, ".md")
+		outputs := make(map[string][]byte)
+		for _, r := range renderers {
+			renderer, err := New(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := renderer.Render(md)
+			outputs[r] = out
+
+			golden := name + "." + r + ".html"
+			if *update {
+				if err = os.WriteFile(golden, out, 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, want) {
+				t.Errorf("%s: %s output differs from %s", file, r, golden)
+			}
+		}
+
+		bf, gm := outputs[Blackfriday], outputs[Goldmark]
+		bfHeader, gmHeader := splitHeader(bf), splitHeader(gm)
+		if (bfHeader == "") != (gmHeader == "") {
+			t.Errorf("%s: toc presence differs, blackfriday %v, goldmark %v",
+				file, bfHeader != "", gmHeader != "")
+		}
+		bfEntries, gmEntries := tocEntries(bfHeader), tocEntries(gmHeader)
+		if strings.Join(bfEntries, "\n") != strings.Join(gmEntries, "\n") {
+			t.Errorf("%s: toc entries differ\nblackfriday: %q\ngoldmark:    %q",
+				file, bfEntries, gmEntries)
+		}
+		if bytes.Contains(md, []byte("<!--more-->")) && !bytes.Contains(gm, []byte("<!--more-->")) {
+			t.Errorf("%s: goldmark dropped the excerpt identifier", file)
+		}
+		logDiff(t, file, bf, gm)
+	}
+}
+
+// TestCompatAnchors 两种渲染器的标题ID及目录锚点相同, 切换渲染器后站外引用的锚点不变
+func TestCompatAnchors(t *testing.T) {
+	files, err := filepath.Glob("testdata/compat/*.md")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no compat corpus: ", err)
+	}
+	for _, file := range files {
+		md, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		for i, r := range []Renderer{NewBlackfriday(), NewGoldmark()} {
+			out := r.Render(md)
+			header := splitHeader(out)
+			ids := submatches(regHeadingID, string(out[len(header):]))
+			hrefs := submatches(regTOCHref, header)
+			// 目录锚点均指向标题
+			if strings.Join(hrefs, ",") != strings.Join(ids, ",") {
+				t.Errorf("%s: renderer %d toc anchors %q, heading ids %q", file, i, hrefs, ids)
+			}
+			if i == 0 {
+				want = ids
+			} else if strings.Join(ids, ",") != strings.Join(want, ",") {
+				t.Errorf("%s: goldmark heading ids %q, blackfriday %q", file, ids, want)
+			}
+		}
+	}
+}
+
+// submatches 各匹配的第一个分组
+func submatches(reg *regexp.Regexp, s string) []string {
+	var values []string
+	for _, m := range reg.FindAllStringSubmatch(s, -1) {
+		values = append(values, m[1])
+	}
+	return values
+}
+
+// splitHeader 同 GenerateExcerptMarkdown 截取目录
+func splitHeader(content []byte) string {
+	index := regHeader.FindIndex(content)
+	if index == nil {
+		return ""
+	}
+	return string(content[:index[1]])
+}
+
+// tocEntries 目录条目文字
+func tocEntries(header string) []string {
+	var entries []string
+	for _, m := range regTOCEntry.FindAllStringSubmatch(header, -1) {
+		entries = append(entries, m[1])
+	}
+	return entries
+}
+
+// logDiff 逐行输出两种渲染器的差异, 忽略空行
+func logDiff(t *testing.T, file string, bf, gm []byte) {
+	bfLines, gmLines := nonEmptyLines(bf), nonEmptyLines(gm)
+	var diff strings.Builder
+	for i := 0; i < len(bfLines) || i < len(gmLines); i++ {
+		var b, g string
+		if i < len(bfLines) {
+			b = bfLines[i]
+		}
+		if i < len(gmLines) {
+			g = gmLines[i]
+		}
+		if b != g {
+			diff.WriteString("- " + b + "\n+ " + g + "\n")
+		}
+	}
+	if diff.Len() > 0 {
+		t.Logf("%s: blackfriday(-) vs goldmark(+):\n%s", file, diff.String())
+	}
+}
+
+// nonEmptyLines 去除空行, blackfriday在块之间输出空行
+func nonEmptyLines(content []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,66 @@
+// Package cache provides ...
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/tools"
+)
+
+// 重置密码参数
+const (
+	passwordResetTTL      = 30 * time.Minute // 链接有效期
+	passwordResetInterval = time.Minute      // 发送间隔
+)
+
+// ErrResetThrottled 重置邮件发送过于频繁
+var ErrResetThrottled = errors.New("reset: too many requests")
+
+var (
+	resetLock   sync.Mutex
+	resetSentAt time.Time
+)
+
+// PasswordResetEnabled 是否可以通过邮件重置密码
+func (c *Cache) PasswordResetEnabled() bool {
+	return config.Conf.EiBlogApp.SMTP.Enable &&
+		(c.Account.Email != "" || config.Conf.EiBlogApp.Account.Email != "")
+}
+
+// SendPasswordReset 发送重置密码邮件, 链接绑定当前密码摘要, 修改密码后即失效
+func (c *Cache) SendPasswordReset(ip, ua string) error {
+	resetLock.Lock()
+	defer resetLock.Unlock()
+
+	now := time.Now()
+	if now.Sub(resetSentAt) < passwordResetInterval {
+		return ErrResetThrottled
+	}
+	resetSentAt = now
+	token := tools.SignToken(c.SessionSecret(), c.Account.Username,
+		now.Add(passwordResetTTL), "reset:"+c.Account.Password)
+	c.NotifyMail(internal.MailReset, map[string]interface{}{
+		"Username": c.Account.Username,
+		"URL":      config.Conf.EiBlogApp.SiteURL("", "/admin/reset?token="+token),
+		"Expire":   int(passwordResetTTL.Minutes()),
+		"IP":       ip,
+		"UA":       ua,
+	})
+	return nil
+}
+
+// VerifyPasswordReset 校验重置密码token, 返回用户名
+func (c *Cache) VerifyPasswordReset(token string) (string, error) {
+	user, err := tools.VerifyToken(c.SessionSecret(), token, time.Now(), "reset:"+c.Account.Password)
+	if err != nil {
+		return "", err
+	}
+	if user != c.Account.Username {
+		return "", tools.ErrTokenInvalid
+	}
+	return user, nil
+}
@@ -0,0 +1,158 @@
+// Package cache provides ...
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sessionTouchInterval 最近活动时间的更新间隔, 避免每次请求都写存储
+const sessionTouchInterval = time.Minute
+
+// SessionMaxAge 会话有效期
+func SessionMaxAge() time.Duration {
+	return sessionDuration(config.Conf.EiBlogApp.Session.MaxAge, 30*24*time.Hour)
+}
+
+// sessionIdle 无活动超时
+func sessionIdle() time.Duration {
+	return sessionDuration(config.Conf.EiBlogApp.Session.Idle, 7*24*time.Hour)
+}
+
+// sessionDuration 解析时长, 未配置或错误时使用默认值
+func sessionDuration(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+	d, err := tools.ParseDuration(s)
+	if err != nil {
+		logrus.Error("cache.sessionDuration.ParseDuration: ", err)
+		return def
+	}
+	return d
+}
+
+// SessionID 会话token的摘要, 存储及页面中只使用摘要
+func SessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SessionSecret cookie签名密钥, 优先使用配置
+func (c *Cache) SessionSecret() []byte {
+	if secret := config.Conf.EiBlogApp.Session.Secret; secret != "" {
+		return []byte(secret)
+	}
+	return []byte(c.Account.SessionSecret)
+}
+
+// CreateSession 创建登录会话, 返回保存在cookie中的token
+func (c *Cache) CreateSession(ctx context.Context, username, ip, ua string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	now := time.Now()
+	sess := &model.Session{
+		ID:        SessionID(token),
+		Username:  username,
+		IP:        ip,
+		UA:        ua,
+		LastSeen:  now,
+		ExpiresAt: now.Add(SessionMaxAge()),
+		CreatedAt: now,
+	}
+	err := c.Sessions.InsertSession(ctx, sess)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CheckSession 校验会话, 无效返回nil
+func (c *Cache) CheckSession(ctx context.Context, token string) *model.Session {
+	if token == "" {
+		return nil
+	}
+	sess, err := c.Sessions.LoadSession(ctx, SessionID(token))
+	if err != nil {
+		if err != store.ErrNotFound {
+			logrus.Error("cache.CheckSession.LoadSession: ", err)
+		}
+		return nil
+	}
+	now := time.Now()
+	if now.After(sess.ExpiresAt) || now.Sub(sess.LastSeen) > sessionIdle() {
+		err = c.Sessions.RemoveSession(ctx, sess.ID)
+		if err != nil {
+			logrus.Error("cache.CheckSession.RemoveSession: ", err)
+		}
+		return nil
+	}
+	if now.Sub(sess.LastSeen) > sessionTouchInterval {
+		sess.LastSeen = now
+		err = c.Sessions.TouchSession(ctx, sess.ID, now)
+		if err != nil {
+			logrus.Error("cache.CheckSession.TouchSession: ", err)
+		}
+	}
+	return sess
+}
+
+// RevokeSession 注销会话
+func (c *Cache) RevokeSession(ctx context.Context, id string) error {
+	return c.Sessions.RemoveSession(ctx, id)
+}
+
+// RevokeSessions 注销用户所有会话, except 为保留的会话ID
+func (c *Cache) RevokeSessions(ctx context.Context, username, except string) error {
+	sessions, err := c.Sessions.LoadSessions(ctx, username)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if sess.ID == except {
+			continue
+		}
+		err = c.Sessions.RemoveSession(ctx, sess.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListSessions 用户的有效会话
+func (c *Cache) ListSessions(ctx context.Context, username string) ([]*model.Session, error) {
+	sessions, err := c.Sessions.LoadSessions(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := sessions[:0]
+	for _, sess := range sessions {
+		if now.After(sess.ExpiresAt) || now.Sub(sess.LastSeen) > sessionIdle() {
+			continue
+		}
+		list = append(list, sess)
+	}
+	return list, nil
+}
+
+// newSessionSecret 生成cookie签名密钥
+func newSessionSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,132 @@
+// Package cache provides ...
+package cache
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/model"
+)
+
+func TestCheckSession(t *testing.T) {
+	setConfig(t, func(app *config.EiBlogApp) {
+		app.Session = config.Session{MaxAge: "720h", Idle: "168h"}
+	})
+	c := newTestCache(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		sess  model.Session
+		valid bool
+	}{
+		{"valid", model.Session{LastSeen: now, ExpiresAt: now.Add(time.Hour)}, true},
+		{"expired", model.Session{LastSeen: now, ExpiresAt: now.Add(-time.Second)}, false},
+		{"idle", model.Session{LastSeen: now.Add(-169 * time.Hour), ExpiresAt: now.Add(time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.name + "-token"
+			sess := tt.sess
+			sess.ID = SessionID(token)
+			sess.Username = "deepzz"
+			if err := c.Sessions.InsertSession(ctx, &sess); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.CheckSession(ctx, token) != nil; got != tt.valid {
+				t.Errorf("CheckSession() valid = %v, want %v", got, tt.valid)
+			}
+			// 失效的会话被删除
+			_, err := c.Sessions.LoadSession(ctx, sess.ID)
+			if !tt.valid && err != store.ErrNotFound {
+				t.Errorf("LoadSession() error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+
+	// 注销后失效
+	token, err := c.CreateSession(ctx, "deepzz", "1.1.1.1", "ua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := c.CheckSession(ctx, token)
+	if sess == nil || sess.ID != SessionID(token) || sess.IP != "1.1.1.1" {
+		t.Fatalf("CheckSession() = %+v, want created session", sess)
+	}
+	if err = c.RevokeSession(ctx, sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c.CheckSession(ctx, token) != nil {
+		t.Error("CheckSession() after revoke is valid")
+	}
+	if c.CheckSession(ctx, "") != nil {
+		t.Error("CheckSession(empty) is valid")
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	c := newTestCache(t)
+	ctx := context.Background()
+
+	var tokens []string
+	for _, username := range []string{"deepzz", "deepzz", "deepzz", "other"} {
+		token, err := c.CreateSession(ctx, username, "1.1.1.1", "ua")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	// 注销其他会话, 保留当前会话
+	err := c.RevokeSessions(ctx, "deepzz", SessionID(tokens[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, false, false, true} {
+		if got := c.CheckSession(ctx, tokens[i]) != nil; got != want {
+			t.Errorf("session %d valid = %v, want %v", i, got, want)
+		}
+	}
+	sessions, err := c.ListSessions(ctx, "deepzz")
+	if err != nil || len(sessions) != 1 || sessions[0].ID != SessionID(tokens[0]) {
+		t.Errorf("ListSessions() = %v, %v, want current session only", sessions, err)
+	}
+}
+
+func TestSessionSecret(t *testing.T) {
+	drainPages(t)
+	setConfig(t, func(app *config.EiBlogApp) { app.Session.Secret = "" })
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+
+	// 重启后使用数据库中的密钥
+	var secrets [][]byte
+	for i := 0; i < 2; i++ {
+		// newTestCache会替换全局数据库, 之后再打开
+		c := newTestCache(t)
+		stores, err := store.NewStore("sqlite", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Store, c.Sessions = stores, stores
+		if err = c.loadOrInit(); err != nil {
+			t.Fatal("loadOrInit() error: ", err)
+		}
+		secrets = append(secrets, c.SessionSecret())
+	}
+	if len(secrets[0]) == 0 || !bytes.Equal(secrets[0], secrets[1]) {
+		t.Errorf("SessionSecret() = %q then %q, want same secret", secrets[0], secrets[1])
+	}
+
+	// 配置优先
+	setConfig(t, func(app *config.EiBlogApp) { app.Session.Secret = "configured" })
+	c := newTestCache(t)
+	c.Account = &model.Account{SessionSecret: string(secrets[0])}
+	if got := string(c.SessionSecret()); got != "configured" {
+		t.Errorf("SessionSecret() = %q, want configured", got)
+	}
+}
@@ -0,0 +1,413 @@
+// Package store provides ...
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/logger"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/pkg/trace"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// metricsStore 记录各方法的span, 耗时, 结果及请求ID
+type metricsStore struct {
+	Store
+	system string // 存储驱动, 如: mongodb, sqlite
+}
+
+// metricsSessionStore 记录各方法的span, 耗时及结果
+type metricsSessionStore struct {
+	SessionStore
+	system string
+}
+
+// observe 开始存储操作的span, 返回的函数记录耗时, 结果及日志, 记录不存在不算失败
+func observe(ctx context.Context, system, method string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := trace.Start(ctx, "store."+method,
+		attribute.String("db.system", system),
+		attribute.String("db.operation", method),
+	)
+	return ctx, func(err *error) {
+		e := *err
+		if e == ErrNotFound {
+			e = nil
+		}
+		trace.End(span, e)
+		metrics.ObserveStore(method, start, e)
+		if e == nil && !logrus.IsLevelEnabled(logrus.DebugLevel) {
+			return
+		}
+		entry := logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method":  method,
+			"latency": time.Since(start).Seconds(),
+		})
+		if e != nil {
+			entry.Warn("store: ", e)
+			return
+		}
+		entry.Debug("store")
+	}
+}
+
+// LoadInsertBlogger implements Store
+func (s metricsStore) LoadInsertBlogger(ctx context.Context, blogger *model.Blogger) (r0 bool, err error) {
+	ctx, done := observe(ctx, s.system, "LoadInsertBlogger")
+	defer done(&err)
+	return s.Store.LoadInsertBlogger(ctx, blogger)
+}
+
+// UpdateBlogger implements Store
+func (s metricsStore) UpdateBlogger(ctx context.Context, fields map[string]interface{}) (err error) {
+	ctx, done := observe(ctx, s.system, "UpdateBlogger")
+	defer done(&err)
+	return s.Store.UpdateBlogger(ctx, fields)
+}
+
+// LoadInsertAccount implements Store
+func (s metricsStore) LoadInsertAccount(ctx context.Context, acct *model.Account) (r0 bool, err error) {
+	ctx, done := observe(ctx, s.system, "LoadInsertAccount")
+	defer done(&err)
+	return s.Store.LoadInsertAccount(ctx, acct)
+}
+
+// UpdateAccount implements Store
+func (s metricsStore) UpdateAccount(ctx context.Context, name string, fields map[string]interface{}) (err error) {
+	ctx, done := observe(ctx, s.system, "UpdateAccount")
+	defer done(&err)
+	return s.Store.UpdateAccount(ctx, name, fields)
+}
+
+// InsertSerie implements Store
+func (s metricsStore) InsertSerie(ctx context.Context, serie *model.Serie) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertSerie")
+	defer done(&err)
+	return s.Store.InsertSerie(ctx, serie)
+}
+
+// RemoveSerie implements Store
+func (s metricsStore) RemoveSerie(ctx context.Context, id int) (err error) {
+	ctx, done := observe(ctx, s.system, "RemoveSerie")
+	defer done(&err)
+	return s.Store.RemoveSerie(ctx, id)
+}
+
+// UpdateSerie implements Store
+func (s metricsStore) UpdateSerie(ctx context.Context, id int, fields map[string]interface{}) (err error) {
+	ctx, done := observe(ctx, s.system, "UpdateSerie")
+	defer done(&err)
+	return s.Store.UpdateSerie(ctx, id, fields)
+}
+
+// LoadAllSerie implements Store
+func (s metricsStore) LoadAllSerie(ctx context.Context) (r0 model.SortedSeries, err error) {
+	ctx, done := observe(ctx, s.system, "LoadAllSerie")
+	defer done(&err)
+	return s.Store.LoadAllSerie(ctx)
+}
+
+// InsertArticle implements Store
+func (s metricsStore) InsertArticle(ctx context.Context, article *model.Article, startID int) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertArticle")
+	defer done(&err)
+	return s.Store.InsertArticle(ctx, article, startID)
+}
+
+// RemoveArticle implements Store
+func (s metricsStore) RemoveArticle(ctx context.Context, id int) (err error) {
+	ctx, done := observe(ctx, s.system, "RemoveArticle")
+	defer done(&err)
+	return s.Store.RemoveArticle(ctx, id)
+}
+
+// CleanArticles implements Store
+func (s metricsStore) CleanArticles(ctx context.Context, exp time.Time) (err error) {
+	ctx, done := observe(ctx, s.system, "CleanArticles")
+	defer done(&err)
+	return s.Store.CleanArticles(ctx, exp)
+}
+
+// UpdateArticle implements Store
+func (s metricsStore) UpdateArticle(ctx context.Context, id int, fields map[string]interface{}) (err error) {
+	ctx, done := observe(ctx, s.system, "UpdateArticle")
+	defer done(&err)
+	return s.Store.UpdateArticle(ctx, id, fields)
+}
+
+// LoadArticle implements Store
+func (s metricsStore) LoadArticle(ctx context.Context, id int) (r0 *model.Article, err error) {
+	ctx, done := observe(ctx, s.system, "LoadArticle")
+	defer done(&err)
+	return s.Store.LoadArticle(ctx, id)
+}
+
+// LoadArticleList implements Store
+func (s metricsStore) LoadArticleList(ctx context.Context, search SearchArticles) (r0 model.SortedArticles, r1 int, err error) {
+	ctx, done := observe(ctx, s.system, "LoadArticleList")
+	defer done(&err)
+	return s.Store.LoadArticleList(ctx, search)
+}
+
+// InsertWebhook implements Store
+func (s metricsStore) InsertWebhook(ctx context.Context, hook *model.Webhook) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertWebhook")
+	defer done(&err)
+	return s.Store.InsertWebhook(ctx, hook)
+}
+
+// RemoveWebhook implements Store
+func (s metricsStore) RemoveWebhook(ctx context.Context, id int) (err error) {
+	ctx, done := observe(ctx, s.system, "RemoveWebhook")
+	defer done(&err)
+	return s.Store.RemoveWebhook(ctx, id)
+}
+
+// UpdateWebhook implements Store
+func (s metricsStore) UpdateWebhook(ctx context.Context, id int, fields map[string]interface{}) (err error) {
+	ctx, done := observe(ctx, s.system, "UpdateWebhook")
+	defer done(&err)
+	return s.Store.UpdateWebhook(ctx, id, fields)
+}
+
+// LoadAllWebhook implements Store
+func (s metricsStore) LoadAllWebhook(ctx context.Context) (r0 []*model.Webhook, err error) {
+	ctx, done := observe(ctx, s.system, "LoadAllWebhook")
+	defer done(&err)
+	return s.Store.LoadAllWebhook(ctx)
+}
+
+// InsertWebhookDelivery implements Store
+func (s metricsStore) InsertWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertWebhookDelivery")
+	defer done(&err)
+	return s.Store.InsertWebhookDelivery(ctx, delivery)
+}
+
+// LoadWebhookDeliveries implements Store
+func (s metricsStore) LoadWebhookDeliveries(ctx context.Context, id int, limit int) (r0 []*model.WebhookDelivery, err error) {
+	ctx, done := observe(ctx, s.system, "LoadWebhookDeliveries")
+	defer done(&err)
+	return s.Store.LoadWebhookDeliveries(ctx, id, limit)
+}
+
+// InsertSubscriber implements Store
+func (s metricsStore) InsertSubscriber(ctx context.Context, sub *model.Subscriber) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertSubscriber")
+	defer done(&err)
+	return s.Store.InsertSubscriber(ctx, sub)
+}
+
+// RemoveSubscriber implements Store
+func (s metricsStore) RemoveSubscriber(ctx context.Context, email string) (err error) {
+	ctx, done := observe(ctx, s.system, "RemoveSubscriber")
+	defer done(&err)
+	return s.Store.RemoveSubscriber(ctx, email)
+}
+
+// UpdateSubscriber implements Store
+func (s metricsStore) UpdateSubscriber(ctx context.Context, email string, fields map[string]interface{}) (err error) {
+	ctx, done := observe(ctx, s.system, "UpdateSubscriber")
+	defer done(&err)
+	return s.Store.UpdateSubscriber(ctx, email, fields)
+}
+
+// LoadSubscriber implements Store
+func (s metricsStore) LoadSubscriber(ctx context.Context, email string) (r0 *model.Subscriber, err error) {
+	ctx, done := observe(ctx, s.system, "LoadSubscriber")
+	defer done(&err)
+	return s.Store.LoadSubscriber(ctx, email)
+}
+
+// LoadSubscriberByToken implements Store
+func (s metricsStore) LoadSubscriberByToken(ctx context.Context, token string) (r0 *model.Subscriber, err error) {
+	ctx, done := observe(ctx, s.system, "LoadSubscriberByToken")
+	defer done(&err)
+	return s.Store.LoadSubscriberByToken(ctx, token)
+}
+
+// LoadAllSubscriber implements Store
+func (s metricsStore) LoadAllSubscriber(ctx context.Context) (r0 []*model.Subscriber, err error) {
+	ctx, done := observe(ctx, s.system, "LoadAllSubscriber")
+	defer done(&err)
+	return s.Store.LoadAllSubscriber(ctx)
+}
+
+// InsertCredential implements Store
+func (s metricsStore) InsertCredential(ctx context.Context, cred *model.Credential) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertCredential")
+	defer done(&err)
+	return s.Store.InsertCredential(ctx, cred)
+}
+
+// RemoveCredential implements Store
+func (s metricsStore) RemoveCredential(ctx context.Context, id string) (err error) {
+	ctx, done := observe(ctx, s.system, "RemoveCredential")
+	defer done(&err)
+	return s.Store.RemoveCredential(ctx, id)
+}
+
+// UpdateCredential implements Store
+func (s metricsStore) UpdateCredential(ctx context.Context, id string, fields map[string]interface{}) (err error) {
+	ctx, done := observe(ctx, s.system, "UpdateCredential")
+	defer done(&err)
+	return s.Store.UpdateCredential(ctx, id, fields)
+}
+
+// LoadCredentials implements Store
+func (s metricsStore) LoadCredentials(ctx context.Context, username string) (r0 []*model.Credential, err error) {
+	ctx, done := observe(ctx, s.system, "LoadCredentials")
+	defer done(&err)
+	return s.Store.LoadCredentials(ctx, username)
+}
+
+// InsertLoginAttempt implements Store
+func (s metricsStore) InsertLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertLoginAttempt")
+	defer done(&err)
+	return s.Store.InsertLoginAttempt(ctx, attempt)
+}
+
+// LoadLoginAttemptList implements Store
+func (s metricsStore) LoadLoginAttemptList(ctx context.Context, search SearchLoginAttempts) (r0 []*model.LoginAttempt, r1 int, err error) {
+	ctx, done := observe(ctx, s.system, "LoadLoginAttemptList")
+	defer done(&err)
+	return s.Store.LoadLoginAttemptList(ctx, search)
+}
+
+// CleanLoginAttempts implements Store
+func (s metricsStore) CleanLoginAttempts(ctx context.Context, exp time.Time) (err error) {
+	ctx, done := observe(ctx, s.system, "CleanLoginAttempts")
+	defer done(&err)
+	return s.Store.CleanLoginAttempts(ctx, exp)
+}
+
+// InsertAuditLog implements Store
+func (s metricsStore) InsertAuditLog(ctx context.Context, log *model.AuditLog) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertAuditLog")
+	defer done(&err)
+	return s.Store.InsertAuditLog(ctx, log)
+}
+
+// LoadAuditLogList implements Store
+func (s metricsStore) LoadAuditLogList(ctx context.Context, search SearchAuditLogs) (r0 []*model.AuditLog, r1 int, err error) {
+	ctx, done := observe(ctx, s.system, "LoadAuditLogList")
+	defer done(&err)
+	return s.Store.LoadAuditLogList(ctx, search)
+}
+
+// CleanAuditLogs implements Store
+func (s metricsStore) CleanAuditLogs(ctx context.Context, exp time.Time) (err error) {
+	ctx, done := observe(ctx, s.system, "CleanAuditLogs")
+	defer done(&err)
+	return s.Store.CleanAuditLogs(ctx, exp)
+}
+
+// DropDatabase implements Store
+func (s metricsStore) DropDatabase(ctx context.Context) (err error) {
+	ctx, done := observe(ctx, s.system, "DropDatabase")
+	defer done(&err)
+	return s.Store.DropDatabase(ctx)
+}
+
+// InsertSession implements Store
+func (s metricsStore) InsertSession(ctx context.Context, sess *model.Session) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertSession")
+	defer done(&err)
+	return s.Store.InsertSession(ctx, sess)
+}
+
+// TouchSession implements Store
+func (s metricsStore) TouchSession(ctx context.Context, id string, lastSeen time.Time) (err error) {
+	ctx, done := observe(ctx, s.system, "TouchSession")
+	defer done(&err)
+	return s.Store.TouchSession(ctx, id, lastSeen)
+}
+
+// RemoveSession implements Store
+func (s metricsStore) RemoveSession(ctx context.Context, id string) (err error) {
+	ctx, done := observe(ctx, s.system, "RemoveSession")
+	defer done(&err)
+	return s.Store.RemoveSession(ctx, id)
+}
+
+// LoadSession implements Store
+func (s metricsStore) LoadSession(ctx context.Context, id string) (r0 *model.Session, err error) {
+	ctx, done := observe(ctx, s.system, "LoadSession")
+	defer done(&err)
+	return s.Store.LoadSession(ctx, id)
+}
+
+// LoadSessions implements Store
+func (s metricsStore) LoadSessions(ctx context.Context, username string) (r0 []*model.Session, err error) {
+	ctx, done := observe(ctx, s.system, "LoadSessions")
+	defer done(&err)
+	return s.Store.LoadSessions(ctx, username)
+}
+
+// CleanSessions implements Store
+func (s metricsStore) CleanSessions(ctx context.Context, exp time.Time) (err error) {
+	ctx, done := observe(ctx, s.system, "CleanSessions")
+	defer done(&err)
+	return s.Store.CleanSessions(ctx, exp)
+}
+
+// Ping implements Store
+func (s metricsStore) Ping(ctx context.Context) (err error) {
+	ctx, done := observe(ctx, s.system, "Ping")
+	defer done(&err)
+	return s.Store.Ping(ctx)
+}
+
+// InsertSession implements SessionStore
+func (s metricsSessionStore) InsertSession(ctx context.Context, sess *model.Session) (err error) {
+	ctx, done := observe(ctx, s.system, "InsertSession")
+	defer done(&err)
+	return s.SessionStore.InsertSession(ctx, sess)
+}
+
+// TouchSession implements SessionStore
+func (s metricsSessionStore) TouchSession(ctx context.Context, id string, lastSeen time.Time) (err error) {
+	ctx, done := observe(ctx, s.system, "TouchSession")
+	defer done(&err)
+	return s.SessionStore.TouchSession(ctx, id, lastSeen)
+}
+
+// RemoveSession implements SessionStore
+func (s metricsSessionStore) RemoveSession(ctx context.Context, id string) (err error) {
+	ctx, done := observe(ctx, s.system, "RemoveSession")
+	defer done(&err)
+	return s.SessionStore.RemoveSession(ctx, id)
+}
+
+// LoadSession implements SessionStore
+func (s metricsSessionStore) LoadSession(ctx context.Context, id string) (r0 *model.Session, err error) {
+	ctx, done := observe(ctx, s.system, "LoadSession")
+	defer done(&err)
+	return s.SessionStore.LoadSession(ctx, id)
+}
+
+// LoadSessions implements SessionStore
+func (s metricsSessionStore) LoadSessions(ctx context.Context, username string) (r0 []*model.Session, err error) {
+	ctx, done := observe(ctx, s.system, "LoadSessions")
+	defer done(&err)
+	return s.SessionStore.LoadSessions(ctx, username)
+}
+
+// CleanSessions implements SessionStore
+func (s metricsSessionStore) CleanSessions(ctx context.Context, exp time.Time) (err error) {
+	ctx, done := observe(ctx, s.system, "CleanSessions")
+	defer done(&err)
+	return s.SessionStore.CleanSessions(ctx, exp)
+}
+
+// Ping implements SessionStore
+func (s metricsSessionStore) Ping(ctx context.Context) (err error) {
+	ctx, done := observe(ctx, s.system, "Ping")
+	defer done(&err)
+	return s.SessionStore.Ping(ctx)
+}
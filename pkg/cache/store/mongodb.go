@@ -3,6 +3,7 @@ package store
 
 import (
 	"context"
+	"regexp"
 	"sort"
 	"time"
 
@@ -25,9 +26,20 @@ const (
 	collectionBlogger = "blogger"
 	collectionCounter = "counter"
 	collectionSerie   = "serie"
+	collectionWebhook = "webhook"
+	collectionDeliver = "webhook_delivery"
+	collectionSubscr  = "subscriber"
+	collectionLogin   = "login_attempt"
+	collectionSession = "session"
+	collectionAudit   = "audit_log"
+	collectionCred    = "credential"
 
 	counterNameSerie   = "serie"
 	counterNameArticle = "article"
+	counterNameWebhook = "webhook"
+	counterNameDeliver = "webhook_delivery"
+	counterNameLogin   = "login_attempt"
+	counterNameAudit   = "audit_log"
 )
 
 type mongodb struct {
@@ -71,6 +83,22 @@ func (db *mongodb) Init(name, source string) (Store, error) {
 	db.Database(mongoDBName).Collection(collectionSerie).
 		Indexes().
 		CreateOne(context.Background(), indexModel)
+	indexModel = mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	db.Database(mongoDBName).Collection(collectionCred).
+		Indexes().
+		CreateOne(context.Background(), indexModel)
+	for _, key := range []string{"email", "token"} {
+		indexModel = mongo.IndexModel{
+			Keys:    bson.D{bson.E{Key: key, Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		}
+		db.Database(mongoDBName).Collection(collectionSubscr).
+			Indexes().
+			CreateOne(context.Background(), indexModel)
+	}
 	return db, nil
 }
 
@@ -265,7 +293,9 @@ func (db *mongodb) LoadArticle(ctx context.Context, id int) (*model.Article, err
 	filter := bson.M{"id": id}
 	result := collection.FindOne(ctx, filter)
 	err := result.Err()
-	if err != nil {
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	} else if err != nil {
 		return nil, err
 	}
 	article := &model.Article{}
@@ -326,11 +356,465 @@ func (db *mongodb) LoadArticleList(ctx context.Context, search SearchArticles) (
 	return articles, int(count), nil
 }
 
+// InsertWebhook 创建webhook
+func (db *mongodb) InsertWebhook(ctx context.Context, hook *model.Webhook) error {
+	collection := db.Database(mongoDBName).Collection(collectionWebhook)
+
+	hook.ID = db.nextValue(ctx, counterNameWebhook)
+	_, err := collection.InsertOne(ctx, hook)
+	return err
+}
+
+// RemoveWebhook 删除webhook
+func (db *mongodb) RemoveWebhook(ctx context.Context, id int) error {
+	collection := db.Database(mongoDBName).Collection(collectionWebhook)
+
+	filter := bson.M{"id": id}
+	_, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	collection = db.Database(mongoDBName).Collection(collectionDeliver)
+	filter = bson.M{"webhook_id": id}
+	_, err = collection.DeleteMany(ctx, filter)
+	return err
+}
+
+// UpdateWebhook 更新webhook
+func (db *mongodb) UpdateWebhook(ctx context.Context, id int,
+	fields map[string]interface{}) error {
+
+	collection := db.Database(mongoDBName).Collection(collectionWebhook)
+
+	filter := bson.M{"id": id}
+	params := bson.M{}
+	for k, v := range fields {
+		params[k] = v
+	}
+	update := bson.M{"$set": params}
+	_, err := collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// LoadAllWebhook 读取所有webhook
+func (db *mongodb) LoadAllWebhook(ctx context.Context) ([]*model.Webhook, error) {
+	collection := db.Database(mongoDBName).Collection(collectionWebhook)
+
+	opts := options.Find().SetSort(bson.M{"id": 1})
+	cur, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var hooks []*model.Webhook
+	for cur.Next(ctx) {
+		obj := model.Webhook{}
+		err = cur.Decode(&obj)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, &obj)
+	}
+	return hooks, nil
+}
+
+// InsertWebhookDelivery 记录webhook推送
+func (db *mongodb) InsertWebhookDelivery(ctx context.Context,
+	delivery *model.WebhookDelivery) error {
+
+	collection := db.Database(mongoDBName).Collection(collectionDeliver)
+
+	delivery.ID = db.nextValue(ctx, counterNameDeliver)
+	_, err := collection.InsertOne(ctx, delivery)
+	return err
+}
+
+// LoadWebhookDeliveries 读取webhook最近的推送记录
+func (db *mongodb) LoadWebhookDeliveries(ctx context.Context, id int,
+	limit int) ([]*model.WebhookDelivery, error) {
+
+	collection := db.Database(mongoDBName).Collection(collectionDeliver)
+
+	opts := options.Find().SetSort(bson.M{"id": -1}).SetLimit(int64(limit))
+	filter := bson.M{"webhook_id": id}
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var deliveries []*model.WebhookDelivery
+	for cur.Next(ctx) {
+		obj := model.WebhookDelivery{}
+		err = cur.Decode(&obj)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &obj)
+	}
+	return deliveries, nil
+}
+
+// InsertSubscriber 创建订阅者
+func (db *mongodb) InsertSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	collection := db.Database(mongoDBName).Collection(collectionSubscr)
+
+	_, err := collection.InsertOne(ctx, sub)
+	return err
+}
+
+// RemoveSubscriber 删除订阅者
+func (db *mongodb) RemoveSubscriber(ctx context.Context, email string) error {
+	collection := db.Database(mongoDBName).Collection(collectionSubscr)
+
+	filter := bson.M{"email": email}
+	_, err := collection.DeleteOne(ctx, filter)
+	return err
+}
+
+// UpdateSubscriber 更新订阅者
+func (db *mongodb) UpdateSubscriber(ctx context.Context, email string,
+	fields map[string]interface{}) error {
+
+	collection := db.Database(mongoDBName).Collection(collectionSubscr)
+
+	filter := bson.M{"email": email}
+	params := bson.M{}
+	for k, v := range fields {
+		params[k] = v
+	}
+	update := bson.M{"$set": params}
+	_, err := collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// LoadSubscriber 通过邮件地址查找订阅者
+func (db *mongodb) LoadSubscriber(ctx context.Context, email string) (*model.Subscriber, error) {
+	return db.findSubscriber(ctx, bson.M{"email": email})
+}
+
+// LoadSubscriberByToken 通过token查找订阅者
+func (db *mongodb) LoadSubscriberByToken(ctx context.Context, token string) (*model.Subscriber, error) {
+	return db.findSubscriber(ctx, bson.M{"token": token})
+}
+
+// findSubscriber 查找订阅者
+func (db *mongodb) findSubscriber(ctx context.Context, filter bson.M) (*model.Subscriber, error) {
+	collection := db.Database(mongoDBName).Collection(collectionSubscr)
+
+	result := collection.FindOne(ctx, filter)
+	err := result.Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	sub := &model.Subscriber{}
+	err = result.Decode(sub)
+	return sub, err
+}
+
+// LoadAllSubscriber 读取所有订阅者
+func (db *mongodb) LoadAllSubscriber(ctx context.Context) ([]*model.Subscriber, error) {
+	collection := db.Database(mongoDBName).Collection(collectionSubscr)
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cur, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var subs []*model.Subscriber
+	for cur.Next(ctx) {
+		obj := model.Subscriber{}
+		err = cur.Decode(&obj)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, &obj)
+	}
+	return subs, nil
+}
+
+// InsertCredential 创建通行密钥
+func (db *mongodb) InsertCredential(ctx context.Context, cred *model.Credential) error {
+	collection := db.Database(mongoDBName).Collection(collectionCred)
+
+	_, err := collection.InsertOne(ctx, cred)
+	return err
+}
+
+// RemoveCredential 删除通行密钥
+func (db *mongodb) RemoveCredential(ctx context.Context, id string) error {
+	collection := db.Database(mongoDBName).Collection(collectionCred)
+
+	filter := bson.M{"id": id}
+	_, err := collection.DeleteOne(ctx, filter)
+	return err
+}
+
+// UpdateCredential 更新通行密钥
+func (db *mongodb) UpdateCredential(ctx context.Context, id string,
+	fields map[string]interface{}) error {
+
+	collection := db.Database(mongoDBName).Collection(collectionCred)
+
+	filter := bson.M{"id": id}
+	params := bson.M{}
+	for k, v := range fields {
+		params[k] = v
+	}
+	update := bson.M{"$set": params}
+	_, err := collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// LoadCredentials 读取用户所有通行密钥
+func (db *mongodb) LoadCredentials(ctx context.Context, username string) ([]*model.Credential, error) {
+	collection := db.Database(mongoDBName).Collection(collectionCred)
+
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cur, err := collection.Find(ctx, bson.M{"username": username}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var creds []*model.Credential
+	for cur.Next(ctx) {
+		obj := model.Credential{}
+		err = cur.Decode(&obj)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, &obj)
+	}
+	return creds, nil
+}
+
+// InsertLoginAttempt 记录登录
+func (db *mongodb) InsertLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error {
+	collection := db.Database(mongoDBName).Collection(collectionLogin)
+
+	attempt.ID = db.nextValue(ctx, counterNameLogin)
+	_, err := collection.InsertOne(ctx, attempt)
+	return err
+}
+
+// LoadLoginAttemptList 查找登录记录列表
+func (db *mongodb) LoadLoginAttemptList(ctx context.Context, search SearchLoginAttempts) (
+	[]*model.LoginAttempt, int, error) {
+	collection := db.Database(mongoDBName).Collection(collectionLogin)
+
+	filter := bson.M{}
+	for k, v := range search.Fields {
+		switch k {
+		case SearchLoginUsername, SearchLoginIP, SearchLoginUA:
+			filter[k] = v.(string)
+		case SearchLoginSuccess, SearchLoginNewDevice:
+			filter[k] = v.(bool)
+		case SearchLoginSince:
+			filter["created_at"] = bson.M{"$gte": v.(time.Time)}
+		}
+	}
+	// search count
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetLimit(int64(search.Limit)).
+		SetSkip(int64((search.Page - 1) * search.Limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: -1}})
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	var attempts []*model.LoginAttempt
+	for cur.Next(ctx) {
+		obj := model.LoginAttempt{}
+		err = cur.Decode(&obj)
+		if err != nil {
+			return nil, 0, err
+		}
+		attempts = append(attempts, &obj)
+	}
+	return attempts, int(count), nil
+}
+
+// CleanLoginAttempts 清理过期登录记录
+func (db *mongodb) CleanLoginAttempts(ctx context.Context, exp time.Time) error {
+	collection := db.Database(mongoDBName).Collection(collectionLogin)
+
+	filter := bson.M{"created_at": bson.M{"$lt": exp}}
+	_, err := collection.DeleteMany(ctx, filter)
+	return err
+}
+
+// InsertAuditLog 记录审计日志
+func (db *mongodb) InsertAuditLog(ctx context.Context, log *model.AuditLog) error {
+	collection := db.Database(mongoDBName).Collection(collectionAudit)
+
+	log.ID = db.nextValue(ctx, counterNameAudit)
+	_, err := collection.InsertOne(ctx, log)
+	return err
+}
+
+// LoadAuditLogList 查找审计日志列表
+func (db *mongodb) LoadAuditLogList(ctx context.Context, search SearchAuditLogs) (
+	[]*model.AuditLog, int, error) {
+	collection := db.Database(mongoDBName).Collection(collectionAudit)
+
+	filter := bson.M{}
+	created := bson.M{}
+	for k, v := range search.Fields {
+		switch k {
+		case SearchAuditActor:
+			filter["actor"] = v.(string)
+		case SearchAuditAction:
+			filter["action"] = v.(string)
+		case SearchAuditTarget:
+			filter["target"] = bson.M{
+				"$regex":   regexp.QuoteMeta(v.(string)),
+				"$options": "$i",
+			}
+		case SearchAuditSince:
+			created["$gte"] = v.(time.Time)
+		case SearchAuditUntil:
+			created["$lt"] = v.(time.Time)
+		}
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	// search count
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetLimit(int64(search.Limit)).
+		SetSkip(int64((search.Page - 1) * search.Limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: -1}})
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	var logs []*model.AuditLog
+	for cur.Next(ctx) {
+		obj := model.AuditLog{}
+		err = cur.Decode(&obj)
+		if err != nil {
+			return nil, 0, err
+		}
+		logs = append(logs, &obj)
+	}
+	return logs, int(count), nil
+}
+
+// CleanAuditLogs 清理过期审计日志
+func (db *mongodb) CleanAuditLogs(ctx context.Context, exp time.Time) error {
+	collection := db.Database(mongoDBName).Collection(collectionAudit)
+
+	filter := bson.M{"created_at": bson.M{"$lt": exp}}
+	_, err := collection.DeleteMany(ctx, filter)
+	return err
+}
+
+// InsertSession 创建会话
+func (db *mongodb) InsertSession(ctx context.Context, sess *model.Session) error {
+	collection := db.Database(mongoDBName).Collection(collectionSession)
+
+	_, err := collection.InsertOne(ctx, sess)
+	return err
+}
+
+// TouchSession 更新会话最近活动时间
+func (db *mongodb) TouchSession(ctx context.Context, id string, lastSeen time.Time) error {
+	collection := db.Database(mongoDBName).Collection(collectionSession)
+
+	filter := bson.M{"id": id}
+	update := bson.M{"$set": bson.M{"last_seen": lastSeen}}
+	_, err := collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RemoveSession 删除会话
+func (db *mongodb) RemoveSession(ctx context.Context, id string) error {
+	collection := db.Database(mongoDBName).Collection(collectionSession)
+
+	filter := bson.M{"id": id}
+	_, err := collection.DeleteOne(ctx, filter)
+	return err
+}
+
+// LoadSession 读取会话
+func (db *mongodb) LoadSession(ctx context.Context, id string) (*model.Session, error) {
+	collection := db.Database(mongoDBName).Collection(collectionSession)
+
+	result := collection.FindOne(ctx, bson.M{"id": id})
+	err := result.Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	sess := &model.Session{}
+	err = result.Decode(sess)
+	return sess, err
+}
+
+// LoadSessions 读取用户所有会话
+func (db *mongodb) LoadSessions(ctx context.Context, username string) ([]*model.Session, error) {
+	collection := db.Database(mongoDBName).Collection(collectionSession)
+
+	opts := options.Find().SetSort(bson.M{"last_seen": -1})
+	cur, err := collection.Find(ctx, bson.M{"username": username}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var sessions []*model.Session
+	for cur.Next(ctx) {
+		obj := model.Session{}
+		err = cur.Decode(&obj)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &obj)
+	}
+	return sessions, nil
+}
+
+// CleanSessions 清理过期会话
+func (db *mongodb) CleanSessions(ctx context.Context, exp time.Time) error {
+	collection := db.Database(mongoDBName).Collection(collectionSession)
+
+	filter := bson.M{"expires_at": bson.M{"$lt": exp}}
+	_, err := collection.DeleteMany(ctx, filter)
+	return err
+}
+
 // DropDatabase drop eiblog database
 func (db *mongodb) DropDatabase(ctx context.Context) error {
 	return db.Database(mongoDBName).Drop(ctx)
 }
 
+// Ping 检查mongodb连接
+func (db *mongodb) Ping(ctx context.Context) error {
+	return db.Client.Ping(ctx, readpref.Primary())
+}
+
+// Close 断开mongodb连接
+func (db *mongodb) Close(ctx context.Context) error {
+	return db.Disconnect(ctx)
+}
+
 // counter counter
 type counter struct {
 	Name    string
@@ -68,6 +68,13 @@ func (db *rdbms) Init(name, source string) (Store, error) {
 		&model.Blogger{},
 		&model.Article{},
 		&model.Serie{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.Subscriber{},
+		&model.LoginAttempt{},
+		&model.Session{},
+		&model.AuditLog{},
+		&model.Credential{},
 	)
 	db.DB = gormDB
 	return db, nil
@@ -156,6 +163,9 @@ func (db *rdbms) UpdateArticle(ctx context.Context, id int, fields map[string]in
 func (db *rdbms) LoadArticle(ctx context.Context, id int) (*model.Article, error) {
 	article := &model.Article{}
 	err := db.Where("id=?", id).First(article).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
 	return article, err
 }
 
@@ -191,11 +201,250 @@ func (db *rdbms) LoadArticleList(ctx context.Context, search SearchArticles) (mo
 	return articles, int(count), err
 }
 
+// InsertWebhook 创建webhook
+func (db *rdbms) InsertWebhook(ctx context.Context, hook *model.Webhook) error {
+	return db.Create(hook).Error
+}
+
+// RemoveWebhook 删除webhook
+func (db *rdbms) RemoveWebhook(ctx context.Context, id int) error {
+	err := db.Where("id=?", id).Delete(model.Webhook{}).Error
+	if err != nil {
+		return err
+	}
+	return db.Where("webhook_id=?", id).Delete(model.WebhookDelivery{}).Error
+}
+
+// UpdateWebhook 更新webhook
+func (db *rdbms) UpdateWebhook(ctx context.Context, id int, fields map[string]interface{}) error {
+	return db.Model(model.Webhook{}).Where("id=?", id).Updates(fields).Error
+}
+
+// LoadAllWebhook 读取所有webhook
+func (db *rdbms) LoadAllWebhook(ctx context.Context) ([]*model.Webhook, error) {
+	var hooks []*model.Webhook
+	err := db.Order("id ASC").Find(&hooks).Error
+	return hooks, err
+}
+
+// InsertWebhookDelivery 记录webhook推送
+func (db *rdbms) InsertWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return db.Create(delivery).Error
+}
+
+// LoadWebhookDeliveries 读取webhook最近的推送记录
+func (db *rdbms) LoadWebhookDeliveries(ctx context.Context, id int, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := db.Where("webhook_id=?", id).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// InsertSubscriber 创建订阅者
+func (db *rdbms) InsertSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	return db.Create(sub).Error
+}
+
+// RemoveSubscriber 删除订阅者
+func (db *rdbms) RemoveSubscriber(ctx context.Context, email string) error {
+	return db.Where("email=?", email).Delete(model.Subscriber{}).Error
+}
+
+// UpdateSubscriber 更新订阅者
+func (db *rdbms) UpdateSubscriber(ctx context.Context, email string, fields map[string]interface{}) error {
+	return db.Model(model.Subscriber{}).Where("email=?", email).Updates(fields).Error
+}
+
+// LoadSubscriber 通过邮件地址查找订阅者
+func (db *rdbms) LoadSubscriber(ctx context.Context, email string) (*model.Subscriber, error) {
+	sub := &model.Subscriber{}
+	err := db.Where("email=?", email).First(sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return sub, err
+}
+
+// LoadSubscriberByToken 通过token查找订阅者
+func (db *rdbms) LoadSubscriberByToken(ctx context.Context, token string) (*model.Subscriber, error) {
+	sub := &model.Subscriber{}
+	err := db.Where("token=?", token).First(sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return sub, err
+}
+
+// LoadAllSubscriber 读取所有订阅者
+func (db *rdbms) LoadAllSubscriber(ctx context.Context) ([]*model.Subscriber, error) {
+	var subs []*model.Subscriber
+	err := db.Order("created_at DESC").Find(&subs).Error
+	return subs, err
+}
+
+// InsertCredential 创建通行密钥
+func (db *rdbms) InsertCredential(ctx context.Context, cred *model.Credential) error {
+	return db.Create(cred).Error
+}
+
+// RemoveCredential 删除通行密钥
+func (db *rdbms) RemoveCredential(ctx context.Context, id string) error {
+	return db.Where("id=?", id).Delete(model.Credential{}).Error
+}
+
+// UpdateCredential 更新通行密钥
+func (db *rdbms) UpdateCredential(ctx context.Context, id string, fields map[string]interface{}) error {
+	return db.Model(model.Credential{}).Where("id=?", id).Updates(fields).Error
+}
+
+// LoadCredentials 读取用户所有通行密钥
+func (db *rdbms) LoadCredentials(ctx context.Context, username string) ([]*model.Credential, error) {
+	var creds []*model.Credential
+	err := db.Where("username=?", username).Order("created_at").Find(&creds).Error
+	return creds, err
+}
+
+// InsertLoginAttempt 记录登录
+func (db *rdbms) InsertLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error {
+	return db.Create(attempt).Error
+}
+
+// LoadLoginAttemptList 查找登录记录列表
+func (db *rdbms) LoadLoginAttemptList(ctx context.Context, search SearchLoginAttempts) ([]*model.LoginAttempt, int, error) {
+	gormDB := db.Model(model.LoginAttempt{})
+	for k, v := range search.Fields {
+		switch k {
+		case SearchLoginUsername:
+			gormDB = gormDB.Where("username=?", v.(string))
+		case SearchLoginSuccess:
+			gormDB = gormDB.Where("success=?", v.(bool))
+		case SearchLoginIP:
+			gormDB = gormDB.Where("ip=?", v.(string))
+		case SearchLoginUA:
+			gormDB = gormDB.Where("ua=?", v.(string))
+		case SearchLoginNewDevice:
+			gormDB = gormDB.Where("new_device=?", v.(bool))
+		case SearchLoginSince:
+			gormDB = gormDB.Where("created_at>=?", v.(time.Time))
+		}
+	}
+	// search count
+	var count int64
+	err := gormDB.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	var attempts []*model.LoginAttempt
+	err = gormDB.Limit(search.Limit).
+		Offset((search.Page - 1) * search.Limit).
+		Order("created_at DESC, id DESC").Find(&attempts).Error
+	return attempts, int(count), err
+}
+
+// CleanLoginAttempts 清理过期登录记录
+func (db *rdbms) CleanLoginAttempts(ctx context.Context, exp time.Time) error {
+	return db.Where("created_at<?", exp).Delete(model.LoginAttempt{}).Error
+}
+
+// InsertAuditLog 记录审计日志
+func (db *rdbms) InsertAuditLog(ctx context.Context, log *model.AuditLog) error {
+	return db.Create(log).Error
+}
+
+// LoadAuditLogList 查找审计日志列表
+func (db *rdbms) LoadAuditLogList(ctx context.Context, search SearchAuditLogs) ([]*model.AuditLog, int, error) {
+	gormDB := db.Model(model.AuditLog{})
+	for k, v := range search.Fields {
+		switch k {
+		case SearchAuditActor:
+			gormDB = gormDB.Where("actor=?", v.(string))
+		case SearchAuditAction:
+			gormDB = gormDB.Where("action=?", v.(string))
+		case SearchAuditTarget:
+			gormDB = gormDB.Where("target LIKE ?", "%"+v.(string)+"%")
+		case SearchAuditSince:
+			gormDB = gormDB.Where("created_at>=?", v.(time.Time))
+		case SearchAuditUntil:
+			gormDB = gormDB.Where("created_at<?", v.(time.Time))
+		}
+	}
+	// search count
+	var count int64
+	err := gormDB.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	var logs []*model.AuditLog
+	err = gormDB.Limit(search.Limit).
+		Offset((search.Page - 1) * search.Limit).
+		Order("created_at DESC, id DESC").Find(&logs).Error
+	return logs, int(count), err
+}
+
+// CleanAuditLogs 清理过期审计日志
+func (db *rdbms) CleanAuditLogs(ctx context.Context, exp time.Time) error {
+	return db.Where("created_at<?", exp).Delete(model.AuditLog{}).Error
+}
+
+// InsertSession 创建会话
+func (db *rdbms) InsertSession(ctx context.Context, sess *model.Session) error {
+	return db.Create(sess).Error
+}
+
+// TouchSession 更新会话最近活动时间
+func (db *rdbms) TouchSession(ctx context.Context, id string, lastSeen time.Time) error {
+	return db.Model(model.Session{}).Where("id=?", id).Update("last_seen", lastSeen).Error
+}
+
+// RemoveSession 删除会话
+func (db *rdbms) RemoveSession(ctx context.Context, id string) error {
+	return db.Where("id=?", id).Delete(model.Session{}).Error
+}
+
+// LoadSession 读取会话
+func (db *rdbms) LoadSession(ctx context.Context, id string) (*model.Session, error) {
+	sess := &model.Session{}
+	err := db.Where("id=?", id).First(sess).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return sess, err
+}
+
+// LoadSessions 读取用户所有会话
+func (db *rdbms) LoadSessions(ctx context.Context, username string) ([]*model.Session, error) {
+	var sessions []*model.Session
+	err := db.Where("username=?", username).Order("last_seen DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// CleanSessions 清理过期会话
+func (db *rdbms) CleanSessions(ctx context.Context, exp time.Time) error {
+	return db.Where("expires_at<?", exp).Delete(model.Session{}).Error
+}
+
 // DropDatabase drop eiblog database
 func (db *rdbms) DropDatabase(ctx context.Context) error {
 	return errors.New("can not drop eiblog database in rdbms")
 }
 
+// Ping 检查数据库连接
+func (db *rdbms) Ping(ctx context.Context) error {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close 关闭数据库连接
+func (db *rdbms) Close(ctx context.Context) error {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
 // register store
 func init() {
 	Register("mysql", &rdbms{})
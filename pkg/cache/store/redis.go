@@ -0,0 +1,133 @@
+// Package store provides ...
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redis key
+const (
+	redisKeySession  = "eiblog:session:"  // 会话: eiblog:session:<id>
+	redisKeySessions = "eiblog:sessions:" // 用户会话集合: eiblog:sessions:<username>
+)
+
+// redisSession 基于redis的会话存储, 过期由redis自动清理
+type redisSession struct {
+	*redis.Client
+}
+
+// NewRedisSessionStore 创建redis会话存储
+func NewRedisSessionStore(addr, password string, db int) (SessionStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Ping(ctx).Err()
+	if err != nil {
+		return nil, err
+	}
+	return metricsSessionStore{&redisSession{client}, "redis"}, nil
+}
+
+// InsertSession 创建会话
+func (rs *redisSession) InsertSession(ctx context.Context, sess *model.Session) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	pipe := rs.TxPipeline()
+	pipe.Set(ctx, redisKeySession+sess.ID, data, time.Until(sess.ExpiresAt))
+	pipe.SAdd(ctx, redisKeySessions+sess.Username, sess.ID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// TouchSession 更新会话最近活动时间
+func (rs *redisSession) TouchSession(ctx context.Context, id string, lastSeen time.Time) error {
+	sess, err := rs.LoadSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	sess.LastSeen = lastSeen
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return rs.Set(ctx, redisKeySession+id, data, redis.KeepTTL).Err()
+}
+
+// RemoveSession 删除会话
+func (rs *redisSession) RemoveSession(ctx context.Context, id string) error {
+	sess, err := rs.LoadSession(ctx, id)
+	if err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	pipe := rs.TxPipeline()
+	pipe.Del(ctx, redisKeySession+id)
+	pipe.SRem(ctx, redisKeySessions+sess.Username, id)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// LoadSession 读取会话
+func (rs *redisSession) LoadSession(ctx context.Context, id string) (*model.Session, error) {
+	data, err := rs.Get(ctx, redisKeySession+id).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	sess := &model.Session{}
+	err = json.Unmarshal(data, sess)
+	return sess, err
+}
+
+// LoadSessions 读取用户所有会话, 同时清理已过期的ID
+func (rs *redisSession) LoadSessions(ctx context.Context, username string) ([]*model.Session, error) {
+	ids, err := rs.SMembers(ctx, redisKeySessions+username).Result()
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*model.Session
+	for _, id := range ids {
+		sess, err := rs.LoadSession(ctx, id)
+		if err == ErrNotFound {
+			rs.SRem(ctx, redisKeySessions+username, id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions, nil
+}
+
+// CleanSessions 过期会话由redis自动删除
+func (rs *redisSession) CleanSessions(ctx context.Context, exp time.Time) error {
+	return nil
+}
+
+// Ping 检查redis连接
+func (rs *redisSession) Ping(ctx context.Context) error {
+	return rs.Client.Ping(ctx).Err()
+}
+
+// Close 关闭redis连接
+func (rs *redisSession) Close(ctx context.Context) error {
+	return rs.Client.Close()
+}
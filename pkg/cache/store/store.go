@@ -3,6 +3,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
@@ -14,6 +15,9 @@ import (
 var (
 	storeMu sync.RWMutex
 	stores  = make(map[string]Driver)
+
+	// ErrNotFound 记录不存在
+	ErrNotFound = errors.New("store: record not found")
 )
 
 // search field
@@ -31,6 +35,39 @@ type SearchArticles struct {
 	Fields map[string]interface{} // 字段:值
 }
 
+// audit search field
+const (
+	SearchAuditActor  = "actor"  // 操作者
+	SearchAuditAction = "action" // 操作
+	SearchAuditTarget = "target" // 操作对象, 模糊匹配
+	SearchAuditSince  = "since"  // 起始时间
+	SearchAuditUntil  = "until"  // 截止时间
+)
+
+// login attempt search field
+const (
+	SearchLoginUsername  = "username"   // 用户名
+	SearchLoginSuccess   = "success"    // 是否成功
+	SearchLoginIP        = "ip"         // 登录IP
+	SearchLoginUA        = "ua"         // 登录UA
+	SearchLoginNewDevice = "new_device" // 是否为新设备
+	SearchLoginSince     = "since"      // 起始时间
+)
+
+// SearchLoginAttempts 登录记录搜索字段
+type SearchLoginAttempts struct {
+	Page   int                    // 第几页/1
+	Limit  int                    // 每页大小
+	Fields map[string]interface{} // 字段:值
+}
+
+// SearchAuditLogs 审计日志搜索字段
+type SearchAuditLogs struct {
+	Page   int                    // 第几页/1
+	Limit  int                    // 每页大小
+	Fields map[string]interface{} // 字段:值
+}
+
 // Store 存储后端
 type Store interface {
 	// LoadInsertBlogger 读取或创建博客
@@ -65,10 +102,82 @@ type Store interface {
 	// LoadArticleList 查找文章列表
 	LoadArticleList(ctx context.Context, search SearchArticles) (model.SortedArticles, int, error)
 
+	// InsertWebhook 创建webhook
+	InsertWebhook(ctx context.Context, hook *model.Webhook) error
+	// RemoveWebhook 删除webhook
+	RemoveWebhook(ctx context.Context, id int) error
+	// UpdateWebhook 更新webhook
+	UpdateWebhook(ctx context.Context, id int, fields map[string]interface{}) error
+	// LoadAllWebhook 读取所有webhook
+	LoadAllWebhook(ctx context.Context) ([]*model.Webhook, error)
+	// InsertWebhookDelivery 记录webhook推送
+	InsertWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// LoadWebhookDeliveries 读取webhook最近的推送记录
+	LoadWebhookDeliveries(ctx context.Context, id int, limit int) ([]*model.WebhookDelivery, error)
+
+	// InsertSubscriber 创建订阅者
+	InsertSubscriber(ctx context.Context, sub *model.Subscriber) error
+	// RemoveSubscriber 删除订阅者
+	RemoveSubscriber(ctx context.Context, email string) error
+	// UpdateSubscriber 更新订阅者
+	UpdateSubscriber(ctx context.Context, email string, fields map[string]interface{}) error
+	// LoadSubscriber 通过邮件地址查找订阅者, 不存在返回 ErrNotFound
+	LoadSubscriber(ctx context.Context, email string) (*model.Subscriber, error)
+	// LoadSubscriberByToken 通过token查找订阅者, 不存在返回 ErrNotFound
+	LoadSubscriberByToken(ctx context.Context, token string) (*model.Subscriber, error)
+	// LoadAllSubscriber 读取所有订阅者
+	LoadAllSubscriber(ctx context.Context) ([]*model.Subscriber, error)
+
+	// InsertCredential 创建通行密钥
+	InsertCredential(ctx context.Context, cred *model.Credential) error
+	// RemoveCredential 删除通行密钥
+	RemoveCredential(ctx context.Context, id string) error
+	// UpdateCredential 更新通行密钥
+	UpdateCredential(ctx context.Context, id string, fields map[string]interface{}) error
+	// LoadCredentials 读取用户所有通行密钥
+	LoadCredentials(ctx context.Context, username string) ([]*model.Credential, error)
+
+	// InsertLoginAttempt 记录登录
+	InsertLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error
+	// LoadLoginAttemptList 查找登录记录列表
+	LoadLoginAttemptList(ctx context.Context, search SearchLoginAttempts) ([]*model.LoginAttempt, int, error)
+	// CleanLoginAttempts 清理过期登录记录
+	CleanLoginAttempts(ctx context.Context, exp time.Time) error
+
+	// InsertAuditLog 记录审计日志
+	InsertAuditLog(ctx context.Context, log *model.AuditLog) error
+	// LoadAuditLogList 查找审计日志列表
+	LoadAuditLogList(ctx context.Context, search SearchAuditLogs) ([]*model.AuditLog, int, error)
+	// CleanAuditLogs 清理过期审计日志
+	CleanAuditLogs(ctx context.Context, exp time.Time) error
+
+	SessionStore
+
 	// 危险操作
 	DropDatabase(ctx context.Context) error
 }
 
+// SessionStore 登录会话存储, 默认使用数据库, 可替换为redis
+type SessionStore interface {
+	// InsertSession 创建会话
+	InsertSession(ctx context.Context, sess *model.Session) error
+	// TouchSession 更新会话最近活动时间
+	TouchSession(ctx context.Context, id string, lastSeen time.Time) error
+	// RemoveSession 删除会话
+	RemoveSession(ctx context.Context, id string) error
+	// LoadSession 读取会话, 不存在返回 ErrNotFound
+	LoadSession(ctx context.Context, id string) (*model.Session, error)
+	// LoadSessions 读取用户所有会话
+	LoadSessions(ctx context.Context, username string) ([]*model.Session, error)
+	// CleanSessions 清理过期会话
+	CleanSessions(ctx context.Context, exp time.Time) error
+
+	// Ping 检查连接
+	Ping(ctx context.Context) error
+	// Close 关闭连接
+	Close(ctx context.Context) error
+}
+
 // Driver 存储驱动
 type Driver interface {
 	// Init 数据库初始化, 建表, 加索引操作等
@@ -110,5 +219,9 @@ func NewStore(name string, source string) (Store, error) {
 		return nil, fmt.Errorf("store: unknown driver %q (forgotten import?)", name)
 	}
 
-	return driver.Init(name, source)
+	store, err := driver.Init(name, source)
+	if err != nil {
+		return nil, err
+	}
+	return metricsStore{store, name}, nil
 }
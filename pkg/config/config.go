@@ -2,6 +2,8 @@
 package config
 
 import (
+	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,6 +21,8 @@ var (
 	ModeProd = "prod"
 	// WorkDir workspace dir
 	WorkDir string
+	// File config file path
+	File string
 )
 
 // Mode run mode
@@ -29,6 +33,55 @@ type Mode struct {
 	EnableGRPC bool   `yaml:"enablegrpc"`
 	GRPCPort   int    `yaml:"grpcport"`
 	Host       string `yaml:"host"`
+
+	Listen         string   `yaml:"listen"`         // unix socket, 如: unix:/run/eiblog.sock, 为空时监听httpport
+	Socket         Socket   `yaml:"socket"`         // unix socket权限及代理
+	URLScheme      string   `yaml:"scheme"`         // 站点协议, http, https, 为空时开启tls为https, 否则为http
+	TLS            TLS      `yaml:"tls"`            // https
+	Timeout        Timeout  `yaml:"timeout"`        // http server超时
+	TrustedProxies []string `yaml:"trustedproxies"` // 可信代理的IP或CIDR, 仅信任其X-Forwarded-For, 为空时均不信任
+}
+
+// Socket unix socket
+type Socket struct {
+	Mode  string `yaml:"mode"`  // 文件权限, 八进制, default: 0660
+	Group string `yaml:"group"` // 属组, 如: www-data, 为空时不修改
+	Proxy bool   `yaml:"proxy"` // 连接均来自可信代理, 信任其X-Forwarded-For
+}
+
+// TLS https, 同时支持HTTP/2
+type TLS struct {
+	Enable       bool   `yaml:"enable"`
+	CertFile     string `yaml:"certfile"`     // 证书文件变化时自动重新加载
+	KeyFile      string `yaml:"keyfile"`      // 私钥文件
+	RedirectPort int    `yaml:"redirectport"` // 在该端口将http请求重定向到https, 为0时不监听
+}
+
+// Timeout http server超时
+type Timeout struct {
+	ReadHeader string `yaml:"readheader"` // default: 10s
+	Read       string `yaml:"read"`       // 包括上传文件, default: 2m
+	Write      string `yaml:"write"`      // default: 2m
+	Idle       string `yaml:"idle"`       // keep-alive, default: 2m
+}
+
+// Scheme 站点协议, 未配置时开启tls为https, 否则为http
+func (m Mode) Scheme() string {
+	if m.URLScheme != "" {
+		return m.URLScheme
+	}
+	if m.TLS.Enable {
+		return "https"
+	}
+	return "http"
+}
+
+// SiteURL 按站点协议生成地址, host为空时为站点域名, 如: SiteURL("", "/rss.html")
+func (m Mode) SiteURL(host, path string) string {
+	if host == "" {
+		host = m.Host
+	}
+	return m.Scheme() + "://" + host + path
 }
 
 // Database sql database
@@ -46,6 +99,7 @@ type General struct {
 	Identifier string `yaml:"identifier"` // 文章截取标识
 	Length     int    `yaml:"length"`     // 文章预览长度
 	Timezone   string `yaml:"timezone"`   // 时区
+	Markdown   string `yaml:"markdown"`   // markdown渲染器, blackfriday, goldmark, default: blackfriday
 }
 
 // Disqus comments
@@ -102,6 +156,113 @@ type FeedRPC struct {
 	PingRPC  []string `yaml:"pingrpc"`
 }
 
+// SMTP mail server
+type SMTP struct {
+	Enable   bool   `yaml:"enable"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"` // 发件人, 如: EiBlog <noreply@example.com>
+	TLS      bool   `yaml:"tls"`  // 直接使用TLS连接(465端口), 否则尝试STARTTLS
+}
+
+// Newsletter email subscription
+type Newsletter struct {
+	Enable   bool   `yaml:"enable"`
+	Mode     string `yaml:"mode"`     // post: 发布即推送, digest: 定期推送摘要
+	Interval string `yaml:"interval"` // digest interval, default: 7d
+}
+
+// LoginLimit admin login brute-force protection
+type LoginLimit struct {
+	Enable      bool   `yaml:"enable"`
+	MaxAttempts int    `yaml:"maxattempts"` // 窗口内允许失败次数, default: 5
+	Window      string `yaml:"window"`      // 失败计数窗口, default: 15m
+	Lockout     string `yaml:"lockout"`     // 首次锁定时长, 之后IP锁定翻倍, 账号锁定不翻倍, default: 1m
+	MaxLockout  string `yaml:"maxlockout"`  // IP最长锁定时长, default: 24h
+	AlertAt     int    `yaml:"alertat"`     // 失败次数达到该值时邮件告警, 0不告警
+}
+
+// Session admin login session
+type Session struct {
+	Secret    string `yaml:"secret"`    // cookie签名密钥, 为空时自动生成并保存
+	MaxAge    string `yaml:"maxage"`    // 会话有效期, default: 30d
+	Idle      string `yaml:"idle"`      // 无活动超时, default: 7d
+	RedisAddr string `yaml:"redisaddr"` // 设置后会话保存到redis, 否则保存到数据库
+	RedisPwd  string `yaml:"redispwd"`
+	RedisDB   int    `yaml:"redisdb"`
+}
+
+// OIDC admin single sign-on, authorization code flow with PKCE
+type OIDC struct {
+	Enable       bool              `yaml:"enable"`
+	Name         string            `yaml:"name"`         // 登录按钮显示名称, default: SSO
+	Issuer       string            `yaml:"issuer"`       // 如: https://accounts.google.com
+	ClientID     string            `yaml:"clientid"`     // *
+	ClientSecret string            `yaml:"clientsecret"` // public client可为空
+	RedirectURL  string            `yaml:"redirecturl"`  // default: https://<host>/admin/oidc/callback
+	Scopes       []string          `yaml:"scopes"`       // default: openid email profile
+	Identities   map[string]string `yaml:"identities"`   // 允许登录的身份(sub或已验证的email) -> 本地账号
+}
+
+// WebAuthn admin passkey login
+type WebAuthn struct {
+	RPID    string   `yaml:"rpid"`    // default: host
+	Origins []string `yaml:"origins"` // default: https://<host>
+}
+
+// Metrics prometheus metrics
+type Metrics struct {
+	Enable bool `yaml:"enable"`
+	Port   int  `yaml:"port"` // 单独的监听端口, 为0时使用httpport
+}
+
+// HTTPCache 前台页面及静态文件的缓存与压缩
+type HTTPCache struct {
+	Pages    string   `yaml:"pages"`    // 首页, 文章, 专题及归档页的Cache-Control, default: public, no-cache
+	Files    string   `yaml:"files"`    // rss, sitemap及不带版本号的/static, default: public, max-age=3600
+	Static   string   `yaml:"static"`   // 带版本号(?v=)的/static, default: public, max-age=31536000, immutable
+	Compress []string `yaml:"compress"` // 按优先级协商的压缩算法, br, gzip, off关闭, default: [br, gzip]
+}
+
+// PageCache 前台页面渲染结果缓存
+type PageCache struct {
+	Enable  bool   `yaml:"enable"`
+	MaxSize int    `yaml:"maxsize"` // 缓存大小上限, 单位MB, default: 32
+	TTL     string `yaml:"ttl"`     // 缓存时长, 用于"N天前"等随时间变化的内容, default: 1h
+}
+
+// GRPC content management api, 见 mode.enablegrpc
+type GRPC struct {
+	Tokens map[string]string `yaml:"tokens"` // 名称:token, 名称用于审计日志, 可热加载
+}
+
+// Log logging
+type Log struct {
+	Level      string `yaml:"level"`      // debug, info, warn, error, default: info
+	Format     string `yaml:"format"`     // text, json, default: text
+	File       string `yaml:"file"`       // 日志文件, 为空时输出到标准错误
+	MaxSize    int    `yaml:"maxsize"`    // 单个文件大小, 超过后切割, 单位MB, default: 100
+	MaxAge     string `yaml:"maxage"`     // 切割后的文件保留时长, 为空时不清理, 如: 30d
+	MaxBackups int    `yaml:"maxbackups"` // 切割后的文件保留个数, 为0时不限制
+	Access     string `yaml:"access"`     // 访问日志格式, 为空时使用默认格式, off关闭访问日志
+}
+
+// Trace opentelemetry tracing
+type Trace struct {
+	Enable      bool    `yaml:"enable"`
+	Exporter    string  `yaml:"exporter"`    // otlp, stdout, default: otlp
+	Endpoint    string  `yaml:"endpoint"`    // otlp http地址, 为空时读取 OTEL_EXPORTER_OTLP_ENDPOINT, default: localhost:4318
+	Insecure    bool    `yaml:"insecure"`    // otlp 使用http而非https
+	SampleRatio float64 `yaml:"sampleratio"` // 采样比例, 0-1, 为0时全部采样
+}
+
+// Audit admin audit log
+type Audit struct {
+	Retention string `yaml:"retention"` // 审计日志及登录记录保留时长, 为空时永久保留, 如: 180d
+}
+
 // Account info
 type Account struct {
 	Username    string `yaml:"username"` // *
@@ -135,16 +296,28 @@ type EiBlogApp struct {
 	FeedRPC       FeedRPC    `yaml:"feedrpc"`
 	Account       Account    `yaml:"account"`
 	Blogger       Blogger    `yaml:"blogger"`
+	SMTP          SMTP       `yaml:"smtp"`
+	Newsletter    Newsletter `yaml:"newsletter"`
+	LoginLimit    LoginLimit `yaml:"loginlimit"`
+	Session       Session    `yaml:"session"`
+	Audit         Audit      `yaml:"audit"`
+	OIDC          OIDC       `yaml:"oidc"`
+	WebAuthn      WebAuthn   `yaml:"webauthn"`
+	Metrics       Metrics    `yaml:"metrics"`
+	HTTPCache     HTTPCache  `yaml:"httpcache"`
+	PageCache     PageCache  `yaml:"pagecache"`
+	GRPC          GRPC       `yaml:"grpc"`
 }
 
 // BackupApp config
 type BackupApp struct {
 	Mode
 
-	BackupTo string `yaml:"backupto"`
-	Interval string `yaml:"interval"` // circle backup, default: 7d
-	Validity int    `yaml:"validity"` // storage days, default: 60
-	Qiniu    Qiniu  `yaml:"qiniu"`    // qiniu config
+	BackupTo string  `yaml:"backupto"`
+	Interval string  `yaml:"interval"` // circle backup, default: 7d
+	Validity int     `yaml:"validity"` // storage days, default: 60
+	Qiniu    Qiniu   `yaml:"qiniu"`    // qiniu config
+	Metrics  Metrics `yaml:"metrics"`
 }
 
 // Config app config
@@ -153,41 +326,96 @@ type Config struct {
 	AppName   string    `yaml:"appname"`
 	Database  Database  `yaml:"database"`
 	ESHost    string    `yaml:"eshost"`
+	Log       Log       `yaml:"log"`
+	Trace     Trace     `yaml:"trace"`
 	EiBlogApp EiBlogApp `yaml:"eiblogapp"`
 	BackupApp BackupApp `yaml:"backupapp"`
 }
 
+// 命令行参数, 在包初始化时读取, 早于main中的flag.Parse
+var (
+	configFlag   = flag.String("config", "", "config file path, env: EIBLOG_CONFIG, default: conf/app.yml")
+	validateFlag = flag.Bool("validate", false, "validate config and exit")
+)
+
 // load config file
 func init() {
 	// compatibility linux and windows
-	var err error
 	WorkDir = workDir()
-	path := filepath.Join(WorkDir, "conf", "app.yml")
+	File = configFile(os.Args[1:])
 
+	conf, err := Load(File)
+	if v, ok := lookupArg(os.Args[1:], "validate", true); ok && v != "false" {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("config: %s ok\n", File)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	Conf = *conf
+	live.Store(conf)
+}
+
+// Load 读取配置文件, 使用环境变量覆盖后校验
+func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("config: read %s: %w", path, err)
 	}
-	err = yaml.Unmarshal(data, &Conf)
+	conf := new(Config)
+	err = yaml.Unmarshal(data, conf)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("config: parse %s: %w", path, err)
 	}
 	// read run mode from env
 	runmode := os.Getenv("RUN_MODE")
 	if runmode == ModeProd || runmode == ModeDev {
-		Conf.RunMode = runmode
+		conf.RunMode = runmode
 	}
 	// read env
-	readDBEnv()
+	err = conf.readEnv(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	err = conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return conf, nil
 }
 
-func readDBEnv() {
-	key := strings.ToUpper(Conf.AppName) + "_DB_DRIVER"
-	if d := os.Getenv(key); d != "" {
-		Conf.Database.Driver = d
+// configFile 配置文件路径, 依次为 -config 参数, EIBLOG_CONFIG 环境变量, conf/app.yml
+func configFile(args []string) string {
+	if path, ok := lookupArg(args, "config", false); ok && path != "" {
+		return path
 	}
-	key = strings.ToUpper(Conf.AppName) + "_DB_SOURCE"
-	if s := os.Getenv(key); s != "" {
-		Conf.Database.Source = s
+	if path := os.Getenv("EIBLOG_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(WorkDir, "conf", "app.yml")
+}
+
+// lookupArg 查找命令行参数, 支持 -name value, -name=value 及 --name, 布尔参数不读取下一个参数
+func lookupArg(args []string, name string, isBool bool) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if arg == name {
+			if !isBool && i+1 < len(args) {
+				return args[i+1], true
+			}
+			return "", true
+		}
+		if v, ok := strings.CutPrefix(arg, name+"="); ok {
+			return v, true
+		}
 	}
+	return "", false
 }
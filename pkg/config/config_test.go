@@ -0,0 +1,191 @@
+// Package config provides ...
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	err := os.WriteFile(secret, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"EIBLOG_DB_SOURCE":                             "legacy.db",
+		"EIBLOG_DATABASE_DRIVER":                       "postgres",
+		"EIBLOG_EIBLOGAPP_MODE_HTTPPORT":               "8080",
+		"EIBLOG_EIBLOGAPP_SMTP_ENABLE":                 "true",
+		"EIBLOG_EIBLOGAPP_HOTWORDS":                    "go, docker,",
+		"EIBLOG_EIBLOGAPP_OIDC_IDENTITIES":             "alice@example.com=deepzz",
+		"EIBLOG_EIBLOGAPP_STATIC_QINIU_SECRETKEY_FILE": secret,
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	conf := &Config{AppName: "eiblog"}
+	if err = conf.readEnv(lookup); err != nil {
+		t.Fatal(err)
+	}
+	app := conf.EiBlogApp
+	if conf.Database.Driver != "postgres" || conf.Database.Source != "legacy.db" {
+		t.Errorf("database = %+v", conf.Database)
+	}
+	if app.HTTPPort != 8080 || !app.SMTP.Enable {
+		t.Errorf("httpport = %d, smtp.enable = %v", app.HTTPPort, app.SMTP.Enable)
+	}
+	if !reflect.DeepEqual(app.HotWords, []string{"go", "docker"}) {
+		t.Errorf("hotwords = %q", app.HotWords)
+	}
+	if app.OIDC.Identities["alice@example.com"] != "deepzz" {
+		t.Errorf("identities = %v", app.OIDC.Identities)
+	}
+	if app.StaticFile.Qiniu.SecretKey != "s3cret" {
+		t.Errorf("secretkey = %q, want from file", app.StaticFile.Qiniu.SecretKey)
+	}
+
+	env = map[string]string{"EIBLOG_EIBLOGAPP_GENERAL_PAGENUM": "ten"}
+	err = conf.readEnv(lookup)
+	if err == nil || !strings.Contains(err.Error(), "EIBLOG_EIBLOGAPP_GENERAL_PAGENUM") {
+		t.Errorf("readEnv() error = %v, want invalid int", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	conf, err := Load(File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.RunMode = "test"
+	conf.EiBlogApp.Host = ""
+	conf.EiBlogApp.General.Timezone = "Mars/Olympus"
+	conf.EiBlogApp.General.Markdown = "pandoc"
+	conf.EiBlogApp.Session.MaxAge = "30x"
+	conf.EiBlogApp.SMTP = SMTP{Enable: true, Port: 0, From: "nobody"}
+	conf.EiBlogApp.WebAuthn.Origins = []string{"example.com"}
+	conf.Log = Log{Level: "verbose", Format: "xml", MaxAge: "forever"}
+	conf.Trace = Trace{Enable: true, Exporter: "jaeger", SampleRatio: 2}
+	conf.EiBlogApp.HTTPCache.Compress = []string{"br", "zstd"}
+	conf.EiBlogApp.PageCache = PageCache{MaxSize: -1, TTL: "1y"}
+	conf.EiBlogApp.TLS = TLS{Enable: true, CertFile: "conf/ssl/domain.crt", RedirectPort: 100000}
+	conf.EiBlogApp.Timeout.Read = "soon"
+	conf.EiBlogApp.TrustedProxies = []string{"10.0.0.0/8", "nginx"}
+	conf.EiBlogApp.EnableGRPC, conf.EiBlogApp.GRPCPort = true, conf.EiBlogApp.HTTPPort
+	conf.EiBlogApp.GRPC.Tokens = map[string]string{"ci": "short"}
+	conf.EiBlogApp.URLScheme = "ftp"
+	conf.EiBlogApp.Socket.Mode = "rw-rw----"
+	conf.BackupApp.Listen = "/run/backup.sock"
+
+	err = conf.Validate()
+	verr, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("Validate() error = %v, want ValidationError", err)
+	}
+	for _, field := range []string{
+		"runmode",
+		"eiblogapp.mode.host",
+		"eiblogapp.general.timezone",
+		"eiblogapp.general.markdown",
+		"eiblogapp.session.maxage",
+		"eiblogapp.smtp.host",
+		"eiblogapp.smtp.port",
+		"eiblogapp.smtp.from",
+		"eiblogapp.webauthn.origins[0]",
+		"log.level",
+		"log.format",
+		"log.maxage",
+		"trace.exporter",
+		"trace.sampleratio",
+		"eiblogapp.httpcache.compress[1]",
+		"eiblogapp.pagecache.maxsize",
+		"eiblogapp.pagecache.ttl",
+		"eiblogapp.mode.tls.keyfile",
+		"eiblogapp.mode.tls.redirectport",
+		"eiblogapp.mode.timeout.read",
+		"eiblogapp.mode.trustedproxies[1]",
+		"eiblogapp.mode.scheme",
+		"eiblogapp.mode.socket.mode",
+		"eiblogapp.mode.grpcport",
+		"eiblogapp.grpc.tokens.ci",
+		"backupapp.mode.listen",
+	} {
+		found := false
+		for _, e := range verr {
+			found = found || strings.HasPrefix(e, field+": ")
+		}
+		if !found {
+			t.Errorf("missing error for %s in:\n%v", field, err)
+		}
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		host string
+		want string
+	}{
+		{Mode{Host: "example.com"}, "", "http://example.com/rss.html"},
+		{Mode{Host: "example.com", TLS: TLS{Enable: true}}, "", "https://example.com/rss.html"},
+		// 由代理提供https时需明确配置
+		{Mode{Host: "example.com", Listen: "unix:/run/eiblog.sock"}, "", "http://example.com/rss.html"},
+		{Mode{Host: "example.com", TrustedProxies: []string{"127.0.0.1"}}, "", "http://example.com/rss.html"},
+		{Mode{Host: "example.com", URLScheme: "https"}, "", "https://example.com/rss.html"},
+		{Mode{Host: "example.com", URLScheme: "http", TLS: TLS{Enable: true}}, "", "http://example.com/rss.html"},
+		{Mode{Host: "example.com", TLS: TLS{Enable: true}}, "st.example.com", "https://st.example.com/rss.html"},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.SiteURL(tt.host, "/rss.html"); got != tt.want {
+			t.Errorf("SiteURL(%q) with %+v = %q, want %q", tt.host, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestLookupArg(t *testing.T) {
+	tests := []struct {
+		args   []string
+		name   string
+		isBool bool
+		value  string
+		ok     bool
+	}{
+		{[]string{"-config", "a.yml"}, "config", false, "a.yml", true},
+		{[]string{"--config=b.yml", "-d"}, "config", false, "b.yml", true},
+		{[]string{"-d", "--", "-config", "c.yml"}, "config", false, "", false},
+		{[]string{"-validate", "-config", "a.yml"}, "validate", true, "", true},
+		{[]string{"-validate=false"}, "validate", true, "false", true},
+		{[]string{"-l", "eiblog.log"}, "validate", true, "", false},
+	}
+	for _, tt := range tests {
+		value, ok := lookupArg(tt.args, tt.name, tt.isBool)
+		if value != tt.value || ok != tt.ok {
+			t.Errorf("lookupArg(%q, %s) = %q, %v, want %q, %v",
+				tt.args, tt.name, value, ok, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := Config{AppName: "eiblog"}
+	old.EiBlogApp.HotWords = []string{"go"}
+	old.EiBlogApp.HTTPPort = 9000
+	nw := old
+	nw.EiBlogApp.StaticVersion = 2
+	nw.EiBlogApp.HotWords = []string{"go", "docker"}
+	nw.EiBlogApp.Twitter.Site = "deepzz"
+	nw.EiBlogApp.HTTPPort = 8080
+	nw.EiBlogApp.OIDC.Scopes = []string{"openid"}
+	nw.EiBlogApp.HTTPCache.Pages = "no-store"
+	nw.EiBlogApp.HTTPCache.Compress = []string{"gzip"}
+
+	fields := restartRequired("", reflect.ValueOf(old), reflect.ValueOf(nw))
+	want := []string{"eiblogapp.mode.httpport", "eiblogapp.oidc.scopes", "eiblogapp.httpcache.compress"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("restartRequired() = %q, want %q", fields, want)
+	}
+}
@@ -0,0 +1,167 @@
+// Package config provides ...
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 环境变量覆盖配置, 命名规则为 <APPNAME>_<yaml路径>, 路径各级以下划线连接并大写, 如:
+//
+//	EIBLOG_DATABASE_SOURCE                   -> database.source
+//	EIBLOG_EIBLOGAPP_MODE_HTTPPORT           -> eiblogapp.mode.httpport
+//	EIBLOG_EIBLOGAPP_STATIC_QINIU_SECRETKEY  -> eiblogapp.static.qiniu.secretkey
+//
+// 变量名加 _FILE 后缀时从文件读取值(去掉末尾换行), 用于docker secrets等场景.
+// 列表以逗号分隔, map以 key=value 逗号分隔.
+// 兼容旧版的 <APPNAME>_DB_DRIVER 及 <APPNAME>_DB_SOURCE.
+
+// lookupEnvFunc 读取环境变量, 便于测试替换
+type lookupEnvFunc func(key string) (string, bool)
+
+// envPrefix 环境变量前缀
+func (c *Config) envPrefix() string {
+	name := c.AppName
+	if name == "" {
+		name = "eiblog"
+	}
+	return strings.ToUpper(name)
+}
+
+// readEnv 使用环境变量覆盖配置
+func (c *Config) readEnv(lookup lookupEnvFunc) error {
+	prefix := c.envPrefix()
+	// 兼容旧版变量名
+	legacy := map[string]*string{
+		prefix + "_DB_DRIVER": &c.Database.Driver,
+		prefix + "_DB_SOURCE": &c.Database.Source,
+	}
+	for key, field := range legacy {
+		v, ok, err := lookupValue(lookup, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*field = v
+		}
+	}
+	return setEnv(lookup, prefix, reflect.ValueOf(c).Elem())
+}
+
+// setEnv 递归设置结构体字段
+func setEnv(lookup lookupEnvFunc, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := yamlName(field)
+		if name == "-" {
+			continue
+		}
+		key := prefix + "_" + strings.ToUpper(name)
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := setEnv(lookup, key, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok, err := lookupValue(lookup, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err = setValue(fv, s); err != nil {
+			return fmt.Errorf("config: env %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// yamlName 字段对应的yaml键名
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// lookupValue 读取环境变量, 未设置时尝试 _FILE 变量
+func lookupValue(lookup lookupEnvFunc, key string) (string, bool, error) {
+	if v, ok := lookup(key); ok {
+		return v, true, nil
+	}
+	path, ok := lookup(key + "_FILE")
+	if !ok || path == "" {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("config: env %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// setValue 按字段类型解析
+func setValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid int %q", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid float %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		m := make(map[string]string)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid map item %q, want key=value", item)
+			}
+			m[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
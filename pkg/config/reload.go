@@ -0,0 +1,65 @@
+// Package config provides ...
+package config
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// live 当前生效的配置, 热加载时整体替换
+var live atomic.Pointer[Config]
+
+// hotFields 无需重启即可生效的配置项, 读取时须使用 Current()
+var hotFields = map[string]bool{
+	"eiblogapp.staticversion":    true,
+	"eiblogapp.hotwords":         true,
+	"eiblogapp.twitter":          true,
+	"eiblogapp.google":           true,
+	"eiblogapp.httpcache.pages":  true,
+	"eiblogapp.httpcache.files":  true,
+	"eiblogapp.httpcache.static": true,
+	"eiblogapp.grpc.tokens":      true,
+}
+
+// Current 当前生效的配置, 热加载后立即更新
+func Current() *Config {
+	return live.Load()
+}
+
+// Reload 重新读取配置文件, 校验失败时保留原配置. 返回已修改但需重启才能生效的配置项
+func Reload() ([]string, error) {
+	conf, err := Load(File)
+	if err != nil {
+		return nil, err
+	}
+	restart := restartRequired("", reflect.ValueOf(*live.Load()), reflect.ValueOf(*conf))
+	live.Store(conf)
+	return restart, nil
+}
+
+// restartRequired 比较新旧配置, 返回 hotFields 以外发生变化的配置项
+func restartRequired(prefix string, old, new reflect.Value) []string {
+	var fields []string
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := yamlName(t.Field(i))
+		if name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if hotFields[name] {
+			continue
+		}
+		o, n := old.Field(i), new.Field(i)
+		if o.Kind() == reflect.Struct {
+			fields = append(fields, restartRequired(name, o, n)...)
+			continue
+		}
+		if !reflect.DeepEqual(o.Interface(), n.Interface()) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
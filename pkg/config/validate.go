@@ -0,0 +1,255 @@
+// Package config provides ...
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eiblog/eiblog/tools"
+)
+
+// ValidationError 配置校验错误, 每项为 "字段路径: 原因"
+type ValidationError []string
+
+// Error 每项一行
+func (e ValidationError) Error() string {
+	return "config: invalid config:\n  " + strings.Join(e, "\n  ")
+}
+
+// validator 收集校验错误
+type validator struct {
+	errs ValidationError
+}
+
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, field+": "+fmt.Sprintf(format, args...))
+}
+
+// required 不能为空
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(field, "is required")
+	}
+}
+
+// oneOf 为空或在可选值内
+func (v *validator) oneOf(field, value string, options ...string) {
+	if value == "" {
+		return
+	}
+	for _, o := range options {
+		if value == o {
+			return
+		}
+	}
+	v.addf(field, "must be one of %s, got %q", strings.Join(options, ", "), value)
+}
+
+// port 端口范围
+func (v *validator) port(field string, port int) {
+	if port < 1 || port > 65535 {
+		v.addf(field, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+// positive 大于0
+func (v *validator) positive(field string, n int) {
+	if n <= 0 {
+		v.addf(field, "must be greater than 0, got %d", n)
+	}
+}
+
+// url 为空或为http(s)地址
+func (v *validator) url(field, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(field, "must be an http(s) URL, got %q", value)
+	}
+}
+
+// duration 为空或为有效时长, 如: 30s, 15m, 2h, 7d
+func (v *validator) duration(field, value string) {
+	if value == "" {
+		return
+	}
+	d, err := tools.ParseDuration(value)
+	if err != nil || d <= 0 {
+		v.addf(field, "must be a duration like 30m, 2h or 7d, got %q", value)
+	}
+}
+
+// timezone 有效时区
+func (v *validator) timezone(field, value string) {
+	if _, err := time.LoadLocation(value); err != nil {
+		v.addf(field, "unknown time zone %q", value)
+	}
+}
+
+// mode 服务端口, tls及超时
+func (v *validator) mode(field string, mode Mode) {
+	if mode.EnableHTTP {
+		if mode.Listen == "" {
+			v.port(field+".httpport", mode.HTTPPort)
+		} else if !strings.HasPrefix(mode.Listen, "unix:") || len(mode.Listen) == len("unix:") {
+			v.addf(field+".listen", "must be like unix:/path/to/eiblog.sock, got %q", mode.Listen)
+		}
+	}
+	if mode.Socket.Mode != "" {
+		if perm, err := strconv.ParseUint(mode.Socket.Mode, 8, 32); err != nil || perm > 0777 {
+			v.addf(field+".socket.mode", "must be an octal file mode like 0660, got %q", mode.Socket.Mode)
+		}
+	}
+	v.oneOf(field+".scheme", mode.URLScheme, "http", "https")
+	if mode.EnableGRPC {
+		v.port(field+".grpcport", mode.GRPCPort)
+		if mode.EnableHTTP && mode.Listen == "" && mode.GRPCPort == mode.HTTPPort {
+			v.addf(field+".grpcport", "must differ from httpport %d", mode.HTTPPort)
+		}
+	}
+	if mode.TLS.Enable {
+		v.required(field+".tls.certfile", mode.TLS.CertFile)
+		v.required(field+".tls.keyfile", mode.TLS.KeyFile)
+		if mode.TLS.RedirectPort != 0 {
+			v.port(field+".tls.redirectport", mode.TLS.RedirectPort)
+		}
+	}
+	v.duration(field+".timeout.readheader", mode.Timeout.ReadHeader)
+	v.duration(field+".timeout.read", mode.Timeout.Read)
+	v.duration(field+".timeout.write", mode.Timeout.Write)
+	v.duration(field+".timeout.idle", mode.Timeout.Idle)
+	for i, proxy := range mode.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			v.addf(fmt.Sprintf("%s.trustedproxies[%d]", field, i), "must be an IP or CIDR, got %q", proxy)
+		}
+	}
+}
+
+// metrics 单独端口
+func (v *validator) metrics(field string, m Metrics) {
+	if m.Enable && m.Port != 0 {
+		v.port(field+".port", m.Port)
+	}
+}
+
+// Validate 校验必填项, 地址, 时长及时区等, 返回所有错误
+func (c *Config) Validate() error {
+	v := &validator{}
+	v.oneOf("runmode", c.RunMode, ModeDev, ModeProd)
+	v.required("appname", c.AppName)
+	v.required("database.driver", c.Database.Driver)
+	v.oneOf("database.driver", c.Database.Driver,
+		"mongodb", "mysql", "postgres", "sqlite", "sqlserver", "clickhouse")
+	v.required("database.source", c.Database.Source)
+	v.url("eshost", c.ESHost)
+	v.oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error")
+	v.oneOf("log.format", c.Log.Format, "text", "json")
+	if c.Log.MaxSize < 0 {
+		v.addf("log.maxsize", "must not be negative, got %d", c.Log.MaxSize)
+	}
+	if c.Log.MaxBackups < 0 {
+		v.addf("log.maxbackups", "must not be negative, got %d", c.Log.MaxBackups)
+	}
+	v.duration("log.maxage", c.Log.MaxAge)
+	if c.Trace.Enable {
+		v.oneOf("trace.exporter", c.Trace.Exporter, "otlp", "stdout")
+		if c.Trace.SampleRatio < 0 || c.Trace.SampleRatio > 1 {
+			v.addf("trace.sampleratio", "must be between 0 and 1, got %v", c.Trace.SampleRatio)
+		}
+	}
+
+	app := c.EiBlogApp
+	v.mode("eiblogapp.mode", app.Mode)
+	v.required("eiblogapp.mode.host", app.Host)
+	v.positive("eiblogapp.general.pagenum", app.General.PageNum)
+	v.positive("eiblogapp.general.pagesize", app.General.PageSize)
+	v.timezone("eiblogapp.general.timezone", app.General.Timezone)
+	v.oneOf("eiblogapp.general.markdown", app.General.Markdown, "blackfriday", "goldmark")
+	v.url("eiblogapp.remark42.domain", app.Remark42.Domain)
+	v.url("eiblogapp.google.url", app.Google.URL)
+	v.url("eiblogapp.feedrpc.feedrurl", app.FeedRPC.FeedrURL)
+	for i, u := range app.FeedRPC.PingRPC {
+		v.url(fmt.Sprintf("eiblogapp.feedrpc.pingrpc[%d]", i), u)
+	}
+	v.required("eiblogapp.static.type", app.StaticFile.Type)
+	v.oneOf("eiblogapp.static.type", app.StaticFile.Type, "qiniu", "local")
+	switch app.StaticFile.Type {
+	case "qiniu":
+		v.required("eiblogapp.static.qiniu.bucket", app.StaticFile.Qiniu.Bucket)
+		v.required("eiblogapp.static.qiniu.accesskey", app.StaticFile.Qiniu.AccessKey)
+		v.required("eiblogapp.static.qiniu.secretkey", app.StaticFile.Qiniu.SecretKey)
+	case "local":
+		v.required("eiblogapp.static.local.path", app.StaticFile.LocalStor.LocalPath)
+	}
+	v.required("eiblogapp.account.username", app.Account.Username)
+	v.required("eiblogapp.account.password", app.Account.Password)
+	if app.SMTP.Enable {
+		v.required("eiblogapp.smtp.host", app.SMTP.Host)
+		v.port("eiblogapp.smtp.port", app.SMTP.Port)
+		if _, err := mail.ParseAddress(app.SMTP.From); err != nil {
+			v.addf("eiblogapp.smtp.from", "must be an email address, got %q", app.SMTP.From)
+		}
+	}
+	if app.Newsletter.Enable {
+		if !app.SMTP.Enable {
+			v.addf("eiblogapp.newsletter.enable", "requires eiblogapp.smtp.enable")
+		}
+		v.oneOf("eiblogapp.newsletter.mode", app.Newsletter.Mode, "post", "digest")
+		v.duration("eiblogapp.newsletter.interval", app.Newsletter.Interval)
+	}
+	v.duration("eiblogapp.loginlimit.window", app.LoginLimit.Window)
+	v.duration("eiblogapp.loginlimit.lockout", app.LoginLimit.Lockout)
+	v.duration("eiblogapp.loginlimit.maxlockout", app.LoginLimit.MaxLockout)
+	v.duration("eiblogapp.session.maxage", app.Session.MaxAge)
+	v.duration("eiblogapp.session.idle", app.Session.Idle)
+	v.duration("eiblogapp.audit.retention", app.Audit.Retention)
+	if app.OIDC.Enable {
+		v.required("eiblogapp.oidc.issuer", app.OIDC.Issuer)
+		v.url("eiblogapp.oidc.issuer", app.OIDC.Issuer)
+		v.required("eiblogapp.oidc.clientid", app.OIDC.ClientID)
+		v.url("eiblogapp.oidc.redirecturl", app.OIDC.RedirectURL)
+	}
+	for i, u := range app.WebAuthn.Origins {
+		v.url(fmt.Sprintf("eiblogapp.webauthn.origins[%d]", i), u)
+	}
+	v.metrics("eiblogapp.metrics", app.Metrics)
+	for i, enc := range app.HTTPCache.Compress {
+		v.oneOf(fmt.Sprintf("eiblogapp.httpcache.compress[%d]", i), enc, "br", "gzip", "off")
+	}
+	if app.PageCache.MaxSize < 0 {
+		v.addf("eiblogapp.pagecache.maxsize", "must not be negative, got %d", app.PageCache.MaxSize)
+	}
+	v.duration("eiblogapp.pagecache.ttl", app.PageCache.TTL)
+	if app.EnableGRPC && len(app.GRPC.Tokens) == 0 {
+		v.addf("eiblogapp.grpc.tokens", "is required when eiblogapp.mode.enablegrpc is true")
+	}
+	names := make([]string, 0, len(app.GRPC.Tokens))
+	for name := range app.GRPC.Tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(app.GRPC.Tokens[name]) < 16 {
+			v.addf("eiblogapp.grpc.tokens."+name, "must be at least 16 characters")
+		}
+	}
+
+	backup := c.BackupApp
+	v.mode("backupapp.mode", backup.Mode)
+	v.oneOf("backupapp.backupto", backup.BackupTo, "qiniu")
+	v.duration("backupapp.interval", backup.Interval)
+	v.metrics("backupapp.metrics", backup.Metrics)
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
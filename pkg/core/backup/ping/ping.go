@@ -2,7 +2,14 @@
 package ping
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/core/backup/timer"
+	"github.com/eiblog/eiblog/pkg/health"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,9 +17,32 @@ import (
 // RegisterRoutes register routes
 func RegisterRoutes(group gin.IRoutes) {
 	group.GET("/ping", handlePing)
+	newChecker().RegisterRoutes(group)
 }
 
 // handlePing ping
 func handlePing(c *gin.Context) {
 	c.String(http.StatusOK, "it's ok")
 }
+
+// newChecker 就绪检查: 备份任务是否运行及最近一次备份结果
+func newChecker() *health.Checker {
+	h := health.NewChecker()
+	h.Add("backup", func(ctx context.Context) (string, error) {
+		status := timer.LoadStatus()
+		if !status.Running {
+			return "", errors.New("backup timer is not running")
+		}
+		next := "next at " + status.Next.Format(time.RFC3339)
+		if status.Last.IsZero() {
+			return "no backup yet, " + next, nil
+		}
+		detail := fmt.Sprintf("last at %s, took %s, %s", status.Last.Format(time.RFC3339),
+			status.Duration.Round(time.Second), next)
+		if status.Err != nil {
+			return detail, fmt.Errorf("last backup failed: %w", status.Err)
+		}
+		return detail, nil
+	})
+	return h
+}
@@ -14,16 +14,18 @@ import (
 	"github.com/eiblog/eiblog/pkg/cache/store"
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/internal"
+
+	"github.com/sirupsen/logrus"
 )
 
 // Storage qiniu storage
 type Storage struct{}
 
 // BackupData implements timer.Storage
-func (s Storage) BackupData(now time.Time) error {
+func (s Storage) BackupData(ctx context.Context, now time.Time) error {
 	switch config.Conf.Database.Driver {
 	case "mongodb":
-		return backupFromMongoDB(now)
+		return backupFromMongoDB(ctx, now)
 	default:
 		return errors.New("unsupported source backup to qiniu: " +
 			config.Conf.Database.Driver)
@@ -31,20 +33,24 @@ func (s Storage) BackupData(now time.Time) error {
 }
 
 // RestoreData implements timer.Storage
-func (s Storage) RestoreData() error {
+func (s Storage) RestoreData(ctx context.Context) error {
 	switch config.Conf.Database.Driver {
 	case "mongodb":
-		return restoreToMongoDB()
+		return restoreToMongoDB(ctx)
 	default:
 		return errors.New("unsupported source restore from qiniu: " +
 			config.Conf.Database.Driver)
 	}
 }
 
-func backupFromMongoDB(now time.Time) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*20)
+func backupFromMongoDB(ctx context.Context, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*20)
 	defer cancel()
 
+	name := fmt.Sprintf("eiblog-%s.tar.gz", now.Format("2006-01-02"))
+	// 完成或中断后清理临时文件
+	defer cleanTemp("/tmp/eiblog", "/tmp/"+name)
+
 	// dump
 	u, err := url.Parse(config.Conf.Database.Source)
 	if err != nil {
@@ -57,7 +63,6 @@ func backupFromMongoDB(now time.Time) error {
 		return err
 	}
 	// tar
-	name := fmt.Sprintf("eiblog-%s.tar.gz", now.Format("2006-01-02"))
 	arg = fmt.Sprintf("tar czf /tmp/%s -C /tmp eiblog", name)
 	cmd = exec.CommandContext(ctx, "sh", "-c", arg)
 	err = cmd.Run()
@@ -70,6 +75,7 @@ func backupFromMongoDB(now time.Time) error {
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 	s, err := f.Stat()
 	if err != nil {
 		return err
@@ -97,7 +103,12 @@ func backupFromMongoDB(now time.Time) error {
 	return internal.QiniuDelete(deleteParams)
 }
 
-func restoreToMongoDB() error {
+func restoreToMongoDB(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*20)
+	defer cancel()
+
+	// 完成或中断后清理临时文件
+	defer cleanTemp("/tmp/eiblog", "/tmp/eiblog.tar.gz")
 	// backup file
 	params := internal.QiniuContentParams{
 		Prefix: "blog/",
@@ -108,21 +119,17 @@ func restoreToMongoDB() error {
 	if err != nil {
 		return err
 	}
-	f, err := os.OpenFile("/tmp/eiblog.tar.gz", os.O_WRONLY|os.O_CREATE, 0644)
+	err = os.WriteFile("/tmp/eiblog.tar.gz", raw, 0644)
 	if err != nil {
 		return err
 	}
-	_, _ = f.Write(raw)
-	defer f.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*20)
-	defer cancel()
 	// drop database
 	store, err := store.NewStore(config.Conf.Database.Driver,
 		config.Conf.Database.Source)
 	if err != nil {
 		return err
 	}
+	defer store.Close(context.Background())
 	err = store.DropDatabase(ctx)
 	if err != nil {
 		return err
@@ -143,3 +150,13 @@ func restoreToMongoDB() error {
 	cmd = exec.CommandContext(ctx, "sh", "-c", arg)
 	return cmd.Run()
 }
+
+// cleanTemp 删除临时文件
+func cleanTemp(paths ...string) {
+	for _, path := range paths {
+		err := os.RemoveAll(path)
+		if err != nil {
+			logrus.Error("qiniu: cleanTemp.RemoveAll: ", err)
+		}
+	}
+}
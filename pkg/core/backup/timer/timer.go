@@ -2,18 +2,56 @@
 package timer
 
 import (
+	"context"
 	"errors"
-	"strconv"
+	"sync"
 	"time"
 
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/core/backup/timer/qiniu"
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/pkg/trace"
+	"github.com/eiblog/eiblog/tools"
 
 	"github.com/sirupsen/logrus"
 )
 
-// Start to backup with ticker
-func Start(restore bool) (err error) {
+// status 备份任务状态
+var status struct {
+	sync.Mutex
+	running  bool
+	next     time.Time
+	last     time.Time
+	duration time.Duration
+	err      error
+}
+
+// Status 备份任务状态
+type Status struct {
+	Running  bool          // 定时任务是否运行
+	Next     time.Time     // 下次备份时间
+	Last     time.Time     // 最近一次备份时间, 未备份为零值
+	Duration time.Duration // 最近一次备份耗时
+	Err      error         // 最近一次备份结果
+}
+
+// LoadStatus 读取备份任务状态
+func LoadStatus() Status {
+	status.Lock()
+	defer status.Unlock()
+
+	return Status{
+		Running:  status.running,
+		Next:     status.next,
+		Last:     status.last,
+		Duration: status.duration,
+		Err:      status.err,
+	}
+}
+
+// Start to backup with ticker, ctx 取消后返回, 进行中的备份会被终止并清理临时文件
+func Start(ctx context.Context, restore bool) (err error) {
 	var storage Storage
 	// backup instance
 	switch config.Conf.BackupApp.BackupTo {
@@ -25,7 +63,7 @@ func Start(restore bool) (err error) {
 			config.Conf.BackupApp.BackupTo)
 	}
 	if restore {
-		err = storage.RestoreData()
+		err = storage.RestoreData(ctx)
 		if err != nil {
 			return err
 		}
@@ -37,38 +75,73 @@ func Start(restore bool) (err error) {
 		return err
 	}
 	t := time.NewTicker(interval)
-	for now := range t.C {
-		err = storage.BackupData(now)
+	defer t.Stop()
+	status.Lock()
+	status.running = true
+	status.next = time.Now().Add(interval)
+	status.Unlock()
+	defer func() {
+		status.Lock()
+		status.running = false
+		status.Unlock()
+	}()
+	for {
+		var now time.Time
+		select {
+		case now = <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+		spanCtx, span := trace.Start(ctx, "backup "+config.Conf.BackupApp.BackupTo)
+		err = storage.BackupData(spanCtx, now)
+		trace.End(span, err)
+		if ctx.Err() != nil {
+			logrus.Warn("timer: Start.BackupData canceled: ", now)
+			return nil
+		}
 		if err != nil {
 			logrus.Error("timer: Start.BackupData: ", now, err)
 		}
+		metrics.ObserveBackup(now, err)
+		status.Lock()
+		status.last, status.duration, status.err = now, time.Since(now), err
+		status.next = now.Add(interval)
+		status.Unlock()
+		notifyBackup(now, err)
 	}
-	return nil
 }
 
-// ParseDuration parse string to duration
-func ParseDuration(d string) (time.Duration, error) {
-	if len(d) == 0 {
-		return 0, errors.New("timer: incorrect duration input")
+// notifyBackup 邮件通知备份结果
+func notifyBackup(now time.Time, err error) {
+	conf := config.Conf.EiBlogApp.SMTP
+	to := config.Conf.EiBlogApp.Account.Email
+	if !conf.Enable || to == "" {
+		return
 	}
-
-	length := len(d)
-	switch d[length-1] {
-	case 's', 'm', 'h':
-		return time.ParseDuration(d)
-	case 'd':
-		di, err := strconv.Atoi(d[:length-1])
-		if err != nil {
-			return 0, err
-		}
-		return time.Duration(di) * time.Hour * 24, nil
+	data := map[string]interface{}{
+		"App":      config.Conf.BackupApp.Name,
+		"Time":     now.Format("2006-01-02 15:04:05"),
+		"Duration": time.Since(now).Round(time.Second).String(),
+		"Name":     now.Format("2006-01-02"),
+		"BackupTo": config.Conf.BackupApp.BackupTo,
+		"Validity": config.Conf.BackupApp.Validity,
+	}
+	if err != nil {
+		data["Error"] = err.Error()
 	}
+	err = internal.MailNotify(conf, []string{to}, internal.MailBackup, data)
+	if err != nil {
+		logrus.Error("timer: notifyBackup.MailNotify: ", err)
+	}
+}
 
-	return 0, errors.New("timer: unsupported duration:" + d)
+// ParseDuration parse string to duration
+func ParseDuration(d string) (time.Duration, error) {
+	return tools.ParseDuration(d)
 }
 
 // Storage backup backend
 type Storage interface {
-	BackupData(now time.Time) error
-	RestoreData() error
+	BackupData(ctx context.Context, now time.Time) error
+	RestoreData(ctx context.Context) error
 }
@@ -3,11 +3,14 @@ package admin
 
 import (
 	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/eiblog/eiblog/pkg/cache"
@@ -18,6 +21,7 @@ import (
 	"github.com/eiblog/eiblog/tools"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
@@ -31,6 +35,10 @@ const (
 // RegisterRoutes register routes
 func RegisterRoutes(e *gin.Engine) {
 	e.POST("/admin/login", handleAcctLogin)
+	e.POST("/admin/forgot", handleAcctForgot)
+	e.POST("/admin/reset", handleAcctReset)
+	registerOIDC(e)
+	registerWebAuthn(e)
 }
 
 // RegisterRoutesAuthz register routes
@@ -38,6 +46,15 @@ func RegisterRoutesAuthz(group gin.IRoutes) {
 	group.POST("/api/account", handleAPIAccount)
 	group.POST("/api/blog", handleAPIBlogger)
 	group.POST("/api/password", handleAPIPassword)
+	group.POST("/api/totp-enable", handleAPITOTPEnable)
+	group.POST("/api/totp-disable", handleAPITOTPDisable)
+	group.POST("/api/session-revoke", handleAPISessionRevoke)
+	group.POST("/api/session-revoke-all", handleAPISessionRevokeAll)
+	group.POST("/api/login-alerts-read", handleAPILoginAlertsRead)
+	group.POST("/api/passkey-register-begin", handleAPIPasskeyRegisterBegin)
+	group.POST("/api/passkey-register-finish", handleAPIPasskeyRegisterFinish)
+	group.POST("/api/passkey-rename", handleAPIPasskeyRename)
+	group.POST("/api/passkey-delete", handleAPIPasskeyDelete)
 	group.POST("/api/post-delete", handleAPIPostDelete)
 	group.POST("/api/post-add", handleAPIPostCreate)
 	group.POST("/api/serie-delete", handleAPISerieDelete)
@@ -48,37 +65,257 @@ func RegisterRoutesAuthz(group gin.IRoutes) {
 	group.POST("/api/trash-recover", handleAPITrashRecover)
 	group.POST("/api/file-upload", handleAPIUpload)
 	group.POST("/api/file-delete", handleAPIDelete)
+	group.POST("/api/webhook-add", handleAPIWebhookCreate)
+	group.POST("/api/webhook-delete", handleAPIWebhookDelete)
+	group.POST("/api/subscriber-delete", handleAPISubscriberDelete)
+	group.GET("/api/subscriber-export", handleAPISubscriberExport)
 }
 
 // handleAcctLogin 登录接口
 func handleAcctLogin(c *gin.Context) {
 	user := c.PostForm("user")
 	pwd := c.PostForm("password")
-	// code := c.PostForm("code") // 二次验证
+	code := c.PostForm("code") // 二次验证
+	ip, ua := c.ClientIP(), c.Request.UserAgent()
 	if user == "" || pwd == "" {
-		logrus.Warnf("参数错误: %s %s", user, pwd)
+		logrus.Warnf("参数错误: %s, %s", user, ip)
 		c.Redirect(http.StatusFound, "/admin/login")
 		return
 	}
-	if cache.Ei.Account.Username != user ||
-		cache.Ei.Account.Password != tools.EncryptPasswd(user, pwd) {
-		logrus.Warnf("账号或密码错误 %s, %s", user, pwd)
+	if wait := cache.Ei.LoginLocked(ip, user); wait > 0 {
+		logrus.Warnf("登录已锁定 %s, %s, %s", user, ip, wait)
+		c.Redirect(http.StatusFound, "/admin/login?wait="+strconv.Itoa(int(wait.Minutes())+1))
+		return
+	}
+	ok, rehash := tools.VerifyPassword(user, pwd, cache.Ei.Account.Password)
+	if cache.Ei.Account.Username != user || !ok {
+		logrus.Warnf("账号或密码错误 %s, %s", user, ip)
+		cache.Ei.LoginFailed(c.Request.Context(), ip, user, ua, "password")
+		auditLogin(c.Request.Context(), user, ip, cache.AuditLoginFailed, "password")
+		c.Redirect(http.StatusFound, "/admin/login")
+		return
+	}
+	if cache.Ei.Account.TOTPSecret != "" && !verifyTwoFactor(c.Request.Context(), code) {
+		logrus.Warnf("两步验证失败 %s, %s", user, ip)
+		cache.Ei.LoginFailed(c.Request.Context(), ip, user, ua, "totp")
+		auditLogin(c.Request.Context(), user, ip, cache.AuditLoginFailed, "totp")
 		c.Redirect(http.StatusFound, "/admin/login")
 		return
 	}
+	cache.Ei.LoginSucceeded(ip, user)
+	// 升级旧版密码摘要
+	if rehash {
+		upgradePassword(c.Request.Context(), user, pwd)
+	}
 	// 登录成功
-	eiblog.SetLogin(c, user)
+	err := eiblog.SetLogin(c, user)
+	if err != nil {
+		logrus.Error("handleAcctLogin.SetLogin: ", err)
+		c.Redirect(http.StatusFound, "/admin/login")
+		return
+	}
+	auditLogin(c.Request.Context(), user, ip, cache.AuditLogin, ua)
+	recordLogin(c, user)
+	c.Redirect(http.StatusFound, "/admin/profile")
+}
 
-	cache.Ei.Account.LoginIP = c.ClientIP()
+// recordLogin 记录登录历史及最近登录信息, 新设备登录时提醒并邮件通知
+func recordLogin(c *gin.Context, user string) {
+	ip, ua := c.ClientIP(), c.Request.UserAgent()
+	var sessionID string
+	if sess := eiblog.GetSession(c); sess != nil {
+		sessionID = sess.ID
+	}
+	if cache.Ei.RecordLogin(c.Request.Context(), user, ip, ua, sessionID) {
+		cache.Ei.NotifyMail(internal.MailNewDevice, map[string]interface{}{
+			"Username": user,
+			"Time":     time.Now().In(tools.TimeLocation).Format("2006-01-02 15:04:05"),
+			"IP":       ip,
+			"UA":       ua,
+		})
+	}
+	cache.Ei.Account.LoginIP = ip
+	cache.Ei.Account.LoginUA = ua
 	cache.Ei.Account.LoginAt = time.Now()
-	err := cache.Ei.UpdateAccount(context.Background(), user, map[string]interface{}{
+	err := cache.Ei.UpdateAccount(c.Request.Context(), user, map[string]interface{}{
 		"login_ip": cache.Ei.Account.LoginIP,
+		"login_ua": cache.Ei.Account.LoginUA,
 		"login_at": cache.Ei.Account.LoginAt,
 	})
 	if err != nil {
-		logrus.Error("handleAcctLogin.UpdateAccount error:", err)
+		logrus.Error("recordLogin.UpdateAccount error:", err)
 	}
-	c.Redirect(http.StatusFound, "/admin/profile")
+}
+
+// upgradePassword 使用新算法重新计算密码摘要
+func upgradePassword(ctx context.Context, user, pwd string) {
+	newPwd, err := tools.HashPassword(pwd)
+	if err != nil {
+		logrus.Error("upgradePassword.HashPassword: ", err)
+		return
+	}
+	err = cache.Ei.UpdateAccount(ctx, user, map[string]interface{}{
+		"password": newPwd,
+	})
+	if err != nil {
+		logrus.Error("upgradePassword.UpdateAccount: ", err)
+		return
+	}
+	cache.Ei.Account.Password = newPwd
+}
+
+// handleAPISessionRevoke 注销指定会话
+func handleAPISessionRevoke(c *gin.Context) {
+	id := c.PostForm("sid")
+	sessions, err := cache.Ei.ListSessions(c.Request.Context(), cache.Ei.Account.Username)
+	if err != nil {
+		logrus.Error("handleAPISessionRevoke.ListSessions: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	for _, sess := range sessions {
+		if sess.ID != id {
+			continue
+		}
+		err = cache.Ei.RevokeSession(c.Request.Context(), id)
+		if err != nil {
+			logrus.Error("handleAPISessionRevoke.RevokeSession: ", err)
+			responseNotice(c, NoticeNotice, err.Error(), "")
+			return
+		}
+		audit(c, cache.AuditSessionRevoke, "session:"+id[:8], sess.IP+" "+sess.UA, nil)
+		responseNotice(c, NoticeSuccess, "会话已注销", "")
+		return
+	}
+	responseNotice(c, NoticeNotice, "会话不存在", "")
+}
+
+// handleAPISessionRevokeAll 注销所有会话, 包括当前会话
+func handleAPISessionRevokeAll(c *gin.Context) {
+	err := cache.Ei.RevokeSessions(c.Request.Context(), cache.Ei.Account.Username, "")
+	if err != nil {
+		logrus.Error("handleAPISessionRevokeAll.RevokeSessions: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	audit(c, cache.AuditSessionRevoke, "session:*", nil, nil)
+	eiblog.SetLogout(c)
+	c.Redirect(http.StatusFound, "/admin/login")
+}
+
+// handleAPILoginAlertsRead 新设备登录提醒标记为已读
+func handleAPILoginAlertsRead(c *gin.Context) {
+	err := cache.Ei.ReadLoginAlerts(c.Request.Context())
+	if err != nil {
+		logrus.Error("handleAPILoginAlertsRead.ReadLoginAlerts: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	responseNotice(c, NoticeSuccess, "已确认新设备登录提醒", "")
+}
+
+// twoFactorLock 串行校验两步验证, 避免同一验证码或恢复码被并发使用
+var twoFactorLock sync.Mutex
+
+// verifyTwoFactor 校验两步验证码或恢复码, 验证码及恢复码均只能使用一次
+func verifyTwoFactor(ctx context.Context, code string) bool {
+	twoFactorLock.Lock()
+	defer twoFactorLock.Unlock()
+
+	account := cache.Ei.Account
+	if step, ok := tools.ValidateTOTP(account.TOTPSecret, code, time.Now(), account.TOTPStep); ok {
+		err := cache.Ei.UpdateAccount(ctx, account.Username,
+			map[string]interface{}{"totp_step": step})
+		if err != nil {
+			logrus.Error("verifyTwoFactor.UpdateAccount: ", err)
+			return false
+		}
+		account.TOTPStep = step
+		return true
+	}
+	if code == "" {
+		return false
+	}
+	hash := tools.HashRecoveryCode(code)
+	for i, v := range account.RecoveryCodes {
+		if v != hash {
+			continue
+		}
+		codes := append(pq.StringArray{}, account.RecoveryCodes[:i]...)
+		codes = append(codes, account.RecoveryCodes[i+1:]...)
+		err := cache.Ei.UpdateAccount(ctx, account.Username,
+			map[string]interface{}{"recovery_codes": codes})
+		if err != nil {
+			logrus.Error("verifyTwoFactor.UpdateAccount: ", err)
+			return false
+		}
+		account.RecoveryCodes = codes
+		return true
+	}
+	return false
+}
+
+// handleAPITOTPEnable 开启两步验证
+func handleAPITOTPEnable(c *gin.Context) {
+	secret := eiblog.GetPendingTOTP(c)
+	if secret == "" {
+		responseNotice(c, NoticeNotice, "请刷新页面后重试", "")
+		return
+	}
+	step, ok := tools.ValidateTOTP(secret, c.PostForm("code"), time.Now(), 0)
+	if !ok {
+		responseNotice(c, NoticeNotice, "验证码错误", "")
+		return
+	}
+	codes := tools.GenerateRecoveryCodes(10)
+	hashes := make(pq.StringArray, len(codes))
+	for i, v := range codes {
+		hashes[i] = tools.HashRecoveryCode(v)
+	}
+	err := cache.Ei.UpdateAccount(c.Request.Context(), cache.Ei.Account.Username,
+		map[string]interface{}{
+			"totp_secret":    secret,
+			"totp_step":      step,
+			"recovery_codes": hashes,
+		})
+	if err != nil {
+		logrus.Error("handleAPITOTPEnable.UpdateAccount: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	cache.Ei.Account.TOTPSecret = secret
+	cache.Ei.Account.TOTPStep = step
+	cache.Ei.Account.RecoveryCodes = hashes
+	eiblog.SetPendingTOTP(c, "")
+	eiblog.SetRecoveryCodes(c, codes)
+	audit(c, cache.AuditAccountTOTP, "account:"+cache.Ei.Account.Username, "disabled", "enabled")
+	responseNotice(c, NoticeSuccess, "两步验证已开启", "")
+}
+
+// handleAPITOTPDisable 关闭两步验证
+func handleAPITOTPDisable(c *gin.Context) {
+	if cache.Ei.Account.TOTPSecret == "" {
+		responseNotice(c, NoticeNotice, "两步验证未开启", "")
+		return
+	}
+	if !verifyTwoFactor(c.Request.Context(), c.PostForm("code")) {
+		responseNotice(c, NoticeNotice, "验证码错误", "")
+		return
+	}
+	err := cache.Ei.UpdateAccount(c.Request.Context(), cache.Ei.Account.Username,
+		map[string]interface{}{
+			"totp_secret":    "",
+			"recovery_codes": pq.StringArray{},
+		})
+	if err != nil {
+		logrus.Error("handleAPITOTPDisable.UpdateAccount: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	cache.Ei.Account.TOTPSecret = ""
+	cache.Ei.Account.RecoveryCodes = nil
+	audit(c, cache.AuditAccountTOTP, "account:"+cache.Ei.Account.Username, "enabled", "disabled")
+	responseNotice(c, NoticeSuccess, "两步验证已关闭", "")
 }
 
 // handleAPIBlogger 更新博客信息
@@ -94,27 +331,22 @@ func handleAPIBlogger(c *gin.Context) {
 		return
 	}
 
-	err := cache.Ei.UpdateBlogger(context.Background(), map[string]interface{}{
-		"blog_name":    bn,
-		"b_title":      bt,
-		"sub_title":    st,
-		"bei_an":       ba,
-		"series_say":   ss,
-		"archives_say": as,
-	})
+	before := cache.AuditBlogger(cache.Ei.Blogger)
+	blogger := &model.Blogger{
+		BlogName:    bn,
+		BTitle:      bt,
+		SubTitle:    st,
+		BeiAn:       ba,
+		SeriesSay:   ss,
+		ArchivesSay: as,
+	}
+	err := cache.Ei.EditBlogger(c.Request.Context(), blogger)
 	if err != nil {
-		logrus.Error("handleAPIBlogger.UpdateBlogger: ", err)
+		logrus.Error("handleAPIBlogger.EditBlogger: ", err)
 		responseNotice(c, NoticeNotice, err.Error(), "")
 		return
 	}
-	cache.Ei.Blogger.BlogName = bn
-	cache.Ei.Blogger.BTitle = bt
-	cache.Ei.Blogger.BeiAn = ba
-	cache.Ei.Blogger.SubTitle = st
-	cache.Ei.Blogger.SeriesSay = ss
-	cache.Ei.Blogger.ArchivesSay = as
-	cache.PagesCh <- cache.PageSeries
-	cache.PagesCh <- cache.PageArchive
+	audit(c, cache.AuditBloggerUpdate, "blogger", before, cache.AuditBlogger(blogger))
 	responseNotice(c, NoticeSuccess, "更新成功", "")
 }
 
@@ -129,17 +361,22 @@ func handleAPIAccount(c *gin.Context) {
 		return
 	}
 
-	err := cache.Ei.UpdateAccount(context.Background(), cache.Ei.Account.Username,
-		map[string]interface{}{
-			"email":   e,
-			"phone_n": pn,
-			"address": ad,
-		})
+	fields := map[string]interface{}{
+		"email":   e,
+		"phone_n": pn,
+		"address": ad,
+	}
+	err := cache.Ei.UpdateAccount(c.Request.Context(), cache.Ei.Account.Username, fields)
 	if err != nil {
 		logrus.Error("handleAPIAccount.UpdateAccount: ", err)
 		responseNotice(c, NoticeNotice, err.Error(), "")
 		return
 	}
+	audit(c, cache.AuditAccountUpdate, "account:"+cache.Ei.Account.Username, map[string]interface{}{
+		"email":   cache.Ei.Account.Email,
+		"phone_n": cache.Ei.Account.PhoneN,
+		"address": cache.Ei.Account.Address,
+	}, fields)
 	cache.Ei.Account.Email = e
 	cache.Ei.Account.PhoneN = pn
 	cache.Ei.Account.Address = ad
@@ -159,13 +396,18 @@ func handleAPIPassword(c *gin.Context) {
 		responseNotice(c, NoticeNotice, "密码格式错误", "")
 		return
 	}
-	if cache.Ei.Account.Password != tools.EncryptPasswd(cache.Ei.Account.Username, od) {
+	ok, _ := tools.VerifyPassword(cache.Ei.Account.Username, od, cache.Ei.Account.Password)
+	if !ok {
 		responseNotice(c, NoticeNotice, "原始密码不正确", "")
 		return
 	}
-	newPwd := tools.EncryptPasswd(cache.Ei.Account.Username, nw)
-
-	err := cache.Ei.UpdateAccount(context.Background(), cache.Ei.Account.Username,
+	newPwd, err := tools.HashPassword(nw)
+	if err != nil {
+		logrus.Error("handleAPIPassword.HashPassword: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	err = cache.Ei.UpdateAccount(c.Request.Context(), cache.Ei.Account.Username,
 		map[string]interface{}{
 			"password": newPwd,
 		})
@@ -175,6 +417,12 @@ func handleAPIPassword(c *gin.Context) {
 		return
 	}
 	cache.Ei.Account.Password = newPwd
+	audit(c, cache.AuditAccountPassword, "account:"+cache.Ei.Account.Username, nil, nil)
+	// 其它设备需重新登录
+	err = cache.Ei.RevokeSessions(c.Request.Context(), cache.Ei.Account.Username, eiblog.GetSession(c).ID)
+	if err != nil {
+		logrus.Error("handleAPIPassword.RevokeSessions: ", err)
+	}
 	responseNotice(c, NoticeSuccess, "更新成功", "")
 }
 
@@ -186,12 +434,14 @@ func handleDraftDelete(c *gin.Context) {
 			responseNotice(c, NoticeError, "参数错误", "")
 			return
 		}
-		err = cache.Ei.RemoveArticle(context.Background(), id)
+		article, _ := cache.Ei.LoadArticle(c.Request.Context(), id)
+		err = cache.Ei.RemoveArticle(c.Request.Context(), id)
 		if err != nil {
 			logrus.Error("handleDraftDelete.RemoveArticle: ", err)
 			responseNotice(c, NoticeNotice, "删除失败", "")
 			return
 		}
+		audit(c, cache.AuditArticlePurge, fmt.Sprint("article:", id), cache.AuditArticle(article), nil)
 	}
 	responseNotice(c, NoticeSuccess, "删除成功", "")
 }
@@ -205,13 +455,15 @@ func handleAPIPostDelete(c *gin.Context) {
 			responseNotice(c, NoticeNotice, "参数错误", "")
 			return
 		}
-		err = cache.Ei.DelArticle(id)
+		article, _ := cache.Ei.FindArticleByID(id)
+		err = cache.Ei.DelArticle(c.Request.Context(), id)
 		if err != nil {
 			logrus.Error("handleAPIPostDelete.DelArticle: ", err)
 
 			responseNotice(c, NoticeNotice, err.Error(), "")
 			return
 		}
+		audit(c, cache.AuditArticleTrash, fmt.Sprint("article:", id), cache.AuditArticle(article), nil)
 		ids = append(ids, id)
 	}
 	// elasticsearch
@@ -282,30 +534,24 @@ func handleAPIPostCreate(c *gin.Context) {
 	cid, err = strconv.Atoi(c.PostForm("cid"))
 	// 新文章
 	if err != nil || cid < 1 {
-		err = cache.Ei.AddArticle(article)
+		err = cache.Ei.AddArticle(c.Request.Context(), article)
 		if err != nil {
 			logrus.Error("handleAPIPostCreate.AddArticle: ", err)
 			return
 		}
 
 		cid = article.ID
+		action := cache.AuditArticleCreate
+		if !article.IsDraft {
+			action = cache.AuditArticlePublish
+		}
+		// 自动保存不记录
+		if do != "auto" {
+			audit(c, action, fmt.Sprint("article:", cid), nil, cache.AuditArticle(article))
+		}
 
 		if !article.IsDraft {
-			// 异步执行，快
-			go func() {
-				// elastic
-				err := internal.ElasticAddIndex(article)
-				if err != nil {
-					logrus.Error("handleAPIPostCreate.ElasticAddIndex error", err)
-				}
-				// rss
-				internal.PingFunc(cache.Ei.Blogger.BTitle, slug)
-				// disqus
-				err = internal.ThreadCreate(article, cache.Ei.Blogger.BTitle)
-				if err != nil {
-					logrus.Error("handleAPIPostCreate.ThreadCreate error", err)
-				}
-			}()
+			cache.Ei.SyncArticle(article, true)
 		}
 		return
 	}
@@ -320,8 +566,12 @@ func handleAPIPostCreate(c *gin.Context) {
 	if update == "true" || update == "1" {
 		article.UpdatedAt = time.Now()
 	}
+	before := artc
+	if before == nil && do != "auto" {
+		before, _ = cache.Ei.LoadArticle(c.Request.Context(), article.ID)
+	}
 	// 数据库更新
-	err = cache.Ei.UpdateArticle(context.Background(), article.ID, map[string]interface{}{
+	err = cache.Ei.UpdateArticle(c.Request.Context(), article.ID, map[string]interface{}{
 		"title":      article.Title,
 		"content":    article.Content,
 		"serie_id":   article.SerieID,
@@ -334,25 +584,17 @@ func handleAPIPostCreate(c *gin.Context) {
 		logrus.Error("handleAPIPostCreate.UpdateArticle: ", err)
 		return
 	}
+	if do != "auto" {
+		action := cache.AuditArticleUpdate
+		if artc == nil && !article.IsDraft {
+			action = cache.AuditArticlePublish
+		}
+		audit(c, action, fmt.Sprint("article:", cid), cache.AuditArticle(before),
+			cache.AuditArticle(article))
+	}
 	if !article.IsDraft {
 		cache.Ei.RepArticle(artc, article)
-		// 异步执行，快
-		go func() {
-			// elastic
-			err := internal.ElasticAddIndex(article)
-			if err != nil {
-				logrus.Error("handleAPIPostCreate.RepArticle.ElasticAddIndex error", err)
-			}
-			// rss
-			internal.PingFunc(cache.Ei.Blogger.BTitle, slug)
-			// disqus
-			if artc == nil {
-				err := internal.ThreadCreate(article, cache.Ei.Blogger.BTitle)
-				if err != nil {
-					logrus.Error("handleAPIPostCreate.RepArticle.ThreadCreate error", err)
-				}
-			}
-		}()
+		cache.Ei.SyncArticle(article, artc == nil)
 	}
 }
 
@@ -364,11 +606,21 @@ func handleAPISerieDelete(c *gin.Context) {
 			responseNotice(c, NoticeNotice, err.Error(), "")
 			return
 		}
-		err = cache.Ei.DelSerie(id)
+		var before *model.Serie
+		for _, serie := range cache.Ei.Series {
+			if serie.ID == id {
+				before = serie
+				break
+			}
+		}
+		err = cache.Ei.DelSerie(c.Request.Context(), id)
 		if err != nil {
 			responseNotice(c, NoticeNotice, err.Error(), "")
 			return
 		}
+		if before != nil {
+			audit(c, cache.AuditSerieDelete, fmt.Sprint("serie:", id), cache.AuditSerie(before), nil)
+		}
 	}
 	responseNotice(c, NoticeSuccess, "删除成功", "")
 }
@@ -401,32 +653,28 @@ func handleAPISerieCreate(c *gin.Context) {
 			responseNotice(c, NoticeNotice, "专题不存在", "")
 			return
 		}
-		err = cache.Ei.UpdateSerie(context.Background(), mid, map[string]interface{}{
-			"slug": slug,
-			"name": name,
-			"desc": desc,
-		})
+		before := cache.AuditSerie(serie)
+		err = cache.Ei.EditSerie(c.Request.Context(), serie, slug, name, desc)
 		if err != nil {
-			logrus.Error("handleAPISerieCreate.UpdateSerie: ", err)
+			logrus.Error("handleAPISerieCreate.EditSerie: ", err)
 			responseNotice(c, NoticeNotice, err.Error(), "")
 			return
 		}
-		serie.Slug = slug
-		serie.Name = name
-		serie.Desc = desc
-		cache.PagesCh <- cache.PageSeries
+		audit(c, cache.AuditSerieUpdate, fmt.Sprint("serie:", mid), before, cache.AuditSerie(serie))
 	} else {
-		err = cache.Ei.AddSerie(&model.Serie{
+		serie := &model.Serie{
 			Slug:      slug,
 			Name:      name,
 			Desc:      desc,
 			CreatedAt: time.Now(),
-		})
+		}
+		err = cache.Ei.AddSerie(c.Request.Context(), serie)
 		if err != nil {
 			logrus.Error("handleAPISerieCreate.InsertSerie: ", err)
 			responseNotice(c, NoticeNotice, err.Error(), "")
 			return
 		}
+		audit(c, cache.AuditSerieCreate, fmt.Sprint("serie:", serie.ID), nil, cache.AuditSerie(serie))
 	}
 	responseNotice(c, NoticeSuccess, "操作成功", "")
 }
@@ -439,11 +687,13 @@ func handleAPITrashDelete(c *gin.Context) {
 			responseNotice(c, NoticeNotice, "参数错误", "")
 			return
 		}
-		err = cache.Ei.RemoveArticle(context.Background(), id)
+		article, _ := cache.Ei.LoadArticle(c.Request.Context(), id)
+		err = cache.Ei.RemoveArticle(c.Request.Context(), id)
 		if err != nil {
 			responseNotice(c, NoticeNotice, err.Error(), "")
 			return
 		}
+		audit(c, cache.AuditArticlePurge, fmt.Sprint("article:", id), cache.AuditArticle(article), nil)
 	}
 	responseNotice(c, NoticeSuccess, "删除成功", "")
 }
@@ -457,7 +707,7 @@ func handleAPITrashRecover(c *gin.Context) {
 			return
 
 		}
-		err = cache.Ei.UpdateArticle(context.Background(), id, map[string]interface{}{
+		err = cache.Ei.UpdateArticle(c.Request.Context(), id, map[string]interface{}{
 			"deleted_at": time.Time{},
 			"is_draft":   true,
 		})
@@ -465,36 +715,57 @@ func handleAPITrashRecover(c *gin.Context) {
 			responseNotice(c, NoticeNotice, err.Error(), "")
 			return
 		}
+		article, err := cache.Ei.LoadArticle(c.Request.Context(), id)
+		if err == nil {
+			cache.Ei.FireEvent(cache.EventArticleRestored, cache.EventArticle(article))
+		}
+		audit(c, cache.AuditArticleRestore, fmt.Sprint("article:", id), nil, cache.AuditArticle(article))
 	}
 	responseNotice(c, NoticeSuccess, "恢复成功", "")
 }
 
 // handleAPIUpload 上传文件
 func handleAPIUpload(c *gin.Context) {
+	var (
+		code int
+		data any
+	)
 	conf := config.Conf.EiBlogApp.StaticFile
 	switch conf.Type {
 	case "qiniu":
-		c.JSON(handleAPIQiniuUpload(c))
+		code, data = handleAPIQiniuUpload(c)
 	case "file":
-		c.JSON(handleAPIFileUpload(c))
+		code, data = handleAPIFileUpload(c)
 	default:
 		c.JSON(http.StatusBadRequest, errors.ErrUnsupported)
+		return
 	}
-	return
+	if h, ok := data.(gin.H); ok && code == http.StatusOK {
+		audit(c, cache.AuditFileUpload, fmt.Sprint("file:", h["title"]), nil, h["url"])
+	}
+	c.JSON(code, data)
 }
 
 // handleAPIDelete 删除文件
 func handleAPIDelete(c *gin.Context) {
+	var (
+		code int
+		data any
+	)
 	conf := config.Conf.EiBlogApp.StaticFile
 	switch conf.Type {
 	case "qiniu":
-		c.JSON(handleAPIQiniuDelete(c))
+		code, data = handleAPIQiniuDelete(c)
 	case "file":
-		c.JSON(handleAPIFileDelete(c))
+		code, data = handleAPIFileDelete(c)
 	default:
 		c.JSON(http.StatusBadRequest, errors.ErrUnsupported)
+		return
+	}
+	if code == http.StatusOK {
+		audit(c, cache.AuditFileDelete, "file:"+c.PostForm("title"), nil, nil)
 	}
-	return
+	c.JSON(code, data)
 }
 
 // handleAPIFileUpload 上传文件
@@ -625,6 +896,119 @@ func handleAPIQiniuDelete(c *gin.Context) (int, any) {
 	return http.StatusOK, "删掉了吗？鬼知道。。。"
 }
 
+// handleAPIWebhookCreate 添加webhook，如果有提交 mid 即更新webhook
+func handleAPIWebhookCreate(c *gin.Context) {
+	name := c.PostForm("name")
+	rawurl := c.PostForm("url")
+	secret := c.PostForm("secret")
+	events := c.PostFormArray("events[]")
+	active := c.PostForm("active") == "on"
+	u, err := url.Parse(rawurl)
+	if name == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		len(events) == 0 {
+		responseNotice(c, NoticeNotice, "参数错误", "")
+		return
+	}
+	hook := &model.Webhook{
+		Name:   name,
+		URL:    rawurl,
+		Secret: secret,
+		Events: events,
+		Active: active,
+	}
+	mid, err := strconv.Atoi(c.PostForm("mid"))
+	if err == nil && mid > 0 {
+		old := cache.Ei.FindWebhookByID(mid)
+		if old == nil {
+			responseNotice(c, NoticeNotice, "Webhook不存在", "")
+			return
+		}
+		hook.ID = old.ID
+		hook.CreatedAt = old.CreatedAt
+		before := auditWebhook(old)
+		err = cache.Ei.RepWebhook(c.Request.Context(), hook)
+		if err != nil {
+			logrus.Error("handleAPIWebhookCreate.RepWebhook: ", err)
+			responseNotice(c, NoticeNotice, err.Error(), "")
+			return
+		}
+		audit(c, cache.AuditWebhookUpdate, fmt.Sprint("webhook:", hook.ID),
+			before, auditWebhook(hook))
+	} else {
+		hook.CreatedAt = time.Now()
+		err = cache.Ei.AddWebhook(c.Request.Context(), hook)
+		if err != nil {
+			logrus.Error("handleAPIWebhookCreate.AddWebhook: ", err)
+			responseNotice(c, NoticeNotice, err.Error(), "")
+			return
+		}
+		audit(c, cache.AuditWebhookCreate, fmt.Sprint("webhook:", hook.ID), nil, auditWebhook(hook))
+	}
+	responseNotice(c, NoticeSuccess, "操作成功", "")
+}
+
+// handleAPIWebhookDelete 删除webhook
+func handleAPIWebhookDelete(c *gin.Context) {
+	for _, v := range c.PostFormArray("mid[]") {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			responseNotice(c, NoticeNotice, "参数错误", "")
+			return
+		}
+		old := cache.Ei.FindWebhookByID(id)
+		err = cache.Ei.DelWebhook(c.Request.Context(), id)
+		if err != nil {
+			logrus.Error("handleAPIWebhookDelete.DelWebhook: ", err)
+			responseNotice(c, NoticeNotice, err.Error(), "")
+			return
+		}
+		audit(c, cache.AuditWebhookDelete, fmt.Sprint("webhook:", id), auditWebhook(old), nil)
+	}
+	responseNotice(c, NoticeSuccess, "删除成功", "")
+}
+
+// handleAPISubscriberDelete 删除订阅者
+func handleAPISubscriberDelete(c *gin.Context) {
+	for _, email := range c.PostFormArray("mid[]") {
+		err := cache.Ei.RemoveSubscriber(c.Request.Context(), email)
+		if err != nil {
+			logrus.Error("handleAPISubscriberDelete.RemoveSubscriber: ", err)
+			responseNotice(c, NoticeNotice, err.Error(), "")
+			return
+		}
+		audit(c, cache.AuditSubscriberDelete, "subscriber:"+email, nil, nil)
+	}
+	responseNotice(c, NoticeSuccess, "删除成功", "")
+}
+
+// handleAPISubscriberExport 导出订阅者为csv
+func handleAPISubscriberExport(c *gin.Context) {
+	subs, err := cache.Ei.LoadAllSubscriber(c.Request.Context())
+	if err != nil {
+		logrus.Error("handleAPISubscriberExport.LoadAllSubscriber: ", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	name := fmt.Sprintf("subscribers-%s.csv", time.Now().In(tools.TimeLocation).Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+name)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"email", "status", "ip", "created_at", "confirmed_at"})
+	for _, sub := range subs {
+		confirmed := ""
+		if !sub.ConfirmedAt.IsZero() {
+			confirmed = sub.ConfirmedAt.Format(time.RFC3339)
+		}
+		w.Write([]string{sub.Email, sub.Status, sub.IP,
+			sub.CreatedAt.Format(time.RFC3339), confirmed})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		logrus.Error("handleAPISubscriberExport.Flush: ", err)
+	}
+}
+
 // parseLocationDate 解析日期
 func parseLocationDate(date string) time.Time {
 	t, err := time.ParseInLocation("2006-01-02 15:04", date, tools.TimeLocation)
@@ -634,6 +1018,42 @@ func parseLocationDate(date string) time.Time {
 	return time.Now()
 }
 
+// audit 记录后台操作审计日志
+func audit(c *gin.Context, action, target string, before, after interface{}) {
+	cache.Ei.Audit(c.Request.Context(), &model.AuditLog{
+		Actor:  eiblog.GetUsername(c),
+		IP:     c.ClientIP(),
+		Action: action,
+		Target: target,
+		Before: cache.AuditSummary(before),
+		After:  cache.AuditSummary(after),
+	})
+}
+
+// auditLogin 记录登录审计日志, 未登录时以尝试的用户名为操作者
+func auditLogin(ctx context.Context, user, ip, action, detail string) {
+	cache.Ei.Audit(ctx, &model.AuditLog{
+		Actor:  user,
+		IP:     ip,
+		Action: action,
+		Target: "account:" + user,
+		After:  cache.AuditSummary(detail),
+	})
+}
+
+// auditWebhook webhook审计摘要, 不包含密钥
+func auditWebhook(hook *model.Webhook) map[string]interface{} {
+	if hook == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"name":   hook.Name,
+		"url":    hook.URL,
+		"events": hook.Events,
+		"active": hook.Active,
+	}
+}
+
 func responseNotice(c *gin.Context, typ, content, hl string) {
 	if hl != "" {
 		c.SetCookie("notice_highlight", hl, 86400, "/", "", true, false)
@@ -0,0 +1,97 @@
+// Package admin provides ...
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lastAudit 对象最近一条指定操作的审计日志
+func lastAudit(t *testing.T, action, target string) *model.AuditLog {
+	t.Helper()
+	logs, _, err := cache.Ei.LoadAuditLogList(context.Background(), store.SearchAuditLogs{
+		Page:  1,
+		Limit: 1,
+		Fields: map[string]interface{}{
+			store.SearchAuditAction: action,
+			store.SearchAuditTarget: target,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) == 0 || logs[0].Target != target {
+		t.Fatalf("no %s audit log for %s", action, target)
+	}
+	return logs[0]
+}
+
+func TestAuditTrashRestore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	// 已登录的管理员
+	e.Use(func(c *gin.Context) {
+		c.Set("session", &model.Session{Username: cache.Ei.Account.Username})
+	})
+	e.POST("/post-delete", handleAPIPostDelete)
+	e.POST("/trash-recover", handleAPITrashRecover)
+	e.POST("/trash-delete", handleAPITrashDelete)
+	post := func(path, key string, id int) {
+		req := httptest.NewRequest(http.MethodPost, path,
+			strings.NewReader(url.Values{key: {fmt.Sprint(id)}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("POST %s = %d, want %d", path, w.Code, http.StatusFound)
+		}
+	}
+
+	article := &model.Article{
+		Author:  cache.Ei.Account.Username,
+		Title:   "audit",
+		Slug:    "audit-test",
+		Content: "content",
+	}
+	err := cache.Ei.AddArticle(context.Background(), article)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := fmt.Sprint("article:", article.ID)
+
+	// 移入回收箱, 记录删除前的摘要
+	post("/post-delete", "cid[]", article.ID)
+	log := lastAudit(t, cache.AuditArticleTrash, target)
+	if log.Actor != cache.Ei.Account.Username || log.IP != "10.0.0.1" {
+		t.Errorf("trash actor = %s@%s, want %s@10.0.0.1", log.Actor, log.IP, cache.Ei.Account.Username)
+	}
+	if !strings.Contains(log.Before, `"slug":"audit-test"`) || log.After != "" {
+		t.Errorf("trash before = %q, after = %q", log.Before, log.After)
+	}
+
+	// 恢复为草稿, 记录恢复后的摘要
+	post("/trash-recover", "mid[]", article.ID)
+	log = lastAudit(t, cache.AuditArticleRestore, target)
+	if log.Before != "" || !strings.Contains(log.After, `"is_draft":true`) {
+		t.Errorf("restore before = %q, after = %q", log.Before, log.After)
+	}
+
+	// 物理删除
+	post("/trash-delete", "mid[]", article.ID)
+	log = lastAudit(t, cache.AuditArticlePurge, target)
+	if !strings.Contains(log.Before, `"title":"audit"`) {
+		t.Errorf("purge before = %q", log.Before)
+	}
+}
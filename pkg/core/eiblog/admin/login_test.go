@@ -0,0 +1,171 @@
+// Package admin provides ...
+package admin
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/core/eiblog"
+	"github.com/eiblog/eiblog/pkg/mid"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/gin-gonic/gin"
+)
+
+// alertMails 记录新设备登录提醒的 MailTransport, 忽略后台任务等其它邮件
+type alertMails chan string
+
+// Send 记录提醒邮件的主题
+func (m alertMails) Send(from string, to []string, msg []byte) error {
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		return err
+	}
+	if strings.Contains(subject, "新设备") {
+		m <- subject
+	}
+	return nil
+}
+
+func TestNewDeviceAlert(t *testing.T) {
+	oldSMTP, oldMail, oldEmail := config.Conf.EiBlogApp.SMTP, cache.Ei.Mail, cache.Ei.Account.Email
+	config.Conf.EiBlogApp.SMTP = config.SMTP{
+		Enable: true,
+		Host:   "smtp.example.com",
+		Port:   25,
+		From:   "EiBlog <noreply@example.com>",
+	}
+	mails := make(alertMails, 16)
+	cache.Ei.Mail = mails
+	cache.Ei.Account.Email = "admin@example.com"
+	t.Cleanup(func() {
+		config.Conf.EiBlogApp.SMTP, cache.Ei.Mail = oldSMTP, oldMail
+		cache.Ei.Account.Email = oldEmail
+	})
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(mid.SessionMiddleware(mid.SessionOpts{Secret: []byte("0123456789abcdef0123456789abcdef")}))
+	e.POST("/login", func(c *gin.Context) { recordLogin(c, cache.Ei.Account.Username) })
+	login := func(ip, ua string) bool {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("User-Agent", ua)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		history := cache.Ei.LoginHistory(context.Background(), cache.Ei.Account.Username, 1)
+		if len(history) == 0 || history[0].IP != ip || history[0].UA != ua {
+			t.Fatalf("LoginHistory() = %v, want login from %s", history, ip)
+		}
+		return history[0].NewDevice
+	}
+
+	// 首次登录作为已知设备
+	login("10.1.0.1", "ua-a")
+	for len(mails) > 0 {
+		<-mails
+	}
+	tests := []struct {
+		name      string
+		ip, ua    string
+		newDevice bool
+	}{
+		{"known device", "10.1.0.1", "ua-a", false},
+		{"unseen ua", "10.1.0.1", "ua-b", true},
+		{"unseen ip", "10.1.0.2", "ua-a", true},
+		{"seen again", "10.1.0.2", "ua-a", false},
+	}
+	alerts := 0
+	for _, tt := range tests {
+		if got := login(tt.ip, tt.ua); got != tt.newDevice {
+			t.Errorf("%s: new device = %v, want %v", tt.name, got, tt.newDevice)
+		}
+		if tt.newDevice {
+			alerts++
+		}
+	}
+	// 每次新设备登录发送一封提醒
+	for i := 0; i < alerts; i++ {
+		select {
+		case <-mails:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d alert mails, want %d", i, alerts)
+		}
+	}
+	select {
+	case subject := <-mails:
+		t.Errorf("unexpected mail %q", subject)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 未读提醒, 标记已读后清空
+	if got := len(cache.Ei.LoginAlerts(context.Background(), 10)); got < alerts {
+		t.Errorf("LoginAlerts() = %d, want at least %d", got, alerts)
+	}
+	if err := cache.Ei.ReadLoginAlerts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(cache.Ei.LoginAlerts(context.Background(), 10)); got != 0 {
+		t.Errorf("LoginAlerts() after read = %d, want 0", got)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(mid.SessionMiddleware(mid.SessionOpts{Secret: []byte("0123456789abcdef0123456789abcdef")}))
+	e.POST("/login", func(c *gin.Context) { eiblog.SetLogin(c, cache.Ei.Account.Username) })
+	e.POST("/logout", eiblog.SetLogout)
+	b := &browser{e: e, cookies: make(map[string]*http.Cookie)}
+
+	b.post("/login", nil)
+	sessions, err := cache.Ei.ListSessions(context.Background(), cache.Ei.Account.Username)
+	if err != nil || len(sessions) == 0 {
+		t.Fatalf("ListSessions() = %v, %v, want login session", sessions, err)
+	}
+	before := len(sessions)
+	b.post("/logout", nil)
+
+	// 注销服务端会话并记录登出时间
+	sessions, _ = cache.Ei.ListSessions(context.Background(), cache.Ei.Account.Username)
+	if len(sessions) != before-1 {
+		t.Errorf("sessions after logout = %d, want %d", len(sessions), before-1)
+	}
+	if time.Since(cache.Ei.Account.LogoutAt) > time.Minute {
+		t.Errorf("LogoutAt = %s, want now", cache.Ei.Account.LogoutAt)
+	}
+}
+
+func TestTwoFactorReplay(t *testing.T) {
+	account := cache.Ei.Account
+	oldSecret, oldStep := account.TOTPSecret, account.TOTPStep
+	account.TOTPSecret, account.TOTPStep = tools.GenerateTOTPSecret(), 0
+	t.Cleanup(func() { account.TOTPSecret, account.TOTPStep = oldSecret, oldStep })
+
+	code, err := tools.TOTPCode(account.TOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyTwoFactor(context.Background(), code) {
+		t.Fatal("verifyTwoFactor() rejected a valid code")
+	}
+	// 记录已使用的时间步, 同一验证码不能再次使用
+	if account.TOTPStep == 0 {
+		t.Error("verifyTwoFactor() did not record the used step")
+	}
+	if verifyTwoFactor(context.Background(), code) {
+		t.Error("verifyTwoFactor() accepted the same code twice")
+	}
+}
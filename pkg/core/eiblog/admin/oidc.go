@@ -0,0 +1,89 @@
+// Package admin provides ...
+package admin
+
+import (
+	"net/http"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/core/eiblog"
+	"github.com/eiblog/eiblog/pkg/internal"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// oidcProvider 单点登录身份提供方, 未开启时为nil
+var oidcProvider *internal.OIDCProvider
+
+// registerOIDC 开启单点登录时注册路由
+func registerOIDC(e *gin.Engine) {
+	conf := config.Conf.EiBlogApp.OIDC
+	if !conf.Enable {
+		return
+	}
+	redirectURL := conf.RedirectURL
+	if redirectURL == "" {
+		redirectURL = config.Conf.EiBlogApp.SiteURL("", "/admin/oidc/callback")
+	}
+	oidcProvider = internal.NewOIDCProvider(conf, redirectURL)
+
+	e.GET("/admin/oidc/login", handleOIDCLogin)
+	e.GET("/admin/oidc/callback", handleOIDCCallback)
+}
+
+// handleOIDCLogin 跳转到身份提供方
+func handleOIDCLogin(c *gin.Context) {
+	state, nonce, verifier := internal.OIDCRandom(), internal.OIDCRandom(), internal.OIDCRandom()
+	uri, err := oidcProvider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		logrus.Error("handleOIDCLogin.AuthCodeURL: ", err)
+		c.Redirect(http.StatusFound, "/admin/login?error=sso")
+		return
+	}
+	eiblog.SetPendingOIDC(c, state, nonce, verifier)
+	c.Redirect(http.StatusFound, uri)
+}
+
+// handleOIDCCallback 身份提供方回调, 校验身份后创建会话
+func handleOIDCCallback(c *gin.Context) {
+	ip := c.ClientIP()
+	state, nonce, verifier := eiblog.PopPendingOIDC(c)
+	if state == "" || c.Query("state") != state {
+		logrus.Warnf("单点登录state不匹配 %s", ip)
+		c.Redirect(http.StatusFound, "/admin/login?error=sso")
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		logrus.Warnf("单点登录失败 %s, %s", e, ip)
+		c.Redirect(http.StatusFound, "/admin/login?error=sso")
+		return
+	}
+	claims, err := oidcProvider.Exchange(c.Request.Context(), c.Query("code"), verifier, nonce)
+	if err != nil {
+		logrus.Error("handleOIDCCallback.Exchange: ", err)
+		c.Redirect(http.StatusFound, "/admin/login?error=sso")
+		return
+	}
+	identity := claims.Subject
+	user, ok := oidcProvider.Conf.Identities[identity]
+	if !ok && claims.EmailVerified && claims.Email != "" {
+		identity = claims.Email
+		user, ok = oidcProvider.Conf.Identities[identity]
+	}
+	if !ok || user != cache.Ei.Account.Username {
+		logrus.Warnf("单点登录身份未授权 %s, %s, %s", claims.Subject, claims.Email, ip)
+		auditLogin(c.Request.Context(), claims.Email, ip, cache.AuditLoginFailed, "oidc: "+claims.Subject)
+		c.Redirect(http.StatusFound, "/admin/login?error=sso")
+		return
+	}
+	err = eiblog.SetLogin(c, user)
+	if err != nil {
+		logrus.Error("handleOIDCCallback.SetLogin: ", err)
+		c.Redirect(http.StatusFound, "/admin/login")
+		return
+	}
+	auditLogin(c.Request.Context(), user, ip, cache.AuditLogin, "oidc: "+identity)
+	recordLogin(c, user)
+	c.Redirect(http.StatusFound, "/admin/profile")
+}
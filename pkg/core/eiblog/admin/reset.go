@@ -0,0 +1,93 @@
+// Package admin provides ...
+package admin
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// handleAcctForgot 发送重置密码邮件, 不论用户名是否正确均返回相同结果
+func handleAcctForgot(c *gin.Context) {
+	user := c.PostForm("user")
+	ip, ua := c.ClientIP(), c.Request.UserAgent()
+	if !cache.Ei.PasswordResetEnabled() {
+		c.Redirect(http.StatusFound, "/admin/reset?error=disabled")
+		return
+	}
+	if user == cache.Ei.Account.Username {
+		err := cache.Ei.SendPasswordReset(ip, ua)
+		if err == cache.ErrResetThrottled {
+			c.Redirect(http.StatusFound, "/admin/reset?error=throttled")
+			return
+		}
+		auditLogin(c.Request.Context(), user, ip, cache.AuditPasswordReset, "request")
+	} else {
+		logrus.Warnf("重置密码用户名错误 %s, %s", user, ip)
+	}
+	c.Redirect(http.StatusFound, "/admin/reset?sent=true")
+}
+
+// handleAcctReset 通过邮件链接重置密码, 并注销所有会话
+func handleAcctReset(c *gin.Context) {
+	token := c.PostForm("token")
+	nw := c.PostForm("new")
+	cf := c.PostForm("confirm")
+	code := c.PostForm("code") // 二次验证
+	ip, ua := c.ClientIP(), c.Request.UserAgent()
+	back := func(e string) {
+		c.Redirect(http.StatusFound, "/admin/reset?token="+url.QueryEscape(token)+"&error="+e)
+	}
+	user, err := cache.Ei.VerifyPasswordReset(token)
+	if err != nil {
+		logrus.Warnf("重置密码链接无效 %s, %s", ip, err)
+		c.Redirect(http.StatusFound, "/admin/reset?error=token")
+		return
+	}
+	if wait := cache.Ei.LoginLocked(ip, user); wait > 0 {
+		c.Redirect(http.StatusFound, "/admin/login?wait="+strconv.Itoa(int(wait.Minutes())+1))
+		return
+	}
+	if nw != cf {
+		back("confirm")
+		return
+	}
+	if !tools.ValidatePassword(nw) {
+		back("format")
+		return
+	}
+	if cache.Ei.Account.TOTPSecret != "" && !verifyTwoFactor(c.Request.Context(), code) {
+		logrus.Warnf("重置密码两步验证失败 %s, %s", user, ip)
+		cache.Ei.LoginFailed(c.Request.Context(), ip, user, ua, "reset")
+		back("totp")
+		return
+	}
+	newPwd, err := tools.HashPassword(nw)
+	if err != nil {
+		logrus.Error("handleAcctReset.HashPassword: ", err)
+		back("internal")
+		return
+	}
+	err = cache.Ei.UpdateAccount(c.Request.Context(), user, map[string]interface{}{
+		"password": newPwd,
+	})
+	if err != nil {
+		logrus.Error("handleAcctReset.UpdateAccount: ", err)
+		back("internal")
+		return
+	}
+	// 密码摘要变化后重置链接失效
+	cache.Ei.Account.Password = newPwd
+	err = cache.Ei.RevokeSessions(c.Request.Context(), user, "")
+	if err != nil {
+		logrus.Error("handleAcctReset.RevokeSessions: ", err)
+	}
+	auditLogin(c.Request.Context(), user, ip, cache.AuditPasswordReset, "done")
+	c.Redirect(http.StatusFound, "/admin/login?reset=true")
+}
@@ -0,0 +1,335 @@
+// Package admin provides ...
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/core/eiblog"
+	"github.com/eiblog/eiblog/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/sirupsen/logrus"
+)
+
+// webAuthn 通行密钥依赖方, 配置错误时为nil
+var webAuthn *webauthn.WebAuthn
+
+// registerWebAuthn 初始化通行密钥并注册登录路由
+func registerWebAuthn(e *gin.Engine) {
+	conf := config.Conf.EiBlogApp.WebAuthn
+	rpid := conf.RPID
+	if rpid == "" {
+		rpid = config.Conf.EiBlogApp.Host
+	}
+	if host, _, err := net.SplitHostPort(rpid); err == nil {
+		rpid = host
+	}
+	origins := conf.Origins
+	if len(origins) == 0 {
+		origins = []string{config.Conf.EiBlogApp.SiteURL("", "")}
+	}
+	name := cache.Ei.Blogger.BlogName
+	if name == "" {
+		name = rpid
+	}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          rpid,
+		RPDisplayName: name,
+		RPOrigins:     origins,
+	})
+	if err != nil {
+		logrus.Error("registerWebAuthn.New: ", err)
+		return
+	}
+	webAuthn = w
+
+	e.POST("/admin/webauthn/login-begin", handleWebAuthnLoginBegin)
+	e.POST("/admin/webauthn/login-finish", handleWebAuthnLoginFinish)
+}
+
+// passkeyUser 通行密钥账户, 实现webauthn.User
+type passkeyUser struct {
+	name  string
+	creds []*model.Credential
+}
+
+// loadPasskeyUser 读取账户及其通行密钥
+func loadPasskeyUser(ctx context.Context, name string) (*passkeyUser, error) {
+	creds, err := cache.Ei.LoadCredentials(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &passkeyUser{name: name, creds: creds}, nil
+}
+
+func (u *passkeyUser) WebAuthnID() []byte          { return []byte(u.name) }
+func (u *passkeyUser) WebAuthnName() string        { return u.name }
+func (u *passkeyUser) WebAuthnDisplayName() string { return u.name }
+func (u *passkeyUser) WebAuthnIcon() string        { return "" }
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, 0, len(u.creds))
+	for _, v := range u.creds {
+		var cred webauthn.Credential
+		err := json.Unmarshal([]byte(v.Data), &cred)
+		if err != nil {
+			logrus.Error("passkeyUser.WebAuthnCredentials.Unmarshal: ", err)
+			continue
+		}
+		creds = append(creds, cred)
+	}
+	return creds
+}
+
+// find 根据凭证ID查找
+func (u *passkeyUser) find(id string) *model.Credential {
+	for _, v := range u.creds {
+		if v.ID == id {
+			return v
+		}
+	}
+	return nil
+}
+
+// saveWebAuthnSession 保存挑战数据到会话
+func saveWebAuthnSession(c *gin.Context, data *webauthn.SessionData) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	eiblog.SetWebAuthnSession(c, string(b))
+	return nil
+}
+
+// popWebAuthnSession 取出会话中的挑战数据
+func popWebAuthnSession(c *gin.Context) (*webauthn.SessionData, error) {
+	v := eiblog.PopWebAuthnSession(c)
+	if v == "" {
+		return nil, errors.New("webauthn session not found")
+	}
+	data := new(webauthn.SessionData)
+	err := json.Unmarshal([]byte(v), data)
+	return data, err
+}
+
+// handleAPIPasskeyRegisterBegin 开始注册通行密钥
+func handleAPIPasskeyRegisterBegin(c *gin.Context) {
+	if webAuthn == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "通行密钥未启用"})
+		return
+	}
+	user, err := loadPasskeyUser(c.Request.Context(), cache.Ei.Account.Username)
+	if err != nil {
+		logrus.Error("handleAPIPasskeyRegisterBegin.loadPasskeyUser: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// 排除已注册的凭证
+	var exclusions []protocol.CredentialDescriptor
+	for _, cred := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+	options, data, err := webAuthn.BeginRegistration(user,
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired, // 登录不再需要TOTP, 需验证用户
+		}),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err == nil {
+		err = saveWebAuthnSession(c, data)
+	}
+	if err != nil {
+		logrus.Error("handleAPIPasskeyRegisterBegin.BeginRegistration: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// handleAPIPasskeyRegisterFinish 完成注册通行密钥
+func handleAPIPasskeyRegisterFinish(c *gin.Context) {
+	data, err := popWebAuthnSession(c)
+	if err != nil || webAuthn == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "注册已过期, 请重试"})
+		return
+	}
+	user, err := loadPasskeyUser(c.Request.Context(), cache.Ei.Account.Username)
+	if err != nil {
+		logrus.Error("handleAPIPasskeyRegisterFinish.loadPasskeyUser: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cred, err := webAuthn.FinishRegistration(user, *data, c.Request)
+	if err != nil {
+		logrus.Warn("handleAPIPasskeyRegisterFinish.FinishRegistration: ", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通行密钥校验失败"})
+		return
+	}
+	b, err := json.Marshal(cred)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	name := c.Query("name")
+	if name == "" {
+		name = "通行密钥"
+	}
+	credential := &model.Credential{
+		ID:        base64.RawURLEncoding.EncodeToString(cred.ID),
+		Username:  user.name,
+		Name:      name,
+		Data:      string(b),
+		CreatedAt: time.Now(),
+	}
+	err = cache.Ei.InsertCredential(c.Request.Context(), credential)
+	if err != nil {
+		logrus.Error("handleAPIPasskeyRegisterFinish.InsertCredential: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	audit(c, cache.AuditPasskeyCreate, "passkey:"+credential.ID, nil, credential.Name)
+	c.JSON(http.StatusOK, gin.H{"id": credential.ID})
+}
+
+// handleAPIPasskeyRename 重命名通行密钥
+func handleAPIPasskeyRename(c *gin.Context) {
+	id, name := c.PostForm("id"), c.PostForm("name")
+	if name == "" {
+		responseNotice(c, NoticeNotice, "名称不能为空", "")
+		return
+	}
+	user, err := loadPasskeyUser(c.Request.Context(), cache.Ei.Account.Username)
+	if err != nil {
+		logrus.Error("handleAPIPasskeyRename.loadPasskeyUser: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	cred := user.find(id)
+	if cred == nil {
+		responseNotice(c, NoticeNotice, "通行密钥不存在", "")
+		return
+	}
+	err = cache.Ei.UpdateCredential(c.Request.Context(), id, map[string]interface{}{
+		"name": name,
+	})
+	if err != nil {
+		logrus.Error("handleAPIPasskeyRename.UpdateCredential: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	audit(c, cache.AuditPasskeyUpdate, "passkey:"+id, cred.Name, name)
+	responseNotice(c, NoticeSuccess, "通行密钥已重命名", "")
+}
+
+// handleAPIPasskeyDelete 删除通行密钥
+func handleAPIPasskeyDelete(c *gin.Context) {
+	id := c.PostForm("id")
+	user, err := loadPasskeyUser(c.Request.Context(), cache.Ei.Account.Username)
+	if err != nil {
+		logrus.Error("handleAPIPasskeyDelete.loadPasskeyUser: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	cred := user.find(id)
+	if cred == nil {
+		responseNotice(c, NoticeNotice, "通行密钥不存在", "")
+		return
+	}
+	err = cache.Ei.RemoveCredential(c.Request.Context(), id)
+	if err != nil {
+		logrus.Error("handleAPIPasskeyDelete.RemoveCredential: ", err)
+		responseNotice(c, NoticeNotice, err.Error(), "")
+		return
+	}
+	audit(c, cache.AuditPasskeyDelete, "passkey:"+id, cred.Name, nil)
+	responseNotice(c, NoticeSuccess, "通行密钥已删除", "")
+}
+
+// handleWebAuthnLoginBegin 开始通行密钥登录, 需验证用户(指纹, PIN等), 以代替密码及TOTP
+func handleWebAuthnLoginBegin(c *gin.Context) {
+	options, data, err := webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err == nil {
+		err = saveWebAuthnSession(c, data)
+	}
+	if err != nil {
+		logrus.Error("handleWebAuthnLoginBegin.BeginDiscoverableLogin: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// handleWebAuthnLoginFinish 完成通行密钥登录
+func handleWebAuthnLoginFinish(c *gin.Context) {
+	ip, ua := c.ClientIP(), c.Request.UserAgent()
+	username := cache.Ei.Account.Username
+	if wait := cache.Ei.LoginLocked(ip, username); wait > 0 {
+		logrus.Warnf("登录已锁定 %s, %s, %s", username, ip, wait)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "登录已锁定, 请稍后再试"})
+		return
+	}
+	data, err := popWebAuthnSession(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录已过期, 请重试"})
+		return
+	}
+	var user *passkeyUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		if string(userHandle) != username {
+			return nil, errors.New("unknown user handle")
+		}
+		user, err = loadPasskeyUser(c.Request.Context(), username)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	cred, err := webAuthn.FinishDiscoverableLogin(handler, *data, c.Request)
+	if err == nil && cred.Authenticator.CloneWarning {
+		err = errors.New("signature counter regressed, authenticator may be cloned")
+	}
+	if err != nil {
+		logrus.Warnf("通行密钥登录失败 %s, %s", ip, err)
+		cache.Ei.LoginFailed(c.Request.Context(), ip, username, ua, "webauthn")
+		auditLogin(c.Request.Context(), username, ip, cache.AuditLoginFailed, "webauthn")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "通行密钥校验失败"})
+		return
+	}
+	// 更新签名计数器
+	id := base64.RawURLEncoding.EncodeToString(cred.ID)
+	name := id
+	if v := user.find(id); v != nil {
+		name = v.Name
+	}
+	fields := map[string]interface{}{"last_used_at": time.Now()}
+	if b, err := json.Marshal(cred); err == nil {
+		fields["data"] = string(b)
+	}
+	err = cache.Ei.UpdateCredential(c.Request.Context(), id, fields)
+	if err != nil {
+		logrus.Error("handleWebAuthnLoginFinish.UpdateCredential: ", err)
+	}
+	cache.Ei.LoginSucceeded(ip, username)
+	err = eiblog.SetLogin(c, username)
+	if err != nil {
+		logrus.Error("handleWebAuthnLoginFinish.SetLogin: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	auditLogin(c.Request.Context(), username, ip, cache.AuditLogin, "webauthn: "+name)
+	recordLogin(c, username)
+	c.JSON(http.StatusOK, gin.H{"redirect": "/admin/profile"})
+}
@@ -0,0 +1,255 @@
+// Package admin provides ...
+package admin
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/mid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const testOrigin = "https://example.com"
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// 包初始化时按默认配置创建的数据库
+	os.Remove("db.sqlite")
+	os.Exit(code)
+}
+
+// fakeAuthenticator 使用P-256密钥的软件认证器
+type fakeAuthenticator struct {
+	key   *ecdsa.PrivateKey
+	id    []byte
+	count uint32
+}
+
+func newFakeAuthenticator(t *testing.T) *fakeAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &fakeAuthenticator{key: key, id: id}
+}
+
+// authData 认证器数据, uv为是否已验证用户
+func (a *fakeAuthenticator) authData(uv bool, attested []byte) []byte {
+	rpID := sha256.Sum256([]byte("example.com"))
+	flags := byte(protocol.FlagUserPresent)
+	if uv {
+		flags |= byte(protocol.FlagUserVerified)
+	}
+	if attested != nil {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+	a.count++
+	data := append(rpID[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.count)
+	return append(data, attested...)
+}
+
+// clientData 客户端数据
+func clientData(typ string, challenge protocol.URLEncodedBase64) []byte {
+	b, _ := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    testOrigin,
+	})
+	return b
+}
+
+// create 响应注册, 使用none格式的证明
+func (a *fakeAuthenticator) create(t *testing.T, options []byte, uv bool) []byte {
+	var creation protocol.CredentialCreation
+	if err := json.Unmarshal(options, &creation); err != nil {
+		t.Fatal(err)
+	}
+	key, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // aaguid
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(append(attested, a.id...), key...)
+	object, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(uv, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodeCredential(a.id, map[string]string{
+		"clientDataJSON":    b64(clientData("webauthn.create", creation.Response.Challenge)),
+		"attestationObject": b64(object),
+	})
+}
+
+// get 响应登录, userHandle为账户ID
+func (a *fakeAuthenticator) get(t *testing.T, options []byte, uv bool, userHandle string) []byte {
+	var assertion protocol.CredentialAssertion
+	if err := json.Unmarshal(options, &assertion); err != nil {
+		t.Fatal(err)
+	}
+	data := clientData("webauthn.get", assertion.Response.Challenge)
+	authData := a.authData(uv, nil)
+	hash := sha256.Sum256(data)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), hash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodeCredential(a.id, map[string]string{
+		"clientDataJSON":    b64(data),
+		"authenticatorData": b64(authData),
+		"signature":         b64(sig),
+		"userHandle":        b64([]byte(userHandle)),
+	})
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// encodeCredential 浏览器提交的凭证
+func encodeCredential(id []byte, response map[string]string) []byte {
+	b, _ := json.Marshal(map[string]interface{}{
+		"id":       b64(id),
+		"rawId":    b64(id),
+		"type":     "public-key",
+		"response": response,
+	})
+	return b
+}
+
+// browser 保存cookie的客户端
+type browser struct {
+	e       *gin.Engine
+	cookies map[string]*http.Cookie
+}
+
+func (b *browser) post(path string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range b.cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	b.e.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		b.cookies[cookie.Name] = cookie
+	}
+	return w
+}
+
+// newWebAuthnTest 初始化依赖方及路由, 清除已注册的通行密钥
+func newWebAuthnTest(t *testing.T) *browser {
+	ctx := context.Background()
+	creds, _ := cache.Ei.LoadCredentials(ctx, cache.Ei.Account.Username)
+	for _, cred := range creds {
+		cache.Ei.RemoveCredential(ctx, cred.ID)
+	}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          "example.com",
+		RPDisplayName: "EiBlog",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := webAuthn
+	webAuthn = w
+	t.Cleanup(func() { webAuthn = old })
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(mid.SessionMiddleware(mid.SessionOpts{Secret: []byte("0123456789abcdef0123456789abcdef")}))
+	e.POST("/register-begin", handleAPIPasskeyRegisterBegin)
+	e.POST("/register-finish", handleAPIPasskeyRegisterFinish)
+	e.POST("/login-begin", handleWebAuthnLoginBegin)
+	e.POST("/login-finish", handleWebAuthnLoginFinish)
+	return &browser{e: e, cookies: make(map[string]*http.Cookie)}
+}
+
+// register 注册通行密钥, 返回状态码
+func (b *browser) register(t *testing.T, a *fakeAuthenticator, uv bool) int {
+	w := b.post("/register-begin", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("register-begin = %d, %s", w.Code, w.Body.String())
+	}
+	return b.post("/register-finish", a.create(t, w.Body.Bytes(), uv)).Code
+}
+
+// login 通行密钥登录, 返回状态码
+func (b *browser) login(t *testing.T, a *fakeAuthenticator, uv bool) int {
+	w := b.post("/login-begin", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login-begin = %d, %s", w.Code, w.Body.String())
+	}
+	return b.post("/login-finish", a.get(t, w.Body.Bytes(), uv, cache.Ei.Account.Username)).Code
+}
+
+func TestPasskeyRegister(t *testing.T) {
+	b := newWebAuthnTest(t)
+	a := newFakeAuthenticator(t)
+
+	// 未验证用户的认证器不能注册
+	if code := b.register(t, a, false); code != http.StatusBadRequest {
+		t.Errorf("register without UV = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := b.register(t, a, true); code != http.StatusOK {
+		t.Fatalf("register = %d, want %d", code, http.StatusOK)
+	}
+	creds, err := cache.Ei.LoadCredentials(context.Background(), cache.Ei.Account.Username)
+	if err != nil || len(creds) != 1 || creds[0].ID != b64(a.id) {
+		t.Errorf("LoadCredentials() = %v, %v, want credential %s", creds, err, b64(a.id))
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	b := newWebAuthnTest(t)
+	a := newFakeAuthenticator(t)
+	if code := b.register(t, a, true); code != http.StatusOK {
+		t.Fatalf("register = %d, want %d", code, http.StatusOK)
+	}
+
+	// 未验证用户时为单因素, 不能代替密码及TOTP
+	if code := b.login(t, a, false); code != http.StatusUnauthorized {
+		t.Errorf("login without UV = %d, want %d", code, http.StatusUnauthorized)
+	}
+	// 未注册的认证器
+	if code := b.login(t, newFakeAuthenticator(t), true); code != http.StatusUnauthorized {
+		t.Errorf("login with unknown passkey = %d, want %d", code, http.StatusUnauthorized)
+	}
+	// 清除失败计数, httptest的请求来自192.0.2.1
+	cache.Ei.LoginSucceeded("192.0.2.1", cache.Ei.Account.Username)
+	if code := b.login(t, a, true); code != http.StatusOK {
+		t.Errorf("login = %d, want %d", code, http.StatusOK)
+	}
+}
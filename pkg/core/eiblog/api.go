@@ -3,9 +3,16 @@ package eiblog
 
 import (
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/mid"
+	"github.com/eiblog/eiblog/pkg/model"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 // @title APP Demo API
@@ -26,31 +33,139 @@ func AuthFilter(c *gin.Context) {
 	c.Next()
 }
 
-// SetLogin login user
-func SetLogin(c *gin.Context, username string) {
+// SetLogin login user, 创建服务端会话, cookie中只保存会话token
+func SetLogin(c *gin.Context, username string) error {
+	token, err := cache.Ei.CreateSession(c.Request.Context(), username, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		return err
+	}
 	session := sessions.Default(c)
-	session.Set("username", username)
-	session.Save()
+	session.Set("token", token)
+	// 登录后更换csrf token
+	session.Delete(mid.CSRFSessionKey)
+	return session.Save()
 }
 
-// SetLogout logout user
+// SetLogout logout user, 记录登出时间
 func SetLogout(c *gin.Context) {
+	if sess := GetSession(c); sess != nil {
+		now := time.Now()
+		err := cache.Ei.UpdateAccount(c.Request.Context(), sess.Username,
+			map[string]interface{}{"logout_at": now})
+		if err != nil {
+			logrus.Error("eiblog.SetLogout.UpdateAccount: ", err)
+		}
+		cache.Ei.Account.LogoutAt = now
+	}
 	session := sessions.Default(c)
-	session.Delete("username")
+	if token, ok := session.Get("token").(string); ok {
+		err := cache.Ei.RevokeSession(c.Request.Context(), cache.SessionID(token))
+		if err != nil {
+			logrus.Error("eiblog.SetLogout.RevokeSession: ", err)
+		}
+	}
+	session.Delete("token")
 	session.Save()
 }
 
 // IsLogined account logined
 func IsLogined(c *gin.Context) bool {
-	return GetUsername(c) != ""
+	return GetSession(c) != nil
+}
+
+// GetSession 当前登录会话, 未登录返回nil
+func GetSession(c *gin.Context) *model.Session {
+	if v, ok := c.Get("session"); ok {
+		return v.(*model.Session)
+	}
+	session := sessions.Default(c)
+	token, _ := session.Get("token").(string)
+	sess := cache.Ei.CheckSession(c.Request.Context(), token)
+	if sess == nil || sess.Username != cache.Ei.Account.Username {
+		return nil
+	}
+	c.Set("session", sess)
+	return sess
 }
 
 // GetUsername get logined account
 func GetUsername(c *gin.Context) string {
-	session := sessions.Default(c)
-	username := session.Get("username")
-	if username == nil {
+	sess := GetSession(c)
+	if sess == nil {
 		return ""
 	}
-	return username.(string)
+	return sess.Username
+}
+
+// SetPendingTOTP 保存待确认的两步验证密钥, 为空时删除
+func SetPendingTOTP(c *gin.Context, secret string) {
+	session := sessions.Default(c)
+	if secret == "" {
+		session.Delete("totp_pending")
+	} else {
+		session.Set("totp_pending", secret)
+	}
+	session.Save()
+}
+
+// GetPendingTOTP 获取待确认的两步验证密钥
+func GetPendingTOTP(c *gin.Context) string {
+	session := sessions.Default(c)
+	secret, _ := session.Get("totp_pending").(string)
+	return secret
+}
+
+// SetRecoveryCodes 暂存新生成的恢复码, 仅展示一次
+func SetRecoveryCodes(c *gin.Context, codes []string) {
+	session := sessions.Default(c)
+	session.AddFlash(strings.Join(codes, " "), "recovery_codes")
+	session.Save()
+}
+
+// PopRecoveryCodes 取出暂存的恢复码
+func PopRecoveryCodes(c *gin.Context) []string {
+	session := sessions.Default(c)
+	flashes := session.Flashes("recovery_codes")
+	if len(flashes) == 0 {
+		return nil
+	}
+	session.Save()
+	codes, _ := flashes[0].(string)
+	return strings.Fields(codes)
+}
+
+// SetPendingOIDC 保存单点登录的state, nonce及PKCE verifier
+func SetPendingOIDC(c *gin.Context, state, nonce, verifier string) {
+	session := sessions.Default(c)
+	session.Set("oidc_pending", strings.Join([]string{state, nonce, verifier}, " "))
+	session.Save()
+}
+
+// PopPendingOIDC 取出单点登录的state, nonce及PKCE verifier, 只能使用一次
+func PopPendingOIDC(c *gin.Context) (state, nonce, verifier string) {
+	session := sessions.Default(c)
+	v, _ := session.Get("oidc_pending").(string)
+	session.Delete("oidc_pending")
+	session.Save()
+	fields := strings.Fields(v)
+	if len(fields) != 3 {
+		return "", "", ""
+	}
+	return fields[0], fields[1], fields[2]
+}
+
+// SetWebAuthnSession 保存通行密钥仪式的挑战数据
+func SetWebAuthnSession(c *gin.Context, data string) {
+	session := sessions.Default(c)
+	session.Set("webauthn_session", data)
+	session.Save()
+}
+
+// PopWebAuthnSession 取出通行密钥仪式的挑战数据, 只能使用一次
+func PopWebAuthnSession(c *gin.Context) string {
+	session := sessions.Default(c)
+	v, _ := session.Get("webauthn_session").(string)
+	session.Delete("webauthn_session")
+	session.Save()
+	return v
 }
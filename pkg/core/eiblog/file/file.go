@@ -4,18 +4,21 @@ package file
 import (
 	"net/http"
 
+	"github.com/eiblog/eiblog/pkg/core/eiblog"
+
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes register routes
 func RegisterRoutes(e *gin.Engine) {
-	e.GET("/rss.html", handleFeed)
-	e.GET("/feed", handleFeed)
-	e.GET("/opensearch.xml", handleOpensearch)
-	e.GET("/sitemap.xml", handleSitemap)
-	e.GET("/robots.txt", handleRobots)
-	e.GET("/crossdomain.xml", handleCrossDomain)
-	e.GET("/favicon.ico", handleFavicon)
+	files := e.Group("", eiblog.CacheFiles())
+	files.GET("/rss.html", handleFeed)
+	files.GET("/feed", handleFeed)
+	files.GET("/opensearch.xml", handleOpensearch)
+	files.GET("/sitemap.xml", handleSitemap)
+	files.GET("/robots.txt", handleRobots)
+	files.GET("/crossdomain.xml", handleCrossDomain)
+	files.GET("/favicon.ico", handleFavicon)
 }
 
 // handleFeed feed.xml
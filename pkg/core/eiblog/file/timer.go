@@ -4,6 +4,8 @@ package file
 import (
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -14,29 +16,121 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var xmlTmpl *template.Template
+// xmlTmpl xml template cache, 热加载时整体替换
+var xmlTmpl atomic.Pointer[template.Template]
 
-func init() {
-	root := filepath.Join(config.WorkDir, "website", "template", "*.xml")
+// reloadErr 最近一次热加载的错误
+var reloadErr atomic.Pointer[error]
 
-	var err error
-	xmlTmpl, err = template.New("").Funcs(template.FuncMap{
-		"dateformat":  tools.DateFormat,
-		"imgtonormal": tools.ImgToNormal,
-	}).ParseGlob(root)
+var (
+	timerLock sync.Mutex
+	timers    = make(map[string]*time.Timer) // 等待执行的定时任务
+	active    = make(map[string]bool)        // 执行中的定时任务
+	running   sync.WaitGroup                 // 执行中的定时任务
+	stopped   bool
+)
+
+func init() {
+	tmpl, err := parseXMLTmpl()
 	if err != nil {
 		panic(err)
 	}
+	xmlTmpl.Store(tmpl)
+	generateOpensearch()
+	generateRobots()
+	generateCrossdomain()
+	schedule("feed", 0, timerFeed)
+	schedule("sitemap", 0, timerSitemap)
+}
+
+// schedule 延时执行定时任务, Stop后不再执行
+func schedule(name string, d time.Duration, f func()) {
+	timerLock.Lock()
+	defer timerLock.Unlock()
+
+	if stopped {
+		return
+	}
+	timers[name] = time.AfterFunc(d, func() {
+		timerLock.Lock()
+		if stopped {
+			timerLock.Unlock()
+			return
+		}
+		// 任务执行完未重新调度则视为已停止
+		delete(timers, name)
+		active[name] = true
+		running.Add(1)
+		timerLock.Unlock()
+
+		defer func() {
+			timerLock.Lock()
+			delete(active, name)
+			timerLock.Unlock()
+			running.Done()
+		}()
+		f()
+	})
+}
+
+// Workers 定时任务是否存活, 出错未重新调度时为false
+func Workers() map[string]bool {
+	timerLock.Lock()
+	defer timerLock.Unlock()
+
+	workers := make(map[string]bool)
+	for _, name := range []string{"feed", "sitemap"} {
+		workers[name] = timers[name] != nil || active[name]
+	}
+	return workers
+}
+
+// Stop 停止定时任务, 等待正在生成的文件写完
+func Stop() {
+	timerLock.Lock()
+	stopped = true
+	for _, t := range timers {
+		t.Stop()
+	}
+	timerLock.Unlock()
+
+	running.Wait()
+}
+
+// ReloadTemplates 重新解析xml模版并重新生成静态文件, 失败时保留原模版
+func ReloadTemplates() error {
+	tmpl, err := parseXMLTmpl()
+	reloadErr.Store(&err)
+	if err != nil {
+		return err
+	}
+	xmlTmpl.Store(tmpl)
 	generateOpensearch()
 	generateRobots()
 	generateCrossdomain()
-	go timerFeed()
-	go timerSitemap()
+	return nil
+}
+
+// ReloadError 最近一次热加载的错误, 未加载或成功时为nil
+func ReloadError() error {
+	if err := reloadErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// parseXMLTmpl 解析website/template下的xml模版
+func parseXMLTmpl() (*template.Template, error) {
+	root := filepath.Join(config.WorkDir, "website", "template", "*.xml")
+	return template.New("").Funcs(template.FuncMap{
+		"dateformat":  tools.DateFormat,
+		"imgtonormal": tools.ImgToNormal,
+	}).ParseGlob(root)
 }
 
 // timerFeed 定时刷新feed
 func timerFeed() {
-	tpl := xmlTmpl.Lookup("feedTpl.xml")
+	tpl := xmlTmpl.Load().Lookup("feedTpl.xml")
 	if tpl == nil {
 		logrus.Info("file: not found: feedTpl.xml")
 		return
@@ -55,20 +149,23 @@ func timerFeed() {
 	f, err := os.OpenFile("assets/feed.xml", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		logrus.Error("file: timerFeed.OpenFile: ", err)
+		cache.Ei.NotifyJob("timerFeed", err)
 		return
 	}
 	defer f.Close()
 	err = tpl.Execute(f, params)
 	if err != nil {
 		logrus.Error("file: timerFeed.Execute: ", err)
+		cache.Ei.NotifyJob("timerFeed", err)
 		return
 	}
-	time.AfterFunc(time.Hour*4, timerFeed)
+	cache.Ei.NotifyJob("timerFeed", nil)
+	schedule("feed", time.Hour*4, timerFeed)
 }
 
 // timerSitemap 定时刷新sitemap
 func timerSitemap() {
-	tpl := xmlTmpl.Lookup("sitemapTpl.xml")
+	tpl := xmlTmpl.Load().Lookup("sitemapTpl.xml")
 	if tpl == nil {
 		logrus.Info("file: not found: sitemapTpl.xml")
 		return
@@ -81,20 +178,23 @@ func timerSitemap() {
 	f, err := os.OpenFile("assets/sitemap.xml", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		logrus.Error("file: timerSitemap.OpenFile: ", err)
+		cache.Ei.NotifyJob("timerSitemap", err)
 		return
 	}
 	defer f.Close()
 	err = tpl.Execute(f, params)
 	if err != nil {
 		logrus.Error("file: timerSitemap.Execute: ", err)
+		cache.Ei.NotifyJob("timerSitemap", err)
 		return
 	}
-	time.AfterFunc(time.Hour*24, timerSitemap)
+	cache.Ei.NotifyJob("timerSitemap", nil)
+	schedule("sitemap", time.Hour*24, timerSitemap)
 }
 
 // generateOpensearch 生成opensearch.xml
 func generateOpensearch() {
-	tpl := xmlTmpl.Lookup("opensearchTpl.xml")
+	tpl := xmlTmpl.Load().Lookup("opensearchTpl.xml")
 	if tpl == nil {
 		logrus.Info("file: not found: opensearchTpl.xml")
 		return
@@ -119,7 +219,7 @@ func generateOpensearch() {
 
 // generateRobots 生成robots.txt
 func generateRobots() {
-	tpl := xmlTmpl.Lookup("robotsTpl.xml")
+	tpl := xmlTmpl.Load().Lookup("robotsTpl.xml")
 	if tpl == nil {
 		logrus.Info("file: not found: robotsTpl.xml")
 		return
@@ -142,7 +242,7 @@ func generateRobots() {
 
 // generateCrossdomain 生成crossdomain.xml
 func generateCrossdomain() {
-	tpl := xmlTmpl.Lookup("crossdomainTpl.xml")
+	tpl := xmlTmpl.Load().Lookup("crossdomainTpl.xml")
 	if tpl == nil {
 		logrus.Info("file: not found: crossdomainTpl.xml")
 		return
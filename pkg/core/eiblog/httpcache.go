@@ -0,0 +1,63 @@
+// Package eiblog provides ...
+package eiblog
+
+import (
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/mid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 默认Cache-Control, 见 eiblogapp.httpcache
+const (
+	DefaultCachePages  = "public, no-cache"
+	DefaultCacheFiles  = "public, max-age=3600"
+	DefaultCacheStatic = "public, max-age=31536000, immutable"
+)
+
+// CachePages 前台页面的Cache-Control, 配合ETag每次校验
+func CachePages() gin.HandlerFunc {
+	return mid.CacheControl(func(*gin.Context) string {
+		return cacheValue(config.Current().EiBlogApp.HTTPCache.Pages, DefaultCachePages)
+	})
+}
+
+// CacheFiles rss, sitemap等文件的Cache-Control
+func CacheFiles() gin.HandlerFunc {
+	return mid.CacheControl(func(*gin.Context) string {
+		return cacheValue(config.Current().EiBlogApp.HTTPCache.Files, DefaultCacheFiles)
+	})
+}
+
+// CacheStatic /static的Cache-Control, 带版本号(?v=)时长期缓存
+func CacheStatic() gin.HandlerFunc {
+	return mid.CacheControl(func(c *gin.Context) string {
+		conf := config.Current().EiBlogApp.HTTPCache
+		if c.Query("v") != "" {
+			return cacheValue(conf.Static, DefaultCacheStatic)
+		}
+		return cacheValue(conf.Files, DefaultCacheFiles)
+	})
+}
+
+// CompressEncodings 协商的压缩算法, 关闭时返回nil
+func CompressEncodings() []string {
+	encodings := config.Conf.EiBlogApp.HTTPCache.Compress
+	if len(encodings) == 0 {
+		return mid.DefaultCompressEncodings
+	}
+	for _, enc := range encodings {
+		if enc == "off" {
+			return nil
+		}
+	}
+	return encodings
+}
+
+// cacheValue 未配置时使用默认值
+func cacheValue(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
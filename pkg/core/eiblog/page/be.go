@@ -3,27 +3,37 @@ package page
 
 import (
 	"bytes"
-	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	htemplate "html/template"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/eiblog/eiblog/pkg/cache"
 	"github.com/eiblog/eiblog/pkg/cache/store"
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/core/eiblog"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/pkg/mid"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/pkg/trace"
+	"github.com/eiblog/eiblog/tools"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
 )
 
 // baseBEParams 基础参数
-func baseBEParams() gin.H {
+func baseBEParams(c *gin.Context) gin.H {
 	return gin.H{
 		"Author":     cache.Ei.Account.Username,
 		"StaticFile": config.Conf.EiBlogApp.StaticFile,
+		"CSRFToken":  mid.CSRFToken(c),
+		// 新设备登录提醒
+		"LoginAlerts": cache.Ei.LoginAlerts(c.Request.Context(), 5),
 	}
 }
 
@@ -37,16 +47,96 @@ func handleLoginPage(c *gin.Context) {
 		return
 	}
 	params := gin.H{"BTitle": cache.Ei.Blogger.BTitle}
+	// 登录锁定剩余分钟
+	if wait, err := strconv.Atoi(c.Query("wait")); err == nil && wait > 0 {
+		params["Message"] = fmt.Sprintf("登录失败次数过多, 请 %d 分钟后再试", wait)
+	} else if c.Query("error") == "sso" {
+		params["Message"] = "单点登录失败, 请确认账号已授权"
+	} else if c.Query("reset") == "true" {
+		params["Notice"] = "密码已重置, 请使用新密码登录"
+	}
+	if conf := config.Conf.EiBlogApp.OIDC; conf.Enable {
+		params["OIDC"] = conf.Name
+		if conf.Name == "" {
+			params["OIDC"] = "SSO"
+		}
+	}
 	renderHTMLAdminLayout(c, "login.html", params)
 }
 
+// resetMessages 重置密码错误提示
+var resetMessages = map[string]string{
+	"disabled":  "未配置邮件通知, 无法通过邮件重置密码",
+	"throttled": "发送过于频繁, 请稍后再试",
+	"token":     "重置链接无效或已过期, 请重新获取",
+	"confirm":   "两次密码输入不一致",
+	"format":    "密码格式错误",
+	"totp":      "两步验证失败",
+	"internal":  "重置失败, 请稍后再试",
+}
+
+// handleResetPage 重置密码页面, 带有效token时设置新密码, 否则发送重置邮件
+func handleResetPage(c *gin.Context) {
+	if eiblog.IsLogined(c) {
+		c.Redirect(http.StatusFound, "/admin/profile")
+		return
+	}
+	params := gin.H{"BTitle": cache.Ei.Blogger.BTitle}
+	if token := c.Query("token"); token != "" {
+		if _, err := cache.Ei.VerifyPasswordReset(token); err == nil {
+			params["Token"] = token
+			params["TOTP"] = cache.Ei.Account.TOTPSecret != ""
+		} else {
+			params["Message"] = resetMessages["token"]
+		}
+	}
+	if msg, ok := resetMessages[c.Query("error")]; ok {
+		params["Message"] = msg
+	}
+	if c.Query("sent") == "true" {
+		params["Notice"] = "如果用户名正确, 重置链接已发送到账号绑定的邮箱"
+	}
+	renderHTMLAdminLayout(c, "reset.html", params)
+}
+
 // handleAdminProfile 个人配置
 func handleAdminProfile(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 	params["Title"] = "个人配置 | " + cache.Ei.Blogger.BTitle
 	params["Path"] = c.Request.URL.Path
 	params["Console"] = true
 	params["Ei"] = cache.Ei
+	// 登录会话
+	sessions, err := cache.Ei.ListSessions(c.Request.Context(), cache.Ei.Account.Username)
+	if err != nil {
+		logrus.Error("handleAdminProfile.ListSessions: ", err)
+	}
+	params["Sessions"] = sessions
+	params["CurrentSession"] = eiblog.GetSession(c).ID
+	// 登录记录
+	params["LoginHistory"] = cache.Ei.LoginHistory(c.Request.Context(), cache.Ei.Account.Username, 20)
+	// 通行密钥
+	creds, err := cache.Ei.LoadCredentials(c.Request.Context(), cache.Ei.Account.Username)
+	if err != nil {
+		logrus.Error("handleAdminProfile.LoadCredentials: ", err)
+	}
+	params["Credentials"] = creds
+	// 两步验证
+	params["RecoveryCodes"] = eiblog.PopRecoveryCodes(c)
+	if cache.Ei.Account.TOTPSecret == "" {
+		secret := eiblog.GetPendingTOTP(c)
+		if secret == "" {
+			secret = tools.GenerateTOTPSecret()
+			eiblog.SetPendingTOTP(c, secret)
+		}
+		uri := tools.TOTPURI(cache.Ei.Blogger.BTitle, cache.Ei.Account.Username, secret)
+		png, err := qrcode.Encode(uri, qrcode.Medium, 200)
+		if err != nil {
+			logrus.Error("handleAdminProfile.qrcode.Encode: ", err)
+		}
+		params["TOTPSecret"] = secret
+		params["TOTPQRCode"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
 	renderHTMLAdminLayout(c, "admin-profile", params)
 }
 
@@ -58,10 +148,10 @@ type T struct {
 
 // handleAdminPost 写文章页
 func handleAdminPost(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 	id, err := strconv.Atoi(c.Query("cid"))
 	if err == nil && id > 0 {
-		article, _ := cache.Ei.LoadArticle(context.Background(), id)
+		article, _ := cache.Ei.LoadArticle(c.Request.Context(), id)
 		if article != nil {
 			params["Title"] = "编辑文章 | " + cache.Ei.Blogger.BTitle
 			params["Edit"] = article
@@ -96,7 +186,7 @@ func handleAdminPosts(c *gin.Context) {
 	}
 	vals := c.Request.URL.Query()
 
-	params := baseBEParams()
+	params := baseBEParams(c)
 	params["Title"] = "文章管理 | " + cache.Ei.Blogger.BTitle
 	params["Manage"] = true
 	params["Path"] = c.Request.URL.Path
@@ -104,7 +194,7 @@ func handleAdminPosts(c *gin.Context) {
 	params["Serie"] = se
 	params["KW"] = kw
 	var maxi int
-	params["List"], maxi = cache.Ei.PageArticleBE(se, kw, false, false,
+	params["List"], maxi = cache.Ei.PageArticleBE(c.Request.Context(), se, kw, false, false,
 		pg, config.Conf.EiBlogApp.General.PageSize)
 	if pg < maxi {
 		vals.Set("page", fmt.Sprint(pg+1))
@@ -125,7 +215,7 @@ func handleAdminPosts(c *gin.Context) {
 
 // handleAdminSeries 专题列表
 func handleAdminSeries(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 	params["Title"] = "专题管理 | " + cache.Ei.Blogger.BTitle
 	params["Manage"] = true
 	params["Path"] = c.Request.URL.Path
@@ -135,7 +225,7 @@ func handleAdminSeries(c *gin.Context) {
 
 // handleAdminSerie 编辑专题
 func handleAdminSerie(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 
 	id, err := strconv.Atoi(c.Query("mid"))
 	params["Title"] = "新增专题 | " + cache.Ei.Blogger.BTitle
@@ -155,7 +245,7 @@ func handleAdminSerie(c *gin.Context) {
 
 // handleAdminTags 标签列表
 func handleAdminTags(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 	params["Title"] = "标签管理 | " + cache.Ei.Blogger.BTitle
 	params["Manage"] = true
 	params["Path"] = c.Request.URL.Path
@@ -163,24 +253,9 @@ func handleAdminTags(c *gin.Context) {
 	renderHTMLAdminLayout(c, "admin-tags", params)
 }
 
-// handleDraftDelete 编辑页删除草稿
-func handleDraftDelete(c *gin.Context) {
-	id, err := strconv.Atoi(c.Query("cid"))
-	if err != nil || id < 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
-		return
-	}
-	err = cache.Ei.RemoveArticle(context.Background(), id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "删除错误"})
-		return
-	}
-	c.Redirect(http.StatusFound, "/admin/write-post")
-}
-
 // handleAdminDraft 草稿箱页
 func handleAdminDraft(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 
 	params["Title"] = "草稿箱 | " + cache.Ei.Blogger.BTitle
 	params["Manage"] = true
@@ -191,7 +266,7 @@ func handleAdminDraft(c *gin.Context) {
 		Limit:  9999,
 		Fields: map[string]interface{}{store.SearchArticleDraft: true},
 	}
-	params["List"], _, err = cache.Ei.LoadArticleList(context.Background(), search)
+	params["List"], _, err = cache.Ei.LoadArticleList(c.Request.Context(), search)
 	if err != nil {
 		logrus.Error("handleDraft.LoadDraftArticles: ", err)
 		c.Status(http.StatusBadRequest)
@@ -203,7 +278,7 @@ func handleAdminDraft(c *gin.Context) {
 
 // handleAdminTrash 回收箱页
 func handleAdminTrash(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 	params["Title"] = "回收箱 | " + cache.Ei.Blogger.BTitle
 	params["Manage"] = true
 	params["Path"] = c.Request.URL.Path
@@ -213,7 +288,7 @@ func handleAdminTrash(c *gin.Context) {
 		Limit:  9999,
 		Fields: map[string]interface{}{store.SearchArticleTrash: true},
 	}
-	params["List"], _, err = cache.Ei.LoadArticleList(context.Background(), search)
+	params["List"], _, err = cache.Ei.LoadArticleList(c.Request.Context(), search)
 	if err != nil {
 		logrus.Error("handleTrash.LoadArticleList: ", err)
 	}
@@ -222,7 +297,7 @@ func handleAdminTrash(c *gin.Context) {
 
 // handleAdminGeneral 基本设置
 func handleAdminGeneral(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 	params["Title"] = "基本设置 | " + cache.Ei.Blogger.BTitle
 	params["Setting"] = true
 	params["Path"] = c.Request.URL.Path
@@ -231,31 +306,143 @@ func handleAdminGeneral(c *gin.Context) {
 
 // handleAdminDiscussion 阅读设置
 func handleAdminDiscussion(c *gin.Context) {
-	params := baseBEParams()
+	params := baseBEParams(c)
 	params["Title"] = "阅读设置 | " + cache.Ei.Blogger.BTitle
 	params["Setting"] = true
 	params["Path"] = c.Request.URL.Path
 	renderHTMLAdminLayout(c, "admin-discussion", params)
 }
 
+// handleAdminWebhooks webhook列表
+func handleAdminWebhooks(c *gin.Context) {
+	params := baseBEParams(c)
+	params["Title"] = "Webhooks | " + cache.Ei.Blogger.BTitle
+	params["Setting"] = true
+	params["Path"] = c.Request.URL.Path
+	params["List"] = cache.Ei.Webhooks
+	renderHTMLAdminLayout(c, "admin-webhooks", params)
+}
+
+// handleAdminWebhook 编辑webhook及查看推送记录
+func handleAdminWebhook(c *gin.Context) {
+	params := baseBEParams(c)
+
+	id, err := strconv.Atoi(c.Query("mid"))
+	params["Title"] = "新增Webhook | " + cache.Ei.Blogger.BTitle
+	if err == nil && id > 0 {
+		if hook := cache.Ei.FindWebhookByID(id); hook != nil {
+			params["Title"] = "编辑Webhook | " + cache.Ei.Blogger.BTitle
+			params["Edit"] = hook
+			params["Deliveries"], err = cache.Ei.LoadWebhookDeliveries(c.Request.Context(), id, 50)
+			if err != nil {
+				logrus.Error("handleAdminWebhook.LoadWebhookDeliveries: ", err)
+			}
+		}
+	}
+	params["Events"] = cache.Events
+	params["Setting"] = true
+	params["Path"] = c.Request.URL.Path
+	renderHTMLAdminLayout(c, "admin-webhook", params)
+}
+
+// handleAdminSubscribers 邮件订阅者列表
+func handleAdminSubscribers(c *gin.Context) {
+	params := baseBEParams(c)
+	params["Title"] = "订阅者 | " + cache.Ei.Blogger.BTitle
+	params["Manage"] = true
+	params["Path"] = c.Request.URL.Path
+	subs, err := cache.Ei.LoadAllSubscriber(c.Request.Context())
+	if err != nil {
+		logrus.Error("handleAdminSubscribers.LoadAllSubscriber: ", err)
+	}
+	count := make(map[string]int)
+	for _, sub := range subs {
+		count[sub.Status]++
+	}
+	params["List"] = subs
+	params["Active"] = count[model.SubscriberActive]
+	params["Pending"] = count[model.SubscriberPending]
+	params["Unsubscribed"] = count[model.SubscriberUnsubscribed]
+	renderHTMLAdminLayout(c, "admin-subscribers", params)
+}
+
+// handleAdminAudit 审计日志
+func handleAdminAudit(c *gin.Context) {
+	pg, err := strconv.Atoi(c.Query("page"))
+	if err != nil || pg < 1 {
+		pg = 1
+	}
+	vals := c.Request.URL.Query()
+	fields := make(map[string]interface{})
+	for _, k := range []string{store.SearchAuditActor, store.SearchAuditAction,
+		store.SearchAuditTarget} {
+		if v := c.Query(k); v != "" {
+			fields[k] = v
+		}
+	}
+	since, err := time.ParseInLocation("2006-01-02", c.Query("since"), tools.TimeLocation)
+	if err == nil {
+		fields[store.SearchAuditSince] = since
+	}
+	until, err := time.ParseInLocation("2006-01-02", c.Query("until"), tools.TimeLocation)
+	if err == nil {
+		fields[store.SearchAuditUntil] = until.AddDate(0, 0, 1)
+	}
+
+	params := baseBEParams(c)
+	params["Title"] = "审计日志 | " + cache.Ei.Blogger.BTitle
+	params["Setting"] = true
+	params["Path"] = c.Request.URL.Path
+	params["Actions"] = cache.AuditActions
+	params["Actor"] = c.Query(store.SearchAuditActor)
+	params["Action"] = c.Query(store.SearchAuditAction)
+	params["Target"] = c.Query(store.SearchAuditTarget)
+	params["Since"] = c.Query("since")
+	params["Until"] = c.Query("until")
+	params["Filtered"] = len(fields) > 0
+	var maxi int
+	params["List"], maxi = cache.Ei.PageAuditLogs(c.Request.Context(), fields, pg,
+		config.Conf.EiBlogApp.General.PageSize)
+	if pg < maxi {
+		vals.Set("page", fmt.Sprint(pg+1))
+		params["Next"] = vals.Encode()
+	}
+	if pg > 1 {
+		vals.Set("page", fmt.Sprint(pg-1))
+		params["Prev"] = vals.Encode()
+	}
+	params["PP"] = make(map[int]string, maxi)
+	for i := 0; i < maxi; i++ {
+		vals.Set("page", fmt.Sprint(i+1))
+		params["PP"].(map[int]string)[i+1] = vals.Encode()
+	}
+	params["Cur"] = pg
+	renderHTMLAdminLayout(c, "admin-audit", params)
+}
+
 // renderHTMLAdminLayout 渲染admin页面
 func renderHTMLAdminLayout(c *gin.Context, name string, data gin.H) {
 	c.Header("Content-Type", "text/html; charset=utf-8")
+	defer metrics.ObserveTemplate(name, time.Now())
+	_, span := trace.Start(c.Request.Context(), "template "+name)
+	defer span.End()
+	// 同一次渲染使用同一份模版
+	tmpl := htmlTmpl.Load()
 	// special page
-	if name == "login.html" {
-		err := htmlTmpl.ExecuteTemplate(c.Writer, name, data)
+	if name == "login.html" || name == "reset.html" {
+		err := tmpl.ExecuteTemplate(c.Writer, name, data)
 		if err != nil {
 			panic(err)
 		}
 		return
 	}
 	buf := bytes.Buffer{}
-	err := htmlTmpl.ExecuteTemplate(&buf, name, data)
+	err := tmpl.ExecuteTemplate(&buf, name, data)
 	if err != nil {
 		panic(err)
 	}
 	data["LayoutContent"] = htemplate.HTML(buf.String())
-	err = htmlTmpl.ExecuteTemplate(c.Writer, "adminLayout.html", data)
+	err = tmpl.ExecuteTemplate(c.Writer, "adminLayout.html", data)
 	if err != nil {
 		panic(err)
 	}
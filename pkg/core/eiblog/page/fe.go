@@ -3,7 +3,6 @@ package page
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 	htemplate "html/template"
 	"io"
@@ -16,6 +15,10 @@ import (
 	"github.com/eiblog/eiblog/pkg/cache"
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/pkg/mid"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/pkg/trace"
 	"github.com/eiblog/eiblog/tools"
 
 	"github.com/gin-gonic/gin"
@@ -24,7 +27,8 @@ import (
 
 // baseFEParams 基础参数
 func baseFEParams() gin.H {
-	version := config.Conf.EiBlogApp.StaticVersion
+	// 可热加载的配置项
+	app := config.Current().EiBlogApp
 
 	return gin.H{
 		"BlogName":   cache.Ei.Blogger.BlogName,
@@ -33,14 +37,21 @@ func baseFEParams() gin.H {
 		"BeiAn":      cache.Ei.Blogger.BeiAn,
 		"Domain":     config.Conf.EiBlogApp.Host,
 		"CopyYear":   time.Now().Year(),
-		"Twitter":    config.Conf.EiBlogApp.Twitter,
+		"Twitter":    app.Twitter,
 		"StaticFile": config.Conf.EiBlogApp.StaticFile,
 		"Disqus":     config.Conf.EiBlogApp.Disqus,
-		"AdSense":    config.Conf.EiBlogApp.Google.AdSense,
-		"Version":    version,
+		"AdSense":    app.Google.AdSense,
+		"Version":    app.StaticVersion,
 	}
 }
 
+// notModified 前台页面的协商缓存, 页面内容还与静态版本号及年份有关
+func notModified(c *gin.Context, modified time.Time, parts ...interface{}) bool {
+	parts = append(parts, modified.UnixNano(),
+		config.Current().EiBlogApp.StaticVersion, time.Now().Year())
+	return mid.NotModified(c, mid.ETag(c, parts...), modified)
+}
+
 // handleNotFound not found page
 func handleNotFound(c *gin.Context) {
 	params := baseFEParams()
@@ -53,19 +64,23 @@ func handleNotFound(c *gin.Context) {
 
 // handleHomePage 首页
 func handleHomePage(c *gin.Context) {
-	params := baseFEParams()
-	params["Title"] = cache.Ei.Blogger.BTitle + " | " + cache.Ei.Blogger.SubTitle
-	params["Description"] = "博客首页，" + cache.Ei.Blogger.SubTitle
-	params["Path"] = c.Request.URL.Path
-	params["CurrentPage"] = "blog-home"
+	if notModified(c, cache.Ei.Modified()) {
+		return
+	}
 	pn, err := strconv.Atoi(c.Query("pn"))
 	if err != nil || pn < 1 {
 		pn = 1
 	}
-	params["Prev"], params["Next"], params["List"] = cache.Ei.PageArticleFE(pn,
-		config.Conf.EiBlogApp.General.PageNum)
-
-	renderHTMLHomeLayout(c, "home", params)
+	renderHTMLHomeLayoutCache(c, cache.PageKeyHome(pn), "home", func() gin.H {
+		params := baseFEParams()
+		params["Title"] = cache.Ei.Blogger.BTitle + " | " + cache.Ei.Blogger.SubTitle
+		params["Description"] = "博客首页，" + cache.Ei.Blogger.SubTitle
+		params["Path"] = c.Request.URL.Path
+		params["CurrentPage"] = "blog-home"
+		params["Prev"], params["Next"], params["List"] = cache.Ei.PageArticleFE(pn,
+			config.Conf.EiBlogApp.General.PageNum)
+		return params
+	})
 }
 
 // handleArticlePage 文章页
@@ -76,60 +91,86 @@ func handleArticlePage(c *gin.Context) {
 		return
 	}
 	article := cache.Ei.ArticlesMap[slug[:len(slug)-5]]
-	params := baseFEParams()
-	params["Title"] = article.Title + " | " + cache.Ei.Blogger.BTitle
-	params["Path"] = c.Request.URL.Path
-	params["CurrentPage"] = "post-" + article.Slug
-	params["Article"] = article
+	updatedAt := article.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = article.CreatedAt
+	}
+	days := int(time.Now().Sub(updatedAt).Hours()) / 24
+	// 上下篇, 专题及评论数变化时Modified随之更新
+	modified := cache.Ei.Modified()
+	if updatedAt.After(modified) {
+		modified = updatedAt
+	}
+	if notModified(c, modified, article.ID, days) {
+		return
+	}
 
 	var name string
 	switch slug {
 	case "blogroll.html":
 		name = "blogroll"
-		params["Description"] = "友情连接，" + cache.Ei.Blogger.SubTitle
 	case "about.html":
 		name = "about"
-		params["Description"] = "关于作者，" + cache.Ei.Blogger.SubTitle
 	default:
-		params["Description"] = article.Desc + "，" + cache.Ei.Blogger.SubTitle
 		name = "article"
-		params["Copyright"] = cache.Ei.Blogger.Copyright
-		if !article.UpdatedAt.IsZero() {
-			params["Days"] = int(time.Now().Sub(article.UpdatedAt).Hours()) / 24
-		} else {
-			params["Days"] = int(time.Now().Sub(article.CreatedAt).Hours()) / 24
-		}
-		if article.SerieID > 0 {
-			for _, series := range cache.Ei.Series {
-				if series.ID == article.SerieID {
-					params["Serie"] = series
+	}
+	renderHTMLHomeLayoutCache(c, cache.PageKeyArticle(article.Slug), name, func() gin.H {
+		params := baseFEParams()
+		params["Title"] = article.Title + " | " + cache.Ei.Blogger.BTitle
+		params["Path"] = c.Request.URL.Path
+		params["CurrentPage"] = "post-" + article.Slug
+		params["Article"] = article
+		switch name {
+		case "blogroll":
+			params["Description"] = "友情连接，" + cache.Ei.Blogger.SubTitle
+		case "about":
+			params["Description"] = "关于作者，" + cache.Ei.Blogger.SubTitle
+		default:
+			params["Description"] = article.Desc + "，" + cache.Ei.Blogger.SubTitle
+			params["Copyright"] = cache.Ei.Blogger.Copyright
+			params["Days"] = days
+			if article.SerieID > 0 {
+				for _, series := range cache.Ei.Series {
+					if series.ID == article.SerieID {
+						params["Serie"] = series
+					}
 				}
 			}
 		}
-	}
-	renderHTMLHomeLayout(c, name, params)
+		return params
+	})
 }
 
 // handleSeriesPage 专题页
 func handleSeriesPage(c *gin.Context) {
-	params := baseFEParams()
-	params["Title"] = "专题 | " + cache.Ei.Blogger.BTitle
-	params["Description"] = "专题列表，" + cache.Ei.Blogger.SubTitle
-	params["Path"] = c.Request.URL.Path
-	params["CurrentPage"] = "series"
-	params["Article"] = cache.Ei.PageSeries
-	renderHTMLHomeLayout(c, "series", params)
+	if notModified(c, cache.Ei.PageModified(cache.PageSeries)) {
+		return
+	}
+	renderHTMLHomeLayoutCache(c, cache.PageKeySeries, "series", func() gin.H {
+		params := baseFEParams()
+		params["Title"] = "专题 | " + cache.Ei.Blogger.BTitle
+		params["Description"] = "专题列表，" + cache.Ei.Blogger.SubTitle
+		params["Path"] = c.Request.URL.Path
+		params["CurrentPage"] = "series"
+		params["Article"] = cache.Ei.PageSeries
+		return params
+	})
 }
 
 // handleArchivePage 归档页
 func handleArchivePage(c *gin.Context) {
-	params := baseFEParams()
-	params["Title"] = "归档 | " + cache.Ei.Blogger.BTitle
-	params["Description"] = "博客归档，" + cache.Ei.Blogger.SubTitle
-	params["Path"] = c.Request.URL.Path
-	params["CurrentPage"] = "archives"
-	params["Article"] = cache.Ei.PageArchives
-	renderHTMLHomeLayout(c, "archives", params)
+	if notModified(c, cache.Ei.PageModified(cache.PageArchive)) {
+		return
+	}
+	renderHTMLHomeLayoutCache(c, cache.PageKeyArchives, "archives", func() gin.H {
+		params := baseFEParams()
+		params["Title"] = "归档 | " + cache.Ei.Blogger.BTitle
+		params["Description"] = "博客归档，" + cache.Ei.Blogger.SubTitle
+		params["Path"] = c.Request.URL.Path
+		params["CurrentPage"] = "archives"
+		params["Article"] = cache.Ei.PageArchives
+		return params
+	})
 }
 
 // handleSearchPage 搜索页
@@ -149,7 +190,7 @@ func handleSearchPage(c *gin.Context) {
 		params["Word"] = q
 
 		vals := c.Request.URL.Query()
-		result, err := internal.ElasticSearch(q, config.Conf.EiBlogApp.General.PageNum, start-1)
+		result, err := internal.ElasticSearch(c.Request.Context(), q, config.Conf.EiBlogApp.General.PageNum, start-1)
 		if err != nil {
 			logrus.Error("HandleSearchPage.ElasticSearch: ", err)
 		} else {
@@ -171,7 +212,7 @@ func handleSearchPage(c *gin.Context) {
 			}
 		}
 	} else {
-		params["HotWords"] = config.Conf.EiBlogApp.HotWords
+		params["HotWords"] = config.Current().EiBlogApp.HotWords
 	}
 	renderHTMLHomeLayout(c, "search", params)
 }
@@ -199,7 +240,7 @@ func handleDisqusList(c *gin.Context) {
 	if artc != nil {
 		dcs.Data.Thread = artc.Thread
 	}
-	postsList, err := internal.PostsList(artc, cursor)
+	postsList, err := internal.PostsList(c.Request.Context(), artc, cursor)
 	if err != nil {
 		logrus.Error("hadnleDisqusList.PostsList: ", err)
 		dcs.ErrNo = 0
@@ -231,10 +272,10 @@ func handleDisqusList(c *gin.Context) {
 	if artc != nil && artc.Thread == "" {
 		if dcs.Data.Thread != "" {
 			artc.Thread = dcs.Data.Thread
-		} else if internal.ThreadDetails(artc) == nil {
+		} else if internal.ThreadDetails(c.Request.Context(), artc) == nil {
 			dcs.Data.Thread = artc.Thread
 		}
-		err := cache.Ei.UpdateArticle(context.Background(), artc.ID,
+		err := cache.Ei.UpdateArticle(c.Request.Context(), artc.ID,
 			map[string]interface{}{
 				"thread": artc.Thread,
 			})
@@ -310,20 +351,30 @@ func handleDisqusCreate(c *gin.Context) {
 		Identifier:  identifier,
 		IPAddress:   c.ClientIP(),
 	}
-	postDetail, err := internal.PostCreate(&comment)
+	postDetail, err := internal.PostCreate(c.Request.Context(), &comment)
 	if err != nil {
 		logrus.Error("handleDisqusCreate.PostCreate: ", err)
 		resp.ErrNo = 1
 		resp.ErrMsg = "提交评论失败，请重试"
 		return
 	}
-	err = internal.PostApprove(postDetail.Response.ID)
+	err = internal.PostApprove(c.Request.Context(), postDetail.Response.ID)
 	if err != nil {
 		logrus.Error("handleDisqusCreate.PostApprove: ", err)
 		resp.ErrNo = 1
 		resp.ErrMsg = "提交评论失败，请重试"
 	}
 	resp.ErrNo = 0
+	if article := cache.Ei.ArticlesMap[strings.TrimPrefix(identifier, "post-")]; article != nil {
+		cache.Ei.NotifyMail(internal.MailComment, map[string]interface{}{
+			"Title":   article.Title,
+			"URL":     config.Conf.EiBlogApp.SiteURL("", "/post/"+article.Slug+".html"),
+			"Name":    name,
+			"Email":   email,
+			"Message": msg,
+			"IP":      c.ClientIP(),
+		})
+	}
 	resp.Data = commentsDetail{
 		ID:           postDetail.Response.ID,
 		Name:         name,
@@ -341,9 +392,10 @@ func handleDisqusCreate(c *gin.Context) {
 func handleBeaconPage(c *gin.Context) {
 	ua := c.Request.UserAgent()
 
+	google := config.Current().EiBlogApp.Google
 	vals := c.Request.URL.Query()
-	vals.Set("v", config.Conf.EiBlogApp.Google.V)
-	vals.Set("tid", config.Conf.EiBlogApp.Google.Tid)
+	vals.Set("v", google.V)
+	vals.Set("tid", google.Tid)
 	cookie, _ := c.Cookie("u")
 	vals.Set("cid", cookie)
 
@@ -355,7 +407,7 @@ func handleBeaconPage(c *gin.Context) {
 	vals.Set("_p", fmt.Sprint(201226219+rand.Intn(499999999))) // random page load hash
 	vals.Set("_ee", "1")                                       // external event
 	go func() {
-		url := config.Conf.EiBlogApp.Google.URL + "?" + vals.Encode()
+		url := google.URL + "?" + vals.Encode()
 		req, err := http.NewRequest("POST", url, nil)
 		if err != nil {
 			logrus.Error("HandleBeaconPage.NewRequest: ", err)
@@ -389,28 +441,158 @@ func handleBeaconPage(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// handleNewsletterPage 邮件订阅页
+func handleNewsletterPage(c *gin.Context) {
+	if !config.Conf.EiBlogApp.Newsletter.Enable {
+		handleNotFound(c)
+		return
+	}
+	renderNewsletter(c, "", "subscribe", "")
+}
+
+// handleNewsletterSubscribe 订阅, 发送确认邮件
+func handleNewsletterSubscribe(c *gin.Context) {
+	if !config.Conf.EiBlogApp.Newsletter.Enable {
+		handleNotFound(c)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(c.PostForm("email")))
+	if !tools.ValidateEmail(email) {
+		c.Status(http.StatusBadRequest)
+		renderNewsletter(c, "邮箱地址格式错误。", "subscribe", "")
+		return
+	}
+	err := cache.Ei.Subscribe(c.Request.Context(), email, c.ClientIP())
+	if err == cache.ErrSubscribeThrottled {
+		c.Status(http.StatusTooManyRequests)
+		renderNewsletter(c, "请求过于频繁，请稍后重试。", "subscribe", "")
+		return
+	}
+	if err != nil {
+		logrus.Error("handleNewsletterSubscribe.Subscribe: ", err)
+		c.Status(http.StatusInternalServerError)
+		renderNewsletter(c, "订阅失败，请稍后重试。", "subscribe", "")
+		return
+	}
+	// 不区分是否已订阅, 避免泄露订阅者
+	renderNewsletter(c, "确认邮件已发送，请查收邮件并点击链接完成订阅。", "", "")
+}
+
+// handleNewsletterConfirm 确认订阅
+func handleNewsletterConfirm(c *gin.Context) {
+	if !config.Conf.EiBlogApp.Newsletter.Enable {
+		handleNotFound(c)
+		return
+	}
+	_, err := cache.Ei.ConfirmSubscriber(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		if err != cache.ErrInvalidToken {
+			logrus.Error("handleNewsletterConfirm.ConfirmSubscriber: ", err)
+		}
+		c.Status(http.StatusBadRequest)
+		renderNewsletter(c, "链接无效或已过期，请重新订阅。", "subscribe", "")
+		return
+	}
+	renderNewsletter(c, "订阅成功，感谢关注！", "", "")
+}
+
+// handleNewsletterUnsubscribePage 退订确认页, 避免邮件扫描GET请求误退订
+func handleNewsletterUnsubscribePage(c *gin.Context) {
+	if !config.Conf.EiBlogApp.Newsletter.Enable {
+		handleNotFound(c)
+		return
+	}
+	token := c.Query("token")
+	sub, err := cache.Ei.LoadSubscriberByToken(c.Request.Context(), token)
+	if err != nil || token == "" {
+		c.Status(http.StatusBadRequest)
+		renderNewsletter(c, "链接无效。", "", "")
+		return
+	}
+	if sub.Status == model.SubscriberUnsubscribed {
+		renderNewsletter(c, "你已退订。", "", "")
+		return
+	}
+	renderNewsletter(c, "确认不再接收 "+cache.Ei.Blogger.BTitle+" 的邮件推送？", "unsubscribe", token)
+}
+
+// handleNewsletterUnsubscribe 退订, 同时支持邮件客户端一键退订 (RFC 8058)
+func handleNewsletterUnsubscribe(c *gin.Context) {
+	if !config.Conf.EiBlogApp.Newsletter.Enable {
+		handleNotFound(c)
+		return
+	}
+	_, err := cache.Ei.Unsubscribe(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		if err != cache.ErrInvalidToken {
+			logrus.Error("handleNewsletterUnsubscribe.Unsubscribe: ", err)
+		}
+		c.Status(http.StatusBadRequest)
+		renderNewsletter(c, "链接无效。", "", "")
+		return
+	}
+	renderNewsletter(c, "退订成功，你将不再收到邮件推送。", "", "")
+}
+
+// renderNewsletter 渲染订阅页, form: subscribe 订阅表单, unsubscribe 退订表单
+func renderNewsletter(c *gin.Context, message, form, token string) {
+	params := baseFEParams()
+	params["Title"] = "邮件订阅 | " + cache.Ei.Blogger.BTitle
+	params["Description"] = "邮件订阅，" + cache.Ei.Blogger.SubTitle
+	params["Path"] = "/newsletter.html"
+	params["CurrentPage"] = "newsletter"
+	params["Message"] = message
+	params["Form"] = form
+	params["Token"] = token
+	renderHTMLHomeLayout(c, "newsletter", params)
+}
+
 // renderHTMLHomeLayout homelayout html
 func renderHTMLHomeLayout(c *gin.Context, name string, data gin.H) {
-	c.Header("Content-Type", "text/html; charset=utf-8")
 	// special page
 	if name == "disqus.html" {
-		err := htmlTmpl.ExecuteTemplate(c.Writer, name, data)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		defer metrics.ObserveTemplate(name, time.Now())
+		_, span := trace.Start(c.Request.Context(), "template "+name)
+		defer span.End()
+		err := htmlTmpl.Load().ExecuteTemplate(c.Writer, name, data)
 		if err != nil {
 			panic(err)
 		}
 		return
 	}
+	c.Data(c.Writer.Status(), "text/html; charset=utf-8", executeHomeLayout(c, name, data))
+}
+
+// renderHTMLHomeLayoutCache 优先使用缓存的渲染结果, 未命中时渲染并按key缓存, 内容变化时由cache.Ei清除
+func renderHTMLHomeLayoutCache(c *gin.Context, key, name string, params func() gin.H) {
+	body, ok := cache.Ei.Pages.Get(key)
+	if !ok {
+		// 在读取数据前取得, 渲染期间内容变化时不缓存
+		gen := cache.Ei.Pages.Gen()
+		body = executeHomeLayout(c, name, params())
+		cache.Ei.Pages.Set(key, gen, body)
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body)
+}
+
+// executeHomeLayout 渲染页面及homeLayout
+func executeHomeLayout(c *gin.Context, name string, data gin.H) []byte {
+	defer metrics.ObserveTemplate(name, time.Now())
+	_, span := trace.Start(c.Request.Context(), "template "+name)
+	defer span.End()
+	// 同一次渲染使用同一份模版
+	tmpl := htmlTmpl.Load()
 	buf := bytes.Buffer{}
-	err := htmlTmpl.ExecuteTemplate(&buf, name, data)
+	err := tmpl.ExecuteTemplate(&buf, name, data)
 	if err != nil {
 		panic(err)
 	}
 	data["LayoutContent"] = htemplate.HTML(buf.String())
-	err = htmlTmpl.ExecuteTemplate(c.Writer, "homeLayout.html", data)
+	buf = bytes.Buffer{}
+	err = tmpl.ExecuteTemplate(&buf, "homeLayout.html", data)
 	if err != nil {
 		panic(err)
 	}
-	if c.Writer.Status() == 0 {
-		c.Status(http.StatusOK)
-	}
+	return buf.Bytes()
 }
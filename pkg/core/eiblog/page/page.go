@@ -2,21 +2,68 @@
 package page
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 
 	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/core/eiblog"
 	"github.com/eiblog/eiblog/tools"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-// htmlTmpl html template cache
-var htmlTmpl *template.Template
+// htmlTmpl html template cache, 热加载时整体替换
+var htmlTmpl atomic.Pointer[template.Template]
+
+// reloadErr 最近一次热加载的错误
+var reloadErr atomic.Pointer[error]
+
+// staticHash 静态文件路径:内容摘要, 热加载时清空
+var staticHash sync.Map
 
 func init() {
-	htmlTmpl = template.New("eiblog").Funcs(tools.TplFuncMap)
+	tmpl, err := parseHTMLTmpl()
+	if err != nil {
+		panic(err)
+	}
+	htmlTmpl.Store(tmpl)
+}
+
+// ReloadTemplates 重新解析页面模版, 失败时保留原模版
+func ReloadTemplates() error {
+	staticHash.Range(func(key, _ interface{}) bool {
+		staticHash.Delete(key)
+		return true
+	})
+	tmpl, err := parseHTMLTmpl()
+	reloadErr.Store(&err)
+	if err != nil {
+		return err
+	}
+	htmlTmpl.Store(tmpl)
+	return nil
+}
+
+// ReloadError 最近一次热加载的错误, 未加载或成功时为nil
+func ReloadError() error {
+	if err := reloadErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// parseHTMLTmpl 解析website下的页面模版
+func parseHTMLTmpl() (*template.Template, error) {
+	tmpl := template.New("eiblog").Funcs(tools.TplFuncMap).
+		Funcs(template.FuncMap{"static": staticURL})
 	root := filepath.Join(config.WorkDir, "website")
 	files := tools.ReadDirFiles(root, func(fi fs.FileInfo) bool {
 		name := fi.Name()
@@ -29,28 +76,52 @@ func init() {
 		}
 		return false
 	})
-	_, err := htmlTmpl.ParseFiles(files...)
+	return tmpl.ParseFiles(files...)
+}
+
+// staticURL 模版函数, 以文件内容摘要作为/static的版本号, 文件更新后地址随之变化, 可长期缓存.
+// 如: {{static "admin/style.css"}} => /static/admin/style.css?v=1a2b3c4d
+func staticURL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	url := "/static/" + name
+	if v, ok := staticHash.Load(name); ok {
+		return url + "?v=" + v.(string)
+	}
+	data, err := os.ReadFile(filepath.Join(config.WorkDir, "assets", filepath.FromSlash(name)))
 	if err != nil {
-		panic(err)
+		logrus.Error("page.staticURL: ", err)
+		return url
 	}
+	sum := sha256.Sum256(data)
+	v := hex.EncodeToString(sum[:4])
+	staticHash.Store(name, v)
+	return url + "?v=" + v
 }
 
 // RegisterRoutes register routes
 func RegisterRoutes(e *gin.Engine) {
 	e.NoRoute(handleNotFound)
 
-	e.GET("/", handleHomePage)
-	e.GET("/post/:slug", handleArticlePage)
-	e.GET("/series.html", handleSeriesPage)
-	e.GET("/archives.html", handleArchivePage)
-	e.GET("/search.html", handleSearchPage)
+	pages := e.Group("", eiblog.CachePages())
+	pages.GET("/", handleHomePage)
+	pages.GET("/post/:slug", handleArticlePage)
+	pages.GET("/series.html", handleSeriesPage)
+	pages.GET("/archives.html", handleArchivePage)
+	pages.GET("/search.html", handleSearchPage)
 	e.GET("/disqus/post-:slug", handleDisqusList)
 	e.GET("/disqus/form/post-:slug", handleDisqusPage)
 	e.POST("/disqus/create", handleDisqusCreate)
 	e.GET("/beacon.html", handleBeaconPage)
+	// newsletter
+	e.GET("/newsletter.html", handleNewsletterPage)
+	e.POST("/newsletter/subscribe", handleNewsletterSubscribe)
+	e.GET("/newsletter/confirm", handleNewsletterConfirm)
+	e.GET("/newsletter/unsubscribe", handleNewsletterUnsubscribePage)
+	e.POST("/newsletter/unsubscribe", handleNewsletterUnsubscribe)
 
 	// login page
 	e.GET("/admin/login", handleLoginPage)
+	e.GET("/admin/reset", handleResetPage)
 }
 
 // RegisterRoutesAuthz register admin
@@ -59,7 +130,6 @@ func RegisterRoutesAuthz(group gin.IRoutes) {
 	group.GET("/profile", handleAdminProfile)
 	// write
 	group.GET("/write-post", handleAdminPost)
-	group.GET("/draft-delete", handleDraftDelete)
 	// manage
 	group.GET("/manage-posts", handleAdminPosts)
 	group.GET("/manage-series", handleAdminSeries)
@@ -69,4 +139,8 @@ func RegisterRoutesAuthz(group gin.IRoutes) {
 	group.GET("/manage-trash", handleAdminTrash)
 	group.GET("/options-general", handleAdminGeneral)
 	group.GET("/options-discussion", handleAdminDiscussion)
+	group.GET("/manage-webhooks", handleAdminWebhooks)
+	group.GET("/add-webhook", handleAdminWebhook)
+	group.GET("/manage-subscribers", handleAdminSubscribers)
+	group.GET("/manage-audit", handleAdminAudit)
 }
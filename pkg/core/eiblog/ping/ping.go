@@ -0,0 +1,90 @@
+// Package ping provides ...
+package ping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/core/eiblog/file"
+	"github.com/eiblog/eiblog/pkg/core/eiblog/page"
+	"github.com/eiblog/eiblog/pkg/health"
+	"github.com/eiblog/eiblog/pkg/internal"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes register routes
+func RegisterRoutes(group gin.IRoutes) {
+	newChecker().RegisterRoutes(group)
+}
+
+// newChecker 就绪检查: 存储、缓存、搜索引擎、模版及后台任务
+func newChecker() *health.Checker {
+	h := health.NewChecker()
+	h.Add("store", func(ctx context.Context) (string, error) {
+		return config.Conf.Database.Driver, cache.Ei.Store.Ping(ctx)
+	})
+	if cache.Ei.Sessions != store.SessionStore(cache.Ei.Store) {
+		h.Add("sessions", func(ctx context.Context) (string, error) {
+			return "redis", cache.Ei.Sessions.Ping(ctx)
+		})
+	}
+	h.Add("cache", func(ctx context.Context) (string, error) {
+		if cache.Ei.Blogger == nil || cache.Ei.Account == nil {
+			return "", errors.New("not loaded")
+		}
+		detail := fmt.Sprintf("%d articles", len(cache.Ei.Articles))
+		if cache.Ei.Pages != nil {
+			stats := cache.Ei.Pages.Stats()
+			detail += fmt.Sprintf(", %d pages (%d bytes), hit ratio %.2f",
+				stats.Entries, stats.Bytes, stats.HitRatio())
+		}
+		return detail, nil
+	})
+	if config.Conf.ESHost != "" {
+		h.Add("elasticsearch", func(ctx context.Context) (string, error) {
+			return "", internal.ElasticPing(ctx)
+		})
+	}
+	// 热加载失败时仍使用原模版, 不影响就绪, 错误见日志
+	h.Add("templates", func(ctx context.Context) (string, error) {
+		var details []string
+		if page.ReloadError() != nil {
+			details = append(details, "page reload failed")
+		}
+		if file.ReloadError() != nil {
+			details = append(details, "file reload failed")
+		}
+		return strings.Join(details, "; "), nil
+	})
+	// feed、sitemap等写入失败时后台任务会停止, 只降级, 不影响就绪
+	h.AddOptional("workers", func(ctx context.Context) (string, error) {
+		workers := cache.Ei.Workers()
+		for name, alive := range file.Workers() {
+			workers[name] = alive
+		}
+		var alive, stopped []string
+		for name, ok := range workers {
+			if ok {
+				alive = append(alive, name)
+			} else {
+				stopped = append(stopped, name)
+			}
+		}
+		sort.Strings(alive)
+		sort.Strings(stopped)
+		detail := "running: " + strings.Join(alive, ", ")
+		if len(stopped) > 0 {
+			detail += "; stopped: " + strings.Join(stopped, ", ")
+			return detail, errors.New("workers stopped")
+		}
+		return detail, nil
+	})
+	return h
+}
@@ -0,0 +1,252 @@
+// Package rpc provides ...
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/internal"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/pkg/proto/eiblogpb"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// listPageSize 从存储分页读取草稿及回收箱时每页数量
+const listPageSize = 100
+
+// ListArticles 已发布的文章从缓存读取, 草稿及回收箱从存储分页读取
+func (s *Service) ListArticles(req *eiblogpb.ListArticlesRequest, stream eiblogpb.EiBlog_ListArticlesServer) error {
+	if req.State != eiblogpb.ArticleState_ARTICLE_STATE_PUBLISHED {
+		if req.Tag != "" {
+			return status.Error(codes.InvalidArgument, "tag only applies to published articles")
+		}
+		return listStoreArticles(req, stream)
+	}
+	keyword := strings.ToLower(req.Keyword)
+	for _, article := range cache.Ei.PublishedArticles(req.Tag) {
+		if req.SerieId > 0 && article.SerieID != int(req.SerieId) {
+			continue
+		}
+		if keyword != "" && !strings.Contains(strings.ToLower(article.Title), keyword) {
+			continue
+		}
+		err := stream.Send(articleProto(article, req.WithContent))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listStoreArticles 草稿或回收箱中的文章
+func listStoreArticles(req *eiblogpb.ListArticlesRequest, stream eiblogpb.EiBlog_ListArticlesServer) error {
+	search := store.SearchArticles{Limit: listPageSize, Fields: make(map[string]interface{})}
+	if req.State == eiblogpb.ArticleState_ARTICLE_STATE_DRAFT {
+		search.Fields[store.SearchArticleDraft] = true
+	} else {
+		search.Fields[store.SearchArticleTrash] = true
+	}
+	if req.SerieId > 0 {
+		search.Fields[store.SearchArticleSerieID] = int(req.SerieId)
+	}
+	if req.Keyword != "" {
+		search.Fields[store.SearchArticleTitle] = req.Keyword
+	}
+	for search.Page = 1; ; search.Page++ {
+		articles, _, err := cache.Ei.LoadArticleList(stream.Context(), search)
+		if err != nil {
+			return storeError("rpc.ListArticles.LoadArticleList", err)
+		}
+		for _, article := range articles {
+			err = stream.Send(articleProto(article, req.WithContent))
+			if err != nil {
+				return err
+			}
+		}
+		if len(articles) < listPageSize {
+			return nil
+		}
+	}
+}
+
+// GetArticle 已发布的文章从缓存读取, 其它从存储读取
+func (s *Service) GetArticle(ctx context.Context, req *eiblogpb.GetArticleRequest) (*eiblogpb.Article, error) {
+	switch key := req.Key.(type) {
+	case *eiblogpb.GetArticleRequest_Id:
+		if article := cache.Ei.PublishedArticle(int(key.Id), ""); article != nil {
+			return articleProto(article, true), nil
+		}
+		article, err := cache.Ei.LoadArticle(ctx, int(key.Id))
+		if err != nil {
+			return nil, storeError("rpc.GetArticle.LoadArticle", err)
+		}
+		return articleProto(article, true), nil
+	case *eiblogpb.GetArticleRequest_Slug:
+		article := cache.Ei.PublishedArticle(0, key.Slug)
+		if article == nil {
+			return nil, status.Error(codes.NotFound, "not found")
+		}
+		return articleProto(article, true), nil
+	}
+	return nil, status.Error(codes.InvalidArgument, "id or slug is required")
+}
+
+// CreateArticle 同后台撰写文章
+func (s *Service) CreateArticle(ctx context.Context, req *eiblogpb.CreateArticleRequest) (*eiblogpb.Article, error) {
+	if req.Slug == "" || req.Title == "" || req.Content == "" {
+		return nil, status.Error(codes.InvalidArgument, "slug, title and content are required")
+	}
+	if cache.Ei.PublishedArticle(0, req.Slug) != nil {
+		return nil, status.Errorf(codes.AlreadyExists, "slug %q already exists", req.Slug)
+	}
+	if err := checkSerie(req.SerieId); err != nil {
+		return nil, err
+	}
+	article := &model.Article{
+		Title:     req.Title,
+		Content:   req.Content,
+		Slug:      req.Slug,
+		IsDraft:   req.Draft,
+		Author:    cache.Ei.Account.Username,
+		SerieID:   int(req.SerieId),
+		Tags:      req.Tags,
+		CreatedAt: time.Now().UTC(),
+	}
+	if req.CreatedAt != nil {
+		article.CreatedAt = req.CreatedAt.AsTime()
+	}
+	err := cache.Ei.AddArticle(ctx, article)
+	if err != nil {
+		return nil, storeError("rpc.CreateArticle.AddArticle", err)
+	}
+	action := cache.AuditArticleCreate
+	if !article.IsDraft {
+		action = cache.AuditArticlePublish
+		cache.Ei.SyncArticle(article, true)
+	}
+	audit(ctx, action, fmt.Sprint("article:", article.ID), nil, cache.AuditArticle(article))
+	return articleProto(article, true), nil
+}
+
+// UpdateArticle 同后台编辑文章, slug不可修改
+func (s *Service) UpdateArticle(ctx context.Context, req *eiblogpb.UpdateArticleRequest) (*eiblogpb.Article, error) {
+	if req.Title == "" || req.Content == "" {
+		return nil, status.Error(codes.InvalidArgument, "title and content are required")
+	}
+	if err := checkSerie(req.SerieId); err != nil {
+		return nil, err
+	}
+	artc := cache.Ei.PublishedArticle(int(req.Id), "")
+	before := artc
+	if before == nil {
+		var err error
+		before, err = cache.Ei.LoadArticle(ctx, int(req.Id))
+		if err != nil {
+			return nil, storeError("rpc.UpdateArticle.LoadArticle", err)
+		}
+		if !before.DeletedAt.IsZero() {
+			return nil, status.Error(codes.FailedPrecondition, "article is in trash")
+		}
+	}
+	if artc != nil && req.Draft {
+		return nil, status.Error(codes.FailedPrecondition, "published article can not be a draft")
+	}
+	article := &model.Article{
+		ID:        before.ID,
+		Author:    before.Author,
+		Slug:      before.Slug,
+		Title:     req.Title,
+		Content:   req.Content,
+		SerieID:   int(req.SerieId),
+		Tags:      req.Tags,
+		IsDraft:   req.Draft,
+		Thread:    before.Thread,
+		Count:     before.Count,
+		UpdatedAt: before.UpdatedAt,
+		CreatedAt: before.CreatedAt,
+	}
+	if req.CreatedAt != nil {
+		article.CreatedAt = req.CreatedAt.AsTime()
+	}
+	if req.Touch {
+		article.UpdatedAt = time.Now()
+	}
+	err := cache.Ei.UpdateArticle(ctx, article.ID, map[string]interface{}{
+		"title":      article.Title,
+		"content":    article.Content,
+		"serie_id":   article.SerieID,
+		"is_draft":   article.IsDraft,
+		"tags":       article.Tags,
+		"updated_at": article.UpdatedAt,
+		"created_at": article.CreatedAt,
+	})
+	if err != nil {
+		return nil, storeError("rpc.UpdateArticle.UpdateArticle", err)
+	}
+	action := cache.AuditArticleUpdate
+	if artc == nil && !article.IsDraft {
+		action = cache.AuditArticlePublish
+	}
+	audit(ctx, action, fmt.Sprint("article:", article.ID), cache.AuditArticle(before),
+		cache.AuditArticle(article))
+	if !article.IsDraft {
+		cache.Ei.RepArticle(artc, article)
+		cache.Ei.SyncArticle(article, artc == nil)
+	}
+	return articleProto(article, true), nil
+}
+
+// DeleteArticle 已发布的文章移入回收箱, 草稿及回收箱中的文章直接删除
+func (s *Service) DeleteArticle(ctx context.Context, req *eiblogpb.DeleteArticleRequest) (*eiblogpb.DeleteArticleResponse, error) {
+	id := int(req.Id)
+	if article := cache.Ei.PublishedArticle(id, ""); article != nil {
+		if id < cache.ArticleStartID {
+			return nil, status.Error(codes.FailedPrecondition, "builtin page can not be deleted")
+		}
+		err := cache.Ei.DelArticle(ctx, id)
+		if err != nil {
+			return nil, storeError("rpc.DeleteArticle.DelArticle", err)
+		}
+		audit(ctx, cache.AuditArticleTrash, fmt.Sprint("article:", id), cache.AuditArticle(article), nil)
+		err = internal.ElasticDelIndex([]int{id})
+		if err != nil {
+			logrus.Error("rpc.DeleteArticle.ElasticDelIndex: ", err)
+		}
+		return &eiblogpb.DeleteArticleResponse{}, nil
+	}
+	article, err := cache.Ei.LoadArticle(ctx, id)
+	if err != nil {
+		return nil, storeError("rpc.DeleteArticle.LoadArticle", err)
+	}
+	err = cache.Ei.RemoveArticle(ctx, id)
+	if err != nil {
+		return nil, storeError("rpc.DeleteArticle.RemoveArticle", err)
+	}
+	audit(ctx, cache.AuditArticlePurge, fmt.Sprint("article:", id), cache.AuditArticle(article), nil)
+	return &eiblogpb.DeleteArticleResponse{}, nil
+}
+
+// checkSerie 专题需存在, 0为不属于任何专题
+func checkSerie(id int32) error {
+	if id == 0 || findSerie(id) != nil {
+		return nil
+	}
+	return status.Errorf(codes.InvalidArgument, "serie %d not found", id)
+}
+
+// findSerie 按ID查找专题
+func findSerie(id int32) *model.Serie {
+	for _, serie := range cache.Ei.SeriesList() {
+		if serie.ID == int(id) {
+			return serie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Package rpc provides ...
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/cache/store"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/pkg/proto/eiblogpb"
+	"github.com/eiblog/eiblog/pkg/server"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Service 内容管理gRPC服务, 读写均经过cache.Ei, 与后台保持一致
+type Service struct {
+	eiblogpb.UnimplementedEiBlogServer
+}
+
+// Register 注册服务
+func Register(s *grpc.Server) {
+	eiblogpb.RegisterEiBlogServer(s, &Service{})
+}
+
+// audit 记录审计日志, 以token名称为操作者
+func audit(ctx context.Context, action, target string, before, after interface{}) {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok {
+		ip, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+	cache.Ei.Audit(ctx, &model.AuditLog{
+		Actor:  "grpc:" + server.TokenName(ctx),
+		IP:     ip,
+		Action: action,
+		Target: target,
+		Before: cache.AuditSummary(before),
+		After:  cache.AuditSummary(after),
+	})
+}
+
+// storeError 存储错误转为gRPC状态
+func storeError(method string, err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return status.Error(codes.NotFound, "not found")
+	}
+	logrus.Error(method, ": ", err)
+	return status.Error(codes.Internal, err.Error())
+}
+
+// timestamp 零值为nil
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// articleProto withContent为false时不返回正文
+func articleProto(article *model.Article, withContent bool) *eiblogpb.Article {
+	pb := &eiblogpb.Article{
+		Id:           int32(article.ID),
+		Slug:         article.Slug,
+		Title:        article.Title,
+		Author:       article.Author,
+		SerieId:      int32(article.SerieID),
+		Tags:         article.Tags,
+		Draft:        article.IsDraft,
+		CommentCount: int32(article.Count),
+		CreatedAt:    timestamp(article.CreatedAt),
+		UpdatedAt:    timestamp(article.UpdatedAt),
+		DeletedAt:    timestamp(article.DeletedAt),
+	}
+	if withContent {
+		pb.Content = article.Content
+	}
+	return pb
+}
+
+// serieProto 专题及其文章ID
+func serieProto(serie *model.Serie) *eiblogpb.Serie {
+	pb := &eiblogpb.Serie{
+		Id:        int32(serie.ID),
+		Slug:      serie.Slug,
+		Name:      serie.Name,
+		Desc:      serie.Desc,
+		CreatedAt: timestamp(serie.CreatedAt),
+	}
+	for _, article := range serie.Articles {
+		pb.ArticleIds = append(pb.ArticleIds, int32(article.ID))
+	}
+	return pb
+}
+
+// bloggerProto 博客信息
+func bloggerProto(blogger *model.Blogger) *eiblogpb.Blogger {
+	return &eiblogpb.Blogger{
+		BlogName:    blogger.BlogName,
+		SubTitle:    blogger.SubTitle,
+		BeiAn:       blogger.BeiAn,
+		BTitle:      blogger.BTitle,
+		Copyright:   blogger.Copyright,
+		SeriesSay:   blogger.SeriesSay,
+		ArchivesSay: blogger.ArchivesSay,
+	}
+}
@@ -0,0 +1,133 @@
+// Package rpc provides ...
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/cache"
+	"github.com/eiblog/eiblog/pkg/model"
+	"github.com/eiblog/eiblog/pkg/proto/eiblogpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListSeries 专题列表
+func (s *Service) ListSeries(ctx context.Context, req *eiblogpb.ListSeriesRequest) (*eiblogpb.ListSeriesResponse, error) {
+	resp := &eiblogpb.ListSeriesResponse{}
+	for _, serie := range cache.Ei.SeriesList() {
+		resp.Series = append(resp.Series, serieProto(serie))
+	}
+	return resp, nil
+}
+
+// CreateSerie 同后台添加专题
+func (s *Service) CreateSerie(ctx context.Context, req *eiblogpb.CreateSerieRequest) (*eiblogpb.Serie, error) {
+	if err := checkSerieFields(0, req.Slug, req.Name, req.Desc); err != nil {
+		return nil, err
+	}
+	serie := &model.Serie{
+		Slug:      req.Slug,
+		Name:      req.Name,
+		Desc:      req.Desc,
+		CreatedAt: time.Now(),
+	}
+	err := cache.Ei.AddSerie(ctx, serie)
+	if err != nil {
+		return nil, storeError("rpc.CreateSerie.AddSerie", err)
+	}
+	audit(ctx, cache.AuditSerieCreate, fmt.Sprint("serie:", serie.ID), nil, cache.AuditSerie(serie))
+	return serieProto(serie), nil
+}
+
+// UpdateSerie 同后台更新专题
+func (s *Service) UpdateSerie(ctx context.Context, req *eiblogpb.UpdateSerieRequest) (*eiblogpb.Serie, error) {
+	serie := findSerie(req.Id)
+	if serie == nil {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	if err := checkSerieFields(req.Id, req.Slug, req.Name, req.Desc); err != nil {
+		return nil, err
+	}
+	before := cache.AuditSerie(serie)
+	err := cache.Ei.EditSerie(ctx, serie, req.Slug, req.Name, req.Desc)
+	if err != nil {
+		return nil, storeError("rpc.UpdateSerie.EditSerie", err)
+	}
+	audit(ctx, cache.AuditSerieUpdate, fmt.Sprint("serie:", serie.ID), before, cache.AuditSerie(serie))
+	return serieProto(serie), nil
+}
+
+// DeleteSerie 专题下不能有文章
+func (s *Service) DeleteSerie(ctx context.Context, req *eiblogpb.DeleteSerieRequest) (*eiblogpb.DeleteSerieResponse, error) {
+	serie := findSerie(req.Id)
+	if serie == nil {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	if len(serie.Articles) > 0 {
+		return nil, status.Error(codes.FailedPrecondition, "serie has articles")
+	}
+	err := cache.Ei.DelSerie(ctx, serie.ID)
+	if err != nil {
+		return nil, storeError("rpc.DeleteSerie.DelSerie", err)
+	}
+	audit(ctx, cache.AuditSerieDelete, fmt.Sprint("serie:", serie.ID), cache.AuditSerie(serie), nil)
+	return &eiblogpb.DeleteSerieResponse{}, nil
+}
+
+// checkSerieFields 必填项及slug唯一
+func checkSerieFields(id int32, slug, name, desc string) error {
+	if slug == "" || name == "" || desc == "" {
+		return status.Error(codes.InvalidArgument, "slug, name and desc are required")
+	}
+	for _, serie := range cache.Ei.SeriesList() {
+		if serie.Slug == slug && serie.ID != int(id) {
+			return status.Errorf(codes.AlreadyExists, "slug %q already exists", slug)
+		}
+	}
+	return nil
+}
+
+// ListTags 按名称排序
+func (s *Service) ListTags(ctx context.Context, req *eiblogpb.ListTagsRequest) (*eiblogpb.ListTagsResponse, error) {
+	resp := &eiblogpb.ListTagsResponse{}
+	for name, count := range cache.Ei.TagCounts() {
+		resp.Tags = append(resp.Tags, &eiblogpb.Tag{Name: name, ArticleCount: int32(count)})
+	}
+	sort.Slice(resp.Tags, func(i, j int) bool { return resp.Tags[i].Name < resp.Tags[j].Name })
+	return resp, nil
+}
+
+// GetBlogger 博客信息
+func (s *Service) GetBlogger(ctx context.Context, req *eiblogpb.GetBloggerRequest) (*eiblogpb.Blogger, error) {
+	blogger := cache.Ei.BloggerInfo()
+	return bloggerProto(&blogger), nil
+}
+
+// UpdateBlogger 同后台更新博客信息, 版权声明不可修改
+func (s *Service) UpdateBlogger(ctx context.Context, req *eiblogpb.UpdateBloggerRequest) (*eiblogpb.Blogger, error) {
+	pb := req.Blogger
+	if pb == nil || pb.BlogName == "" || pb.BTitle == "" {
+		return nil, status.Error(codes.InvalidArgument, "blog_name and b_title are required")
+	}
+	current := cache.Ei.BloggerInfo()
+	before := cache.AuditBlogger(&current)
+	blogger := &model.Blogger{
+		BlogName:    pb.BlogName,
+		SubTitle:    pb.SubTitle,
+		BeiAn:       pb.BeiAn,
+		BTitle:      pb.BTitle,
+		SeriesSay:   pb.SeriesSay,
+		ArchivesSay: pb.ArchivesSay,
+	}
+	err := cache.Ei.EditBlogger(ctx, blogger)
+	if err != nil {
+		return nil, storeError("rpc.UpdateBlogger.EditBlogger", err)
+	}
+	audit(ctx, cache.AuditBloggerUpdate, "blogger", before, cache.AuditBlogger(blogger))
+	current = cache.Ei.BloggerInfo()
+	return bloggerProto(&current), nil
+}
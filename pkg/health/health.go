@@ -0,0 +1,131 @@
+// Package health provides liveness and readiness checks
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// check status
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// checkTimeout 单次就绪检查的最长时间
+const checkTimeout = 3 * time.Second
+
+// Check 检查项, detail为补充说明, 返回错误时未就绪
+type Check func(ctx context.Context) (detail string, err error)
+
+// CheckResult 检查项结果, 错误只写入日志, 不对外返回
+type CheckResult struct {
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"-"`
+	Duration string `json:"duration"`
+}
+
+// Result 检查结果, 必需项失败为fail, 仅非必需项失败为degraded
+type Result struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker 就绪检查
+type Checker struct {
+	names    []string
+	checks   map[string]Check
+	optional map[string]bool // 失败时只降级的检查项
+}
+
+// NewChecker 创建就绪检查
+func NewChecker() *Checker {
+	return &Checker{
+		checks:   make(map[string]Check),
+		optional: make(map[string]bool),
+	}
+}
+
+// Add 添加必需检查项, 失败时未就绪
+func (h *Checker) Add(name string, check Check) {
+	h.add(name, check, false)
+}
+
+// AddOptional 添加非必需检查项, 失败时降级, 不影响就绪
+func (h *Checker) AddOptional(name string, check Check) {
+	h.add(name, check, true)
+}
+
+func (h *Checker) add(name string, check Check, optional bool) {
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+	h.optional[name] = optional
+}
+
+// Run 并发执行所有检查项
+func (h *Checker) Run(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	result := Result{Status: StatusOK, Checks: make(map[string]CheckResult)}
+	for _, name := range h.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			start := time.Now()
+			detail, err := check(ctx)
+			cr := CheckResult{
+				Status:   StatusOK,
+				Detail:   detail,
+				Duration: time.Since(start).Round(time.Microsecond).String(),
+			}
+			status := StatusFail
+			if h.optional[name] {
+				status = StatusDegraded
+			}
+			if err != nil {
+				logrus.Warnf("health.Run: %s %s: %v", name, status, err)
+				cr.Status = status
+				cr.Error = err.Error()
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			result.Checks[name] = cr
+			if err != nil && result.Status != StatusFail {
+				result.Status = status
+			}
+		}(name, h.checks[name])
+	}
+	wg.Wait()
+	return result
+}
+
+// RegisterRoutes 注册 /healthz 存活检查及 /readyz 就绪检查, 未就绪时返回503, 降级时仍返回200
+func (h *Checker) RegisterRoutes(group gin.IRoutes) {
+	group.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, Result{Status: StatusOK})
+	})
+	group.GET("/readyz", func(c *gin.Context) {
+		result := h.Run(c.Request.Context())
+		code := http.StatusOK
+		if result.Status == StatusFail {
+			code = http.StatusServiceUnavailable
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(code, result)
+	})
+}
@@ -0,0 +1,91 @@
+// Package health provides liveness and readiness checks
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestChecker(t *testing.T) {
+	h := NewChecker()
+	h.Add("store", func(ctx context.Context) (string, error) {
+		return "sqlite", nil
+	})
+	h.Add("elasticsearch", func(ctx context.Context) (string, error) {
+		return "", errors.New("dial tcp 10.0.0.1:9200: connection refused")
+	})
+
+	result := h.Run(context.Background())
+	if result.Status != StatusFail {
+		t.Errorf("Run() status = %s, want %s", result.Status, StatusFail)
+	}
+	if cr := result.Checks["store"]; cr.Status != StatusOK || cr.Detail != "sqlite" {
+		t.Errorf("store = %+v", cr)
+	}
+	if cr := result.Checks["elasticsearch"]; cr.Status != StatusFail || cr.Error == "" {
+		t.Errorf("elasticsearch = %+v", cr)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	h.RegisterRoutes(e)
+	for path, code := range map[string]int{
+		"/healthz": http.StatusOK,
+		"/readyz":  http.StatusServiceUnavailable,
+	} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, code)
+		}
+		// 错误只写入日志
+		if strings.Contains(w.Body.String(), "10.0.0.1") {
+			t.Errorf("GET %s exposes the error: %s", path, w.Body.String())
+		}
+	}
+}
+
+func TestCheckerOptional(t *testing.T) {
+	tests := []struct {
+		name     string
+		required error
+		optional error
+		status   string
+		code     int
+	}{
+		{"all ok", nil, nil, StatusOK, http.StatusOK},
+		{"optional failed", nil, errors.New("stopped: feed"), StatusDegraded, http.StatusOK},
+		{"required failed", errors.New("down"), nil, StatusFail, http.StatusServiceUnavailable},
+		{"both failed", errors.New("down"), errors.New("stopped: feed"), StatusFail, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewChecker()
+			h.Add("store", func(ctx context.Context) (string, error) { return "", tt.required })
+			h.AddOptional("workers", func(ctx context.Context) (string, error) { return "", tt.optional })
+
+			result := h.Run(context.Background())
+			if result.Status != tt.status {
+				t.Errorf("Run() status = %s, want %s", result.Status, tt.status)
+			}
+			if cr := result.Checks["workers"]; tt.optional != nil && cr.Status != StatusDegraded {
+				t.Errorf("workers = %+v, want %s", cr, StatusDegraded)
+			}
+
+			gin.SetMode(gin.TestMode)
+			e := gin.New()
+			h.RegisterRoutes(e)
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.code {
+				t.Errorf("GET /readyz = %d, want %d", w.Code, tt.code)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/eiblog/eiblog/pkg/config"
 	"github.com/eiblog/eiblog/pkg/model"
@@ -46,10 +48,11 @@ type postsCountResp struct {
 }
 
 // PostsCount 获取文章评论数量
-func PostsCount(articles map[string]*model.Article) error {
+func PostsCount(articles map[string]*model.Article) (err error) {
 	if err := checkDisqusConfig(); err != nil {
 		return err
 	}
+	defer observeCall("disqus", "posts_count", time.Now(), &err)
 
 	vals := url.Values{}
 	vals.Set("api_key", config.Conf.EiBlogApp.Disqus.PublicKey)
@@ -65,7 +68,7 @@ func PostsCount(articles map[string]*model.Article) error {
 			continue
 		}
 		count = 0
-		resp, err := httpGet(apiPostsCount + "?" + vals.Encode())
+		resp, err := httpGet(context.Background(), apiPostsCount+"?"+vals.Encode())
 		if err != nil {
 			return err
 		}
@@ -125,10 +128,11 @@ type postDetail struct {
 }
 
 // PostsList 评论列表
-func PostsList(article *model.Article, cursor string) (*PostsListResp, error) {
+func PostsList(ctx context.Context, article *model.Article, cursor string) (_ *PostsListResp, err error) {
 	if err := checkDisqusConfig(); err != nil {
 		return nil, err
 	}
+	defer observeCall("disqus", "posts_list", time.Now(), &err)
 
 	vals := url.Values{}
 	vals.Set("api_key", disqusAPIKey)
@@ -138,7 +142,7 @@ func PostsList(article *model.Article, cursor string) (*PostsListResp, error) {
 	vals.Set("order", "popular")
 	vals.Set("limit", "50")
 
-	resp, err := httpGet(apiPostsList + "?" + vals.Encode())
+	resp, err := httpGet(ctx, apiPostsList+"?"+vals.Encode())
 	if err != nil {
 		return nil, err
 	}
@@ -179,10 +183,11 @@ type PostCreateResp struct {
 }
 
 // PostCreate 评论文章
-func PostCreate(pc *PostComment) (*PostCreateResp, error) {
+func PostCreate(ctx context.Context, pc *PostComment) (_ *PostCreateResp, err error) {
 	if err := checkDisqusConfig(); err != nil {
 		return nil, err
 	}
+	defer observeCall("disqus", "post_create", time.Now(), &err)
 	vals := url.Values{}
 	vals.Set("api_key", disqusAPIKey)
 	vals.Set("message", pc.Message)
@@ -193,7 +198,7 @@ func PostCreate(pc *PostComment) (*PostCreateResp, error) {
 	// vals.Set("state", "approved")
 
 	header := http.Header{"Referer": {"https://disqus.com"}}
-	resp, err := httpPostHeader(apiPostCreate, vals, header)
+	resp, err := httpPostHeader(ctx, apiPostCreate, vals, header)
 	if err != nil {
 		return nil, err
 	}
@@ -223,10 +228,11 @@ type approvedResp struct {
 }
 
 // PostApprove 批准评论
-func PostApprove(post string) error {
+func PostApprove(ctx context.Context, post string) (err error) {
 	if err := checkDisqusConfig(); err != nil {
 		return err
 	}
+	defer observeCall("disqus", "post_approve", time.Now(), &err)
 
 	vals := url.Values{}
 	vals.Set("api_key", config.Conf.EiBlogApp.Disqus.PublicKey)
@@ -234,7 +240,7 @@ func PostApprove(post string) error {
 	vals.Set("post", post)
 
 	header := http.Header{"Referer": {"https://disqus.com"}}
-	resp, err := httpPostHeader(apiPostApprove, vals, header)
+	resp, err := httpPostHeader(ctx, apiPostApprove, vals, header)
 	if err != nil {
 		return err
 	}
@@ -262,10 +268,11 @@ type threadCreateResp struct {
 }
 
 // ThreadCreate 创建thread
-func ThreadCreate(article *model.Article, btitle string) error {
+func ThreadCreate(article *model.Article, btitle string) (err error) {
 	if err := checkDisqusConfig(); err != nil {
 		return err
 	}
+	defer observeCall("disqus", "thread_create", time.Now(), &err)
 
 	vals := url.Values{}
 	vals.Set("api_key", config.Conf.EiBlogApp.Disqus.PublicKey)
@@ -277,7 +284,7 @@ func ThreadCreate(article *model.Article, btitle string) error {
 	urlPath := fmt.Sprintf("https://%s/post/%s.html", config.Conf.EiBlogApp.Host, article.Slug)
 	vals.Set("url", urlPath)
 
-	resp, err := httpPost(apiThreadCreate, vals)
+	resp, err := httpPost(context.Background(), apiThreadCreate, vals)
 	if err != nil {
 		return err
 	}
@@ -309,10 +316,11 @@ type threadDetailsResp struct {
 }
 
 // ThreadDetails thread详细
-func ThreadDetails(article *model.Article) error {
+func ThreadDetails(ctx context.Context, article *model.Article) (err error) {
 	if err := checkDisqusConfig(); err != nil {
 		return err
 	}
+	defer observeCall("disqus", "thread_details", time.Now(), &err)
 
 	vals := url.Values{}
 	vals.Set("api_key", config.Conf.EiBlogApp.Disqus.PublicKey)
@@ -320,7 +328,7 @@ func ThreadDetails(article *model.Article) error {
 	vals.Set("forum", config.Conf.EiBlogApp.Disqus.ShortName)
 	vals.Set("thread:ident", "post-"+article.Slug)
 
-	resp, err := httpGet(apiThreadDetails + "?" + vals.Encode())
+	resp, err := httpGet(ctx, apiThreadDetails+"?"+vals.Encode())
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -35,7 +36,7 @@ func init() {
 	}
 
 	mappings := fmt.Sprintf(`{"mappings":{"%s":{"properties":{"content":{"analyzer":"ik_syno","search_analyzer":"ik_syno","term_vector":"with_positions_offsets","type":"string"},"date":{"index":"not_analyzed","type":"date"},"slug":{"type":"string"},"tag":{"index":"not_analyzed","type":"string"},"title":{"analyzer":"ik_syno","search_analyzer":"ik_syno","term_vector":"with_positions_offsets","type":"string"}}}}}`, "article")
-	err := createIndexAndMappings(ElasticIndex, ElasticType, []byte(mappings))
+	err := createIndexAndMappings(context.Background(), ElasticIndex, ElasticType, []byte(mappings))
 	if err != nil {
 		logrus.Error(err)
 	}
@@ -48,11 +49,32 @@ func checkESConfig() error {
 	return nil
 }
 
+// ElasticPing 检查elasticsearch是否可用
+func ElasticPing(ctx context.Context) error {
+	if err := checkESConfig(); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.Conf.ESHost, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("es: ping status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // ElasticSearch 搜索文章
-func ElasticSearch(query string, size, from int) (*SearchIndexResult, error) {
+func ElasticSearch(ctx context.Context, query string, size, from int) (_ *SearchIndexResult, err error) {
 	if err := checkESConfig(); err != nil {
 		return nil, err
 	}
+	defer observeCall("elasticsearch", "search", time.Now(), &err)
 	// 分析查询
 	var (
 		regTerm = regexp.MustCompile(`(tag|slug|date):`)
@@ -102,14 +124,15 @@ func ElasticSearch(query string, size, from int) (*SearchIndexResult, error) {
 	if kw != "" {
 		dsl = strings.Replace(strings.Replace(`{"highlight":{"fields":{"content":{},"title":{}},"post_tags":["\u003c/b\u003e"],"pre_tags":["\u003cb\u003e"]},"query":{"dis_max":{"queries":[{"match":{"title":{"boost":4,"minimum_should_match":"50%","query":"$1"}}},{"match":{"content":{"boost":4,"minimum_should_match":"75%","query":"$1"}}},{"match":{"tag":{"boost":2,"minimum_should_match":"100%","query":"$1"}}},{"match":{"slug":{"boost":1,"minimum_should_match":"100%","query":"$1"}}}],"tie_breaker":0.3}},$2}`, "$1", kw, -1), "$2", fmt.Sprintf(SearchFilter, strings.Join(filter, ",")), -1)
 	}
-	return indexQueryDSL(ElasticIndex, ElasticType, size, from, []byte(dsl))
+	return indexQueryDSL(ctx, ElasticIndex, ElasticType, size, from, []byte(dsl))
 }
 
 // ElasticAddIndex 添加或更新索引
-func ElasticAddIndex(article *model.Article) error {
+func ElasticAddIndex(article *model.Article) (err error) {
 	if err := checkESConfig(); err != nil {
 		return err
 	}
+	defer observeCall("elasticsearch", "add_index", time.Now(), &err)
 
 	img := tools.PickFirstImage(article.Content)
 	mapping := map[string]interface{}{
@@ -121,20 +144,21 @@ func ElasticAddIndex(article *model.Article) error {
 		"date":    article.CreatedAt,
 	}
 	data, _ := json.Marshal(mapping)
-	return indexOrUpdateDocument(ElasticIndex, ElasticType, article.ID, data)
+	return indexOrUpdateDocument(context.Background(), ElasticIndex, ElasticType, article.ID, data)
 }
 
 // ElasticDelIndex 删除索引
-func ElasticDelIndex(ids []int) error {
+func ElasticDelIndex(ids []int) (err error) {
 	if err := checkESConfig(); err != nil {
 		return err
 	}
+	defer observeCall("elasticsearch", "del_index", time.Now(), &err)
 
 	var target []string
 	for _, id := range ids {
 		target = append(target, fmt.Sprint(id))
 	}
-	return deleteIndexDocument(ElasticIndex, ElasticType, target)
+	return deleteIndexDocument(context.Background(), ElasticIndex, ElasticType, target)
 }
 
 // indicesCreateResult 索引创建结果
@@ -143,9 +167,9 @@ type indicesCreateResult struct {
 }
 
 // createIndexAndMappings 创建索引和映射关系
-func createIndexAndMappings(index, typ string, mappings []byte) error {
+func createIndexAndMappings(ctx context.Context, index, typ string, mappings []byte) error {
 	rawurl := fmt.Sprintf("%s/%s/%s", config.Conf.ESHost, index, typ)
-	resp, err := httpHead(rawurl)
+	resp, err := httpHead(ctx, rawurl)
 	if err != nil {
 		return err
 	}
@@ -155,7 +179,7 @@ func createIndexAndMappings(index, typ string, mappings []byte) error {
 	}
 
 	rawurl = fmt.Sprintf("%s/%s", config.Conf.ESHost, index)
-	resp, err = httpPut(rawurl, mappings)
+	resp, err = httpPut(ctx, rawurl, mappings)
 	if err != nil {
 		return err
 	}
@@ -176,9 +200,9 @@ func createIndexAndMappings(index, typ string, mappings []byte) error {
 }
 
 // indexOrUpdateDocument 创建或更新索引
-func indexOrUpdateDocument(index, typ string, id int, doc []byte) (err error) {
+func indexOrUpdateDocument(ctx context.Context, index, typ string, id int, doc []byte) (err error) {
 	rawurl := fmt.Sprintf("%s/%s/%s/%d", config.Conf.ESHost, index, typ, id)
-	resp, err := httpPut(rawurl, doc)
+	resp, err := httpPut(ctx, rawurl, doc)
 	if err != nil {
 		return err
 	}
@@ -205,7 +229,7 @@ type deleteIndexResult struct {
 }
 
 // deleteIndexDocument 删除文档
-func deleteIndexDocument(index, typ string, ids []string) error {
+func deleteIndexDocument(ctx context.Context, index, typ string, ids []string) error {
 	buf := bytes.Buffer{}
 	for _, id := range ids {
 		dd := deleteIndexReq{Index: index, Type: typ, ID: id}
@@ -215,7 +239,7 @@ func deleteIndexDocument(index, typ string, ids []string) error {
 		buf.WriteByte('\n')
 	}
 	rawurl := fmt.Sprintf("%s/_bulk", config.Conf.ESHost)
-	resp, err := httpPost(rawurl, buf.Bytes())
+	resp, err := httpPost(ctx, rawurl, buf.Bytes())
 	if err != nil {
 		return err
 	}
@@ -264,10 +288,10 @@ type SearchIndexResult struct {
 }
 
 // indexQueryDSL 语句查询文档
-func indexQueryDSL(index, typ string, size, from int, dsl []byte) (*SearchIndexResult, error) {
+func indexQueryDSL(ctx context.Context, index, typ string, size, from int, dsl []byte) (*SearchIndexResult, error) {
 	rawurl := fmt.Sprintf("%s/%s/%s/_search?size=%d&from=%d", config.Conf.ESHost,
 		index, typ, size, from)
-	resp, err := httpPost(rawurl, dsl)
+	resp, err := httpPost(ctx, rawurl, dsl)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +57,7 @@ func LocalUpload(params FileUploadParams) (string, error) {
 	err = file.Close()
 	if err != nil {
 	}
-	url := "https://" + params.RootConf.Domain + "/" + key
+	url := config.Conf.EiBlogApp.SiteURL(params.RootConf.Domain, "/"+key)
 	return url, nil
 }
 
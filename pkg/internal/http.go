@@ -3,6 +3,7 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
@@ -10,10 +11,15 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/eiblog/eiblog/pkg/logger"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/pkg/trace"
 )
 
+// httpClient 出站请求透传请求ID及trace上下文
 var httpClient = &http.Client{
-	Transport: &http.Transport{
+	Transport: trace.Transport(&http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
@@ -27,10 +33,11 @@ var httpClient = &http.Client{
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: false,
 		},
-	},
+	}),
 }
 
-func newRequest(method, rawurl string, data interface{}) (*http.Request, error) {
+// newRequest 创建HTTP Request, 透传context中的请求ID
+func newRequest(ctx context.Context, method, rawurl string, data interface{}) (*http.Request, error) {
 	u, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, err
@@ -38,31 +45,40 @@ func newRequest(method, rawurl string, data interface{}) (*http.Request, error)
 	originHost := u.Host
 	// 获取主机IP
 	// 创建HTTP Request
-	var req *http.Request
+	var (
+		req  *http.Request
+		form bool
+	)
 	switch raw := data.(type) {
 	case url.Values:
-		req, err = http.NewRequest(method, u.String(),
+		req, err = http.NewRequestWithContext(ctx, method, u.String(),
 			strings.NewReader(raw.Encode()))
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		form = true
 	case []byte:
-		req, err = http.NewRequest(method, u.String(),
+		req, err = http.NewRequestWithContext(ctx, method, u.String(),
 			bytes.NewReader(raw))
 	case nil:
-		req, err = http.NewRequest(method, u.String(), nil)
+		req, err = http.NewRequestWithContext(ctx, method, u.String(), nil)
 	default:
 		return nil, fmt.Errorf("http: unsupported data type: %T", data)
 	}
 	if err != nil {
 		return nil, err
 	}
+	if form {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if id := logger.RequestID(ctx); id != "" {
+		req.Header.Set(logger.HeaderRequestID, id)
+	}
 	// 设置Host
 	req.Host = originHost
 	return req, nil
 }
 
 // httpHead HTTP HEAD请求
-func httpHead(rawurl string) (*http.Response, error) {
-	req, err := newRequest(http.MethodHead, rawurl, nil)
+func httpHead(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := newRequest(ctx, http.MethodHead, rawurl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +86,8 @@ func httpHead(rawurl string) (*http.Response, error) {
 }
 
 // httpGet HTTP GET请求
-func httpGet(rawurl string) (*http.Response, error) {
-	req, err := newRequest(http.MethodGet, rawurl, nil)
+func httpGet(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := newRequest(ctx, http.MethodGet, rawurl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -80,8 +96,8 @@ func httpGet(rawurl string) (*http.Response, error) {
 }
 
 // httpPost HTTP POST请求, 自动识别是否是form
-func httpPost(rawurl string, data interface{}) (*http.Response, error) {
-	req, err := newRequest(http.MethodPost, rawurl, data)
+func httpPost(ctx context.Context, rawurl string, data interface{}) (*http.Response, error) {
+	req, err := newRequest(ctx, http.MethodPost, rawurl, data)
 	if err != nil {
 		return nil, err
 	}
@@ -90,25 +106,32 @@ func httpPost(rawurl string, data interface{}) (*http.Response, error) {
 }
 
 // httpPostHeader HTTP POST请求，自定义Header
-func httpPostHeader(rawurl string, data interface{},
+func httpPostHeader(ctx context.Context, rawurl string, data interface{},
 	header http.Header) (*http.Response, error) {
 
-	req, err := newRequest(http.MethodPost, rawurl, data)
+	req, err := newRequest(ctx, http.MethodPost, rawurl, data)
 	if err != nil {
 		return nil, err
 	}
 	// set header
-	req.Header = header
+	for k, v := range header {
+		req.Header[k] = v
+	}
 	// 发起请求
 	return httpClient.Do(req)
 }
 
 // httpPut HTTP PUT请求
-func httpPut(rawurl string, data interface{}) (*http.Response, error) {
-	req, err := newRequest(http.MethodPut, rawurl, data)
+func httpPut(ctx context.Context, rawurl string, data interface{}) (*http.Response, error) {
+	req, err := newRequest(ctx, http.MethodPut, rawurl, data)
 	if err != nil {
 		return nil, err
 	}
 	// 发起请求
 	return httpClient.Do(req)
 }
+
+// observeCall 记录外部调用结果, 用于 defer
+func observeCall(service, operation string, start time.Time, err *error) {
+	metrics.ObserveExternal(service, operation, start, *err)
+}
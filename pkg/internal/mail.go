@@ -0,0 +1,288 @@
+// Package internal provides ...
+package internal
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	htemplate "html/template"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+
+	"github.com/google/uuid"
+)
+
+// 邮件模板
+const (
+	MailComment    = "comment"    // 新评论
+	MailJobFailed  = "job"        // 后台任务失败
+	MailBackup     = "backup"     // 备份结果
+	MailNewDevice  = "newdevice"  // 新设备登录
+	MailLoginAlert = "loginalert" // 登录失败告警
+	MailReset      = "reset"      // 重置密码
+
+	MailSubscribe = "subscribe" // 订阅确认
+	MailPost      = "post"      // 新文章推送
+	MailDigest    = "digest"    // 文章摘要推送
+)
+
+// mailTmplText 通知邮件模板, <name>-subject 为主题, <name> 为内容
+const mailTmplText = `
+{{define "comment-subject"}}[{{.BTitle}}] 《{{.Title}}》有新评论{{end}}
+{{define "comment"}}<p>{{.Name}} &lt;{{.Email}}&gt; 评论了文章 <a href="{{.URL}}">《{{.Title}}》</a>:</p>
+<blockquote>{{.Message}}</blockquote>
+<p>IP: {{.IP}}</p>{{end}}
+
+{{define "job-subject"}}[{{.App}}] 后台任务 {{.Job}} 执行失败{{end}}
+{{define "job"}}<p>后台任务 <b>{{.Job}}</b> 于 {{.Time}} 执行失败:</p>
+<pre>{{.Error}}</pre>
+<p>任务恢复正常前不会重复通知.</p>{{end}}
+
+{{define "backup-subject"}}[{{.App}}] 备份{{if .Error}}失败{{else}}成功{{end}}: {{.Name}}{{end}}
+{{define "backup"}}<p>备份任务于 {{.Time}} 执行{{if .Error}}失败{{else}}成功{{end}}, 耗时 {{.Duration}}.</p>
+<ul>
+<li>存储: {{.BackupTo}}</li>
+<li>日期: {{.Name}}</li>
+<li>保存天数: {{.Validity}}</li>
+</ul>
+{{if .Error}}<pre>{{.Error}}</pre>{{end}}{{end}}
+
+{{define "newdevice-subject"}}[{{.BTitle}}] 账号 {{.Username}} 在新设备登录{{end}}
+{{define "newdevice"}}<p>账号 <b>{{.Username}}</b> 于 {{.Time}} 在新设备登录后台:</p>
+<ul>
+<li>IP: {{.IP}}</li>
+<li>UA: {{.UA}}</li>
+</ul>
+<p>如果不是你本人操作, 请立即修改密码.</p>{{end}}
+
+{{define "loginalert-subject"}}[{{.BTitle}}] 后台登录失败次数过多{{end}}
+{{define "loginalert"}}<p>{{.Key}} 累计登录失败 {{.Count}} 次, 最近一次于 {{.Time}}:</p>
+<ul>
+<li>账号: {{.Username}}</li>
+<li>IP: {{.IP}}</li>
+<li>UA: {{.UA}}</li>
+</ul>
+<p>失败次数过多的IP及账号会按配置自动锁定, 如果不是你本人操作, 请留意账号安全.</p>{{end}}
+
+{{define "reset-subject"}}[{{.BTitle}}] 重置账号 {{.Username}} 的密码{{end}}
+{{define "reset"}}<p>有人请求重置账号 <b>{{.Username}}</b> 的后台密码, 请在 {{.Expire}} 分钟内点击以下链接设置新密码:</p>
+<p><a href="{{.URL}}">{{.URL}}</a></p>
+<ul>
+<li>IP: {{.IP}}</li>
+<li>UA: {{.UA}}</li>
+</ul>
+<p>链接只能使用一次. 如果不是你本人操作, 请忽略本邮件, 密码不会被修改.</p>{{end}}
+
+{{define "subscribe-subject"}}[{{.BTitle}}] 请确认订阅{{end}}
+{{define "subscribe"}}<p>你好, 你 (或其他人) 使用 {{.Email}} 订阅了 <a href="{{.Home}}">{{.BTitle}}</a> 的新文章推送.</p>
+<p>请点击以下链接确认订阅, 如果不是你本人操作请忽略本邮件:</p>
+<p><a href="{{.ConfirmURL}}">{{.ConfirmURL}}</a></p>{{end}}
+
+{{define "post-subject"}}[{{.BTitle}}] {{.Title}}{{end}}
+{{define "post"}}<h2><a href="{{.URL}}">{{.Title}}</a></h2>
+<p>{{.Excerpt}}</p>
+<p><a href="{{.URL}}">阅读全文 &raquo;</a></p>
+<hr><p style="font-size:12px;color:#999">你收到本邮件是因为订阅了 {{.BTitle}}, <a href="{{.UnsubscribeURL}}">退订</a>.</p>{{end}}
+
+{{define "digest-subject"}}[{{.BTitle}}] 最近更新 ({{len .Articles}} 篇){{end}}
+{{define "digest"}}<p>以下是 <a href="{{.Home}}">{{.BTitle}}</a> 最近发布的文章:</p>
+{{range .Articles}}<h3><a href="{{.url}}">{{.title}}</a></h3>
+<p>{{.excerpt}}</p>
+{{end}}
+<hr><p style="font-size:12px;color:#999">你收到本邮件是因为订阅了 {{.BTitle}}, <a href="{{.UnsubscribeURL}}">退订</a>.</p>{{end}}
+`
+
+var (
+	// 主题不需要转义, 内容为html需要转义
+	mailSubjectTmpl = template.Must(template.New("mail").Parse(mailTmplText))
+	mailBodyTmpl    = htemplate.Must(htemplate.New("mail").Parse(mailTmplText))
+)
+
+// MailParams 邮件参数
+type MailParams struct {
+	To      []string
+	Subject string
+	Body    string            // html内容
+	Header  map[string]string // 额外邮件头
+
+	Conf      config.SMTP
+	Transport MailTransport // 为空时使用 SMTPTransport
+}
+
+// MailTransport 邮件发送方式
+type MailTransport interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+// SMTPTransport 通过smtp服务器发送邮件
+type SMTPTransport struct {
+	Conf config.SMTP
+}
+
+// Send 发送邮件
+func (t SMTPTransport) Send(from string, to []string, msg []byte) error {
+	conf := t.Conf
+	addr := net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port))
+	tlsConf := &tls.Config{ServerName: conf.Host}
+
+	var (
+		client *smtp.Client
+		err    error
+	)
+	if conf.TLS {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second},
+			"tcp", addr, tlsConf)
+		if err != nil {
+			return err
+		}
+		client, err = smtp.NewClient(conn, conf.Host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		conn, err := net.DialTimeout("tcp", addr, 30*time.Second)
+		if err != nil {
+			return err
+		}
+		client, err = smtp.NewClient(conn, conf.Host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(tlsConf); err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("AUTH"); ok && conf.Username != "" {
+		auth := smtp.PlainAuth("", conf.Username, conf.Password, conf.Host)
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// MailSend 发送html邮件
+func MailSend(params MailParams) error {
+	if !params.Conf.Enable {
+		return errors.New("mail: smtp is disabled")
+	}
+	if params.Conf.Host == "" || params.Conf.From == "" {
+		return errors.New("mail: smtp config error")
+	}
+	if len(params.To) == 0 {
+		return errors.New("mail: no recipients")
+	}
+	from, err := mail.ParseAddress(params.Conf.From)
+	if err != nil {
+		return err
+	}
+	transport := params.Transport
+	if transport == nil {
+		transport = SMTPTransport{Conf: params.Conf}
+	}
+	msg := buildMailMessage(from, params)
+	return transport.Send(from.Address, params.To, msg)
+}
+
+// MailRender 渲染通知邮件模板, 返回主题与内容
+func MailRender(name string, data interface{}) (subject, body string, err error) {
+	buf := bytes.Buffer{}
+	err = mailSubjectTmpl.ExecuteTemplate(&buf, name+"-subject", data)
+	if err != nil {
+		return "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+	buf.Reset()
+	err = mailBodyTmpl.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, buf.String(), nil
+}
+
+// MailNotify 渲染模板并发送通知邮件
+func MailNotify(conf config.SMTP, to []string, name string, data interface{}) error {
+	subject, body, err := MailRender(name, data)
+	if err != nil {
+		return err
+	}
+	return MailSend(MailParams{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+		Conf:    conf,
+	})
+}
+
+// buildMailMessage 组装邮件, 内容使用base64编码
+func buildMailMessage(from *mail.Address, params MailParams) []byte {
+	buf := bytes.Buffer{}
+	header := map[string]string{
+		"From":                      from.String(),
+		"To":                        strings.Join(params.To, ", "),
+		"Subject":                   mime.QEncoding.Encode("utf-8", params.Subject),
+		"Date":                      time.Now().Format(time.RFC1123Z),
+		"Message-ID":                fmt.Sprintf("<%s@%s>", uuid.New().String(), mailDomain(from.Address)),
+		"MIME-Version":              "1.0",
+		"Content-Type":              "text/html; charset=UTF-8",
+		"Content-Transfer-Encoding": "base64",
+	}
+	for k, v := range params.Header {
+		header[k] = v
+	}
+	for k, v := range header {
+		buf.WriteString(k + ": " + v + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	// 每行最多76个字符
+	body := base64.StdEncoding.EncodeToString([]byte(params.Body))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes()
+}
+
+// mailDomain 邮件地址的域名部分
+func mailDomain(addr string) string {
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return addr[i+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,219 @@
+// Package internal provides ...
+package internal
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/eiblog/eiblog/pkg/config"
+)
+
+// smtpMessage 本地smtp服务收到的邮件
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// newSMTPServer 启动本地smtp服务, 用于替代真实邮件服务器
+func newSMTPServer(t *testing.T) (config.SMTP, <-chan smtpMessage) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan smtpMessage, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, ch)
+		}
+	}()
+	conf := config.SMTP{
+		Enable: true,
+		Host:   "127.0.0.1",
+		Port:   ln.Addr().(*net.TCPAddr).Port,
+		From:   "EiBlog <noreply@example.com>",
+	}
+	return conf, ch
+}
+
+func serveSMTP(conn net.Conn, ch chan<- smtpMessage) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+
+	msg := smtpMessage{}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.From = strings.Trim(line[10:], "<>")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[8:], "<>"))
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			ch <- msg
+			msg = smtpMessage{}
+			tp.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// decodeMailBody 解码base64邮件内容
+func decodeMailBody(t *testing.T, data string) (map[string]string, string) {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(data)))
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw strings.Builder
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			break
+		}
+		raw.WriteString(line)
+	}
+	body, err := base64.StdEncoding.DecodeString(raw.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := make(map[string]string)
+	for k := range header {
+		h[k] = header.Get(k)
+	}
+	return h, string(body)
+}
+
+func TestMailNotify(t *testing.T) {
+	conf, ch := newSMTPServer(t)
+
+	err := MailNotify(conf, []string{"deepzz@example.com"}, MailComment, map[string]interface{}{
+		"BTitle":  "Deepzz's Blog",
+		"Title":   "Hello",
+		"URL":     "https://example.com/post/hello.html",
+		"Name":    "guest",
+		"Email":   "guest@example.com",
+		"Message": "<script>alert(1)</script>",
+		"IP":      "127.0.0.1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := <-ch
+	if msg.From != "noreply@example.com" || len(msg.To) != 1 ||
+		msg.To[0] != "deepzz@example.com" {
+		t.Fatalf("unexpected envelope: %+v", msg)
+	}
+	header, body := decodeMailBody(t, msg.Data)
+	if !strings.Contains(header["Subject"], "utf-8") {
+		t.Errorf("subject not encoded: %s", header["Subject"])
+	}
+	if strings.Contains(body, "<script>") || !strings.Contains(body, "guest") {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestMailSendDisabled(t *testing.T) {
+	conf, _ := newSMTPServer(t)
+	conf.Enable = false
+
+	err := MailSend(MailParams{To: []string{"deepzz@example.com"}, Conf: conf})
+	if err == nil {
+		t.Fatal("MailSend() should fail when smtp is disabled")
+	}
+}
+
+// mailRecorder 记录邮件的 MailTransport
+type mailRecorder struct {
+	msgs [][]byte
+}
+
+func (r *mailRecorder) Send(from string, to []string, msg []byte) error {
+	r.msgs = append(r.msgs, msg)
+	return nil
+}
+
+func TestMailTransport(t *testing.T) {
+	conf, _ := newSMTPServer(t)
+	recorder := &mailRecorder{}
+
+	err := MailSend(MailParams{
+		To:        []string{"reader@example.com"},
+		Subject:   "hello",
+		Body:      "<p>hello</p>",
+		Conf:      conf,
+		Transport: recorder,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.msgs) != 1 {
+		t.Fatalf("transport got %d messages, want 1", len(recorder.msgs))
+	}
+}
+
+func TestMailNewsletter(t *testing.T) {
+	conf, ch := newSMTPServer(t)
+
+	unsubscribe := "https://example.com/newsletter/unsubscribe?token=abc"
+	subject, body, err := MailRender(MailPost, map[string]interface{}{
+		"BTitle":         "Deepzz's Blog",
+		"Title":          "Hello",
+		"URL":            "https://example.com/post/hello.html",
+		"Excerpt":        "first <b>post</b>",
+		"UnsubscribeURL": unsubscribe,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = MailSend(MailParams{
+		To:      []string{"reader@example.com"},
+		Subject: subject,
+		Body:    body,
+		Header: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribe + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+		Conf: conf,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := <-ch
+	header, body := decodeMailBody(t, msg.Data)
+	if header["List-Unsubscribe"] != "<"+unsubscribe+">" {
+		t.Errorf("unexpected List-Unsubscribe: %s", header["List-Unsubscribe"])
+	}
+	if !strings.Contains(body, "https://example.com/post/hello.html") ||
+		!strings.Contains(body, "first &lt;b&gt;post&lt;/b&gt;") {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
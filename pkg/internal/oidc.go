@@ -0,0 +1,410 @@
+// Package internal provides ...
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+)
+
+var (
+	// oidcCacheTTL discovery及JWKS缓存时长
+	oidcCacheTTL = time.Hour
+	// oidcRefreshInterval 遇到未知kid时重新拉取JWKS的最小间隔
+	oidcRefreshInterval = time.Minute
+	// oidcClockSkew 时间校验允许的误差
+	oidcClockSkew = time.Minute
+)
+
+// oidcDiscovery provider metadata
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcJWK json web key
+type oidcJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// oidcAudience aud可以是字符串或数组
+type oidcAudience []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = oidcAudience{s}
+		return nil
+	}
+	var list []string
+	err := json.Unmarshal(data, &list)
+	*a = list
+	return err
+}
+
+// OIDCClaims id token声明
+type OIDCClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	Expiry        int64        `json:"exp"`
+	IssuedAt      int64        `json:"iat"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"email_verified"`
+	Name          string       `json:"name"`
+}
+
+// OIDCProvider OpenID Connect身份提供方, 缓存discovery及JWKS
+type OIDCProvider struct {
+	Conf        config.OIDC
+	RedirectURL string
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	discAt    time.Time
+	keys      map[string]crypto.PublicKey
+	keysAt    time.Time
+}
+
+// NewOIDCProvider 创建身份提供方
+func NewOIDCProvider(conf config.OIDC, redirectURL string) *OIDCProvider {
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDCProvider{Conf: conf, RedirectURL: redirectURL}
+}
+
+// OIDCRandom 生成state, nonce及PKCE verifier
+func OIDCRandom() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// OIDCChallenge PKCE S256 challenge
+func OIDCChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL 授权地址
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	disc, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	vals := url.Values{}
+	vals.Set("response_type", "code")
+	vals.Set("client_id", p.Conf.ClientID)
+	vals.Set("redirect_uri", p.RedirectURL)
+	vals.Set("scope", strings.Join(p.Conf.Scopes, " "))
+	vals.Set("state", state)
+	vals.Set("nonce", nonce)
+	vals.Set("code_challenge", OIDCChallenge(verifier))
+	vals.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(disc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return disc.AuthorizationEndpoint + sep + vals.Encode(), nil
+}
+
+// Exchange 使用授权码换取并校验id token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*OIDCClaims, error) {
+	disc, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vals := url.Values{}
+	vals.Set("grant_type", "authorization_code")
+	vals.Set("code", code)
+	vals.Set("redirect_uri", p.RedirectURL)
+	vals.Set("client_id", p.Conf.ClientID)
+	vals.Set("code_verifier", verifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, disc.TokenEndpoint,
+		strings.NewReader(vals.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.Conf.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.Conf.ClientID), url.QueryEscape(p.Conf.ClientSecret))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint: %s: %s", resp.Status, data)
+	}
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc: no id_token in token response")
+	}
+	return p.Verify(ctx, token.IDToken, nonce)
+}
+
+// Verify 校验id token签名及声明
+func (p *OIDCProvider) Verify(ctx context.Context, raw, nonce string) (*OIDCClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("oidc: malformed id token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := oidcDecodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed signature: %v", err)
+	}
+	key, err := p.loadKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	err = oidcVerifySignature(header.Alg, key, parts[0]+"."+parts[1], sig)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &OIDCClaims{}
+	if err = oidcDecodeSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+	disc, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer != disc.Issuer {
+		return nil, fmt.Errorf("oidc: unexpected issuer %q", claims.Issuer)
+	}
+	var audOK bool
+	for _, aud := range claims.Audience {
+		if aud == p.Conf.ClientID {
+			audOK = true
+			break
+		}
+	}
+	if !audOK {
+		return nil, errors.New("oidc: audience mismatch")
+	}
+	now := time.Now()
+	if claims.Expiry == 0 || now.Add(-oidcClockSkew).After(time.Unix(claims.Expiry, 0)) {
+		return nil, errors.New("oidc: id token expired")
+	}
+	if claims.IssuedAt > 0 && now.Add(oidcClockSkew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return nil, errors.New("oidc: id token issued in the future")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("oidc: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("oidc: missing subject")
+	}
+	return claims, nil
+}
+
+// loadDiscovery 读取provider metadata, 缓存 oidcCacheTTL
+func (p *OIDCProvider) loadDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discAt) < oidcCacheTTL {
+		return p.discovery, nil
+	}
+	issuer := strings.TrimSuffix(p.Conf.Issuer, "/")
+	disc := &oidcDiscovery{}
+	err := oidcGetJSON(ctx, issuer+"/.well-known/openid-configuration", disc)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch: %q", disc.Issuer)
+	}
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		return nil, errors.New("oidc: incomplete provider metadata")
+	}
+	p.discovery, p.discAt = disc, time.Now()
+	return disc, nil
+}
+
+// loadKey 查找签名公钥, 未知kid时重新拉取JWKS以支持密钥轮换
+func (p *OIDCProvider) loadKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	disc, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok && time.Since(p.keysAt) < oidcCacheTTL {
+		return key, nil
+	}
+	if p.keys != nil && time.Since(p.keysAt) < oidcRefreshInterval {
+		return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+	}
+	var set struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	err = oidcGetJSON(ctx, disc.JWKSURI, &set)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys, p.keysAt = keys, time.Now()
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// publicKey 解析RSA或P-256公钥
+func (k oidcJWK) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("oidc: unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("oidc: invalid ec key")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: unsupported key type %q", k.Kty)
+}
+
+// oidcVerifySignature 校验RS256/ES256签名
+func oidcVerifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("oidc: key type mismatch")
+		}
+		err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig)
+		if err != nil {
+			return errors.New("oidc: invalid signature")
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return errors.New("oidc: key type mismatch")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, sum[:], r, s) {
+			return errors.New("oidc: invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("oidc: unsupported alg %q", alg)
+}
+
+// oidcDecodeSegment 解码jwt片段
+func oidcDecodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("oidc: malformed id token: %v", err)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// oidcGetJSON GET请求并解析json
+func oidcGetJSON(ctx context.Context, rawurl string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s: %s", rawurl, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,308 @@
+// Package internal provides ...
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+)
+
+// testIdP 进程内的身份提供方
+type testIdP struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	key       *rsa.PrivateKey
+	kid       string
+	codes     map[string]testGrant // code -> 授权信息
+	discHits  int
+	jwksHits  int
+	tokenAuth string
+}
+
+type testGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	idp := &testIdP{codes: make(map[string]testGrant)}
+	idp.rotate(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		idp.discHits++
+		idp.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.jwksHits++
+		pub := idp.key.PublicKey
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": idp.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			}},
+		})
+	})
+	// 授权后直接回调, 模拟用户同意
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "pkce required", http.StatusBadRequest)
+			return
+		}
+		code := OIDCRandom()
+		idp.mu.Lock()
+		idp.codes[code] = testGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+		idp.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+q.Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		grant, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		idp.tokenAuth = r.Header.Get("Authorization")
+		idp.mu.Unlock()
+		if !ok || OIDCChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		claims := map[string]interface{}{
+			"iss":            idp.URL,
+			"sub":            "1001",
+			"aud":            "eiblog",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          grant.nonce,
+			"email":          "alice@example.com",
+			"email_verified": true,
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token":     idp.sign(t, claims),
+		})
+	})
+	idp.Server = httptest.NewServer(mux)
+	return idp
+}
+
+// rotate 更换签名密钥
+func (idp *testIdP) rotate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.key, idp.kid = key, OIDCRandom()[:8]
+	idp.mu.Unlock()
+}
+
+func (idp *testIdP) sign(t *testing.T, claims map[string]interface{}) string {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": idp.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// login 走完授权流程, 返回校验结果
+func (idp *testIdP) login(p *OIDCProvider, verifier string) (*OIDCClaims, error) {
+	ctx := context.Background()
+	state, nonce := OIDCRandom(), OIDCRandom()
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	u, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Query().Get("state") != state {
+		return nil, errors.New("state mismatch")
+	}
+	return p.Exchange(ctx, u.Query().Get("code"), verifier, nonce)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newTestIdP(t)
+	defer idp.Close()
+	p := NewOIDCProvider(config.OIDC{
+		Issuer:       idp.URL,
+		ClientID:     "eiblog",
+		ClientSecret: "secret",
+	}, "https://example.com/admin/oidc/callback")
+
+	verifier := OIDCRandom()
+	claims, err := idp.login(p, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "1001" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+	if !strings.HasPrefix(idp.tokenAuth, "Basic ") {
+		t.Fatalf("client secret not sent: %q", idp.tokenAuth)
+	}
+	// discovery及JWKS已缓存
+	if _, err = idp.login(p, OIDCRandom()); err != nil {
+		t.Fatal(err)
+	}
+	if idp.discHits != 1 || idp.jwksHits != 1 {
+		t.Fatalf("discovery=%d jwks=%d, want cached", idp.discHits, idp.jwksHits)
+	}
+}
+
+func TestOIDCPKCE(t *testing.T) {
+	idp := newTestIdP(t)
+	defer idp.Close()
+	p := NewOIDCProvider(config.OIDC{Issuer: idp.URL, ClientID: "eiblog"},
+		"https://example.com/admin/oidc/callback")
+
+	ctx := context.Background()
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", OIDCRandom())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	u, _ := url.Parse(resp.Header.Get("Location"))
+	// 错误的verifier
+	_, err = p.Exchange(ctx, u.Query().Get("code"), OIDCRandom(), "nonce")
+	if err == nil {
+		t.Fatal("Exchange() with wrong verifier should fail")
+	}
+}
+
+func TestOIDCVerify(t *testing.T) {
+	idp := newTestIdP(t)
+	defer idp.Close()
+	p := NewOIDCProvider(config.OIDC{Issuer: idp.URL, ClientID: "eiblog"},
+		"https://example.com/admin/oidc/callback")
+	ctx := context.Background()
+	base := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   idp.URL,
+			"sub":   "1001",
+			"aud":   []string{"other", "eiblog"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "n",
+		}
+	}
+	if _, err := p.Verify(ctx, idp.sign(t, base()), "n"); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		value interface{}
+	}{
+		{"issuer", "iss", "https://evil.example.com"},
+		{"audience", "aud", "other"},
+		{"expired", "exp", time.Now().Add(-time.Hour).Unix()},
+		{"nonce", "nonce", "x"},
+	}
+	for _, tt := range tests {
+		claims := base()
+		claims[tt.key] = tt.value
+		if _, err := p.Verify(ctx, idp.sign(t, claims), "n"); err == nil {
+			t.Errorf("Verify() with bad %s should fail", tt.name)
+		}
+	}
+	// 篡改payload
+	token := idp.sign(t, base())
+	parts := strings.Split(token, ".")
+	claims := base()
+	claims["sub"] = "1002"
+	payload, _ := json.Marshal(claims)
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	if _, err := p.Verify(ctx, strings.Join(parts, "."), "n"); err == nil {
+		t.Error("Verify() with tampered payload should fail")
+	}
+	// alg none
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	if _, err := p.Verify(ctx, header+"."+parts[1]+".", "n"); err == nil {
+		t.Error("Verify() with alg none should fail")
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	idp := newTestIdP(t)
+	defer idp.Close()
+	p := NewOIDCProvider(config.OIDC{Issuer: idp.URL, ClientID: "eiblog"},
+		"https://example.com/admin/oidc/callback")
+	ctx := context.Background()
+	claims := map[string]interface{}{
+		"iss":   idp.URL,
+		"sub":   "1001",
+		"aud":   "eiblog",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "n",
+	}
+	if _, err := p.Verify(ctx, idp.sign(t, claims), "n"); err != nil {
+		t.Fatal(err)
+	}
+	idp.rotate(t)
+	// 刚拉取过JWKS, 不会立即重新拉取
+	if _, err := p.Verify(ctx, idp.sign(t, claims), "n"); err == nil {
+		t.Fatal("Verify() should not refetch JWKS within refresh interval")
+	}
+	old := oidcRefreshInterval
+	oidcRefreshInterval = 0
+	defer func() { oidcRefreshInterval = old }()
+	if _, err := p.Verify(ctx, idp.sign(t, claims), "n"); err != nil {
+		t.Fatalf("Verify() after rotation = %v", err)
+	}
+	if idp.jwksHits != 2 {
+		t.Fatalf("jwks hits = %d, want 2", idp.jwksHits)
+	}
+}
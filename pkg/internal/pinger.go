@@ -3,11 +3,13 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/eiblog/eiblog/pkg/config"
 
@@ -15,17 +17,17 @@ import (
 )
 
 // feedrPingFunc http://<your-hub-name>.superfeedr.com/
-var feedrPingFunc = func(btitle, slug string) error {
+var feedrPingFunc = func(btitle, slug string) (err error) {
 	feedrHost := config.Conf.EiBlogApp.FeedRPC.FeedrURL
 	if feedrHost == "" {
 		return nil
 	}
+	defer observeCall("feedr", "publish", time.Now(), &err)
 
 	vals := url.Values{}
 	vals.Set("hub.mode", "publish")
-	vals.Add("hub.url", fmt.Sprintf("https://%s/post/%s.html",
-		config.Conf.BackupApp.Host, slug))
-	resp, err := httpPost(feedrHost, vals)
+	vals.Add("hub.url", config.Conf.EiBlogApp.SiteURL("", "/post/"+slug+".html"))
+	resp, err := httpPost(context.Background(), feedrHost, vals)
 	if err != nil {
 		return err
 	}
@@ -68,9 +70,9 @@ var rpcPingFunc = func(btitle, slug string) error {
 	param := rpcPingParam{MethodName: "weblogUpdates.extendedPing"}
 	param.Params.Param = [4]rpcValue{
 		0: rpcValue{Value: btitle},
-		1: rpcValue{Value: "https://" + config.Conf.EiBlogApp.Host},
-		2: rpcValue{Value: fmt.Sprintf("https://%s/post/%s.html", config.Conf.EiBlogApp.Host, slug)},
-		3: rpcValue{Value: "https://" + config.Conf.EiBlogApp.Host + "/rss.html"},
+		1: rpcValue{Value: config.Conf.EiBlogApp.SiteURL("", "")},
+		2: rpcValue{Value: config.Conf.EiBlogApp.SiteURL("", "/post/"+slug+".html")},
+		3: rpcValue{Value: config.Conf.EiBlogApp.SiteURL("", "/rss.html")},
 	}
 	buf := bytes.Buffer{}
 	buf.WriteString(xml.Header)
@@ -82,24 +84,33 @@ var rpcPingFunc = func(btitle, slug string) error {
 	header := http.Header{}
 	header.Set("Content-Type", "text/xml")
 	for _, addr := range config.Conf.EiBlogApp.FeedRPC.PingRPC {
-		resp, err := httpPostHeader(addr, data, header)
+		err := rpcPing(addr, data, header)
 		if err != nil {
-			logrus.Error("rpcPingFunc.httpPostHeader: ", err)
-			continue
-		}
-		defer resp.Body.Close()
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			logrus.Error("rpcPingFunc.ReadAll: ", err)
-			continue
-		}
-		if resp.StatusCode != 200 {
-			logrus.Error("rpcPingFunc.failed: ", string(data))
+			logrus.Error("rpcPingFunc.rpcPing: ", err)
 		}
 	}
 	return nil
 }
 
+// rpcPing ping单个rpc地址
+func rpcPing(addr string, data []byte, header http.Header) (err error) {
+	defer observeCall("pingrpc", "ping", time.Now(), &err)
+
+	resp, err := httpPostHeader(context.Background(), addr, data, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("pinger: %s status code: %d, %s", addr, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
 // PingFunc ping blog article to SE
 func PingFunc(btitle, slug string) {
 	err := feedrPingFunc(btitle, slug)
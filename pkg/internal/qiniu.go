@@ -27,11 +27,12 @@ type QiniuUploadParams struct {
 }
 
 // QiniuUpload 上传文件
-func QiniuUpload(params QiniuUploadParams) (string, error) {
+func QiniuUpload(params QiniuUploadParams) (_ string, err error) {
 	if params.Conf.AccessKey == "" ||
 		params.Conf.SecretKey == "" {
 		return "", errors.New("qiniu config error")
 	}
+	defer observeCall("qiniu", "upload", time.Now(), &err)
 	key := params.Name
 	if !params.NoCompletePath {
 		key = filepath.Base(params.Name)
@@ -66,7 +67,7 @@ func QiniuUpload(params QiniuUploadParams) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	url := "https://" + params.RootConf.Domain + "/" + key
+	url := config.Conf.EiBlogApp.SiteURL(params.RootConf.Domain, "/"+key)
 	return url, nil
 }
 
@@ -80,7 +81,8 @@ type QiniuDeleteParams struct {
 }
 
 // QiniuDelete 删除文件
-func QiniuDelete(params QiniuDeleteParams) error {
+func QiniuDelete(params QiniuDeleteParams) (err error) {
+	defer observeCall("qiniu", "delete", time.Now(), &err)
 	key := params.Name
 	if !params.NoCompletePath {
 		key = completeQiniuKey(params.Name)
@@ -115,7 +117,8 @@ type QiniuContentParams struct {
 }
 
 // QiniuContent 获取文件列表
-func QiniuContent(params QiniuContentParams) ([]byte, error) {
+func QiniuContent(params QiniuContentParams) (_ []byte, err error) {
+	defer observeCall("qiniu", "content", time.Now(), &err)
 	mac := qbox.NewMac(params.Conf.AccessKey,
 		params.Conf.SecretKey)
 	// region
@@ -138,7 +141,7 @@ func QiniuContent(params QiniuContentParams) ([]byte, error) {
 		return nil, errors.New("no file")
 	}
 	deadline := time.Now().Add(time.Second * 60).Unix()
-	url := storage.MakePrivateURLv2(mac, "https://"+params.RootConf.Domain, files[0].Key, deadline)
+	url := storage.MakePrivateURLv2(mac, config.Conf.EiBlogApp.SiteURL(params.RootConf.Domain, ""), files[0].Key, deadline)
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, err
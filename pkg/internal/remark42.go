@@ -2,6 +2,7 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -39,7 +40,7 @@ func PostRemark42Count(articles map[string]*model.Article) error {
 
 	for _, article := range articles {
 		vals.Set("url", "https://"+config.Conf.EiBlogApp.Host+"/post/"+article.Slug+".html")
-		resp, err := httpGet(fmt.Sprintf(apiCommentsCount, config.Conf.EiBlogApp.Remark42.Domain, config.Conf.EiBlogApp.Remark42.SiteID, vals.Encode()))
+		resp, err := httpGet(context.Background(), fmt.Sprintf(apiCommentsCount, config.Conf.EiBlogApp.Remark42.Domain, config.Conf.EiBlogApp.Remark42.SiteID, vals.Encode()))
 		if err != nil {
 			return err
 		}
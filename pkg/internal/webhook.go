@@ -0,0 +1,62 @@
+// Package internal provides ...
+package internal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// webhook 请求头
+const (
+	WebhookHeaderEvent     = "X-Eiblog-Event"
+	WebhookHeaderDelivery  = "X-Eiblog-Delivery"
+	WebhookHeaderSignature = "X-Eiblog-Signature-256"
+)
+
+// WebhookParams webhook推送参数
+type WebhookParams struct {
+	URL      string
+	Secret   string
+	Event    string
+	Delivery string
+	Payload  []byte
+}
+
+// WebhookSign 计算payload签名, 格式: sha256=<hex>
+func WebhookSign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookPost 推送webhook, 返回状态码及响应内容, 非2xx视为失败
+func WebhookPost(params WebhookParams) (int, string, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("User-Agent", "EiBlog-Hookshot")
+	header.Set(WebhookHeaderEvent, params.Event)
+	header.Set(WebhookHeaderDelivery, params.Delivery)
+	if params.Secret != "" {
+		header.Set(WebhookHeaderSignature, WebhookSign(params.Secret, params.Payload))
+	}
+	resp, err := httpPostHeader(context.Background(), params.URL, params.Payload, header)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	// 只保留部分响应
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, string(data), errors.New("webhook: unexpected status " + resp.Status)
+	}
+	return resp.StatusCode, string(data), nil
+}
@@ -0,0 +1,47 @@
+// Package internal provides ...
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookPost(t *testing.T) {
+	payload := []byte(`{"event":"article.published"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(WebhookHeaderSignature) != WebhookSign("secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(WebhookHeaderEvent) != "article.published" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	code, resp, err := WebhookPost(WebhookParams{
+		URL:      srv.URL,
+		Secret:   "secret",
+		Event:    "article.published",
+		Delivery: "1",
+		Payload:  payload,
+	})
+	if err != nil || code != http.StatusOK || resp != "ok" {
+		t.Fatalf("WebhookPost() = %d, %q, %v", code, resp, err)
+	}
+	// wrong secret
+	code, _, err = WebhookPost(WebhookParams{
+		URL:     srv.URL,
+		Secret:  "other",
+		Event:   "article.published",
+		Payload: payload,
+	})
+	if err == nil || code != http.StatusUnauthorized {
+		t.Fatalf("WebhookPost() with wrong secret = %d, %v", code, err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package logger provides logging setup and request scoped loggers
+package logger
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// HeaderRequestID 请求ID, 透传至出站请求
+const HeaderRequestID = "X-Request-ID"
+
+// 日志中的请求ID及trace ID字段
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+)
+
+// defaultMaxSize 单个日志文件大小, 单位MB
+const defaultMaxSize = 100
+
+type requestIDKey struct{}
+
+func init() {
+	err := Init(config.Conf.Log)
+	if err != nil {
+		logrus.Error("logger.Init: ", err)
+	}
+}
+
+// Init 设置日志级别, 格式及输出
+func Init(conf config.Log) error {
+	level := logrus.InfoLevel
+	if conf.Level != "" {
+		lvl, err := logrus.ParseLevel(conf.Level)
+		if err != nil {
+			return err
+		}
+		level = lvl
+	}
+	logrus.SetLevel(level)
+	logrus.SetFormatter(Formatter(conf.Format))
+
+	out, err := Output(conf)
+	if err != nil {
+		return err
+	}
+	logrus.SetOutput(out)
+	return nil
+}
+
+// Formatter 日志格式, json 或 text
+func Formatter(format string) logrus.Formatter {
+	if format == "json" {
+		return &logrus.JSONFormatter{}
+	}
+	return &logrus.TextFormatter{FullTimestamp: true}
+}
+
+// Output 日志输出, 配置文件时按大小切割, 按时长及个数清理
+func Output(conf config.Log) (io.Writer, error) {
+	if conf.File == "" {
+		return os.Stderr, nil
+	}
+	path := conf.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.WorkDir, path)
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	maxSize := conf.MaxSize
+	if maxSize == 0 {
+		maxSize = defaultMaxSize
+	}
+	var maxAge int
+	if conf.MaxAge != "" {
+		d, err := tools.ParseDuration(conf.MaxAge)
+		if err != nil {
+			return nil, err
+		}
+		// lumberjack 以天为单位, 不足一天按一天
+		maxAge = int((d + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxAge:     maxAge,
+		MaxBackups: conf.MaxBackups,
+		LocalTime:  true,
+	}, nil
+}
+
+// WithRequestID 将请求ID存入context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 从context中读取请求ID, 不存在时为空
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext 带请求ID及trace ID字段的日志
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	if id := RequestID(ctx); id != "" {
+		entry = entry.WithField(FieldRequestID, id)
+	}
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			entry = entry.WithField(FieldTraceID, sc.TraceID().String())
+		}
+	}
+	return entry
+}
@@ -0,0 +1,25 @@
+// Package logger provides logging setup and request scoped loggers
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	ctx := context.Background()
+	if id := RequestID(ctx); id != "" {
+		t.Errorf("RequestID() = %q, want empty", id)
+	}
+	if _, ok := FromContext(ctx).Data[FieldRequestID]; ok {
+		t.Errorf("FromContext() has %s without request id", FieldRequestID)
+	}
+
+	ctx = WithRequestID(ctx, "abc-123")
+	if id := RequestID(ctx); id != "abc-123" {
+		t.Errorf("RequestID() = %q, want abc-123", id)
+	}
+	if id := FromContext(ctx).Data[FieldRequestID]; id != "abc-123" {
+		t.Errorf("FromContext() %s = %v, want abc-123", FieldRequestID, id)
+	}
+}
@@ -0,0 +1,213 @@
+// Package metrics provides prometheus metrics
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "eiblog"
+
+// result label
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route and status code.",
+	}, []string{"method", "route", "code"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latencies by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "gRPC request latencies by method, including streaming.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	templateDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "template",
+		Name:      "render_duration_seconds",
+		Help:      "Page template render durations, including the layout.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25},
+	}, []string{"template"})
+
+	storeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "operation_duration_seconds",
+		Help:      "Store operation latencies by method and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"method", "result"})
+
+	jobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "runs_total",
+		Help:      "Background job runs by job and result.",
+	}, []string{"job", "result"})
+	jobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful background job run.",
+	}, []string{"job"})
+
+	externalDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "external",
+		Name:      "request_duration_seconds",
+		Help:      "Outgoing call latencies by service, operation and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "operation", "result"})
+
+	backupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "backup",
+		Name:      "duration_seconds",
+		Help:      "Backup run durations by result.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	}, []string{"result"})
+	backupLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "backup",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful backup.",
+	}, nil)
+)
+
+// Handler /metrics handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve 在单独的端口提供 /metrics
+func Serve(port int, endRun chan error) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != http.ErrServerClosed {
+			endRun <- err
+		}
+	}()
+	return srv
+}
+
+// Middleware 记录请求数及耗时, 按路由聚合
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CacheSize 注册缓存大小, 如: articles, tags, series
+func CacheSize(kind string, f func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   "cache",
+		Name:        "items",
+		Help:        "Number of items in the cache by kind.",
+		ConstLabels: prometheus.Labels{"kind": kind},
+	}, f)
+}
+
+// PageCache 注册页面缓存的命中及未命中次数, 缓存大小
+func PageCache(hits, misses, bytes func() float64) {
+	for result, f := range map[string]func() float64{"hit": hits, "miss": misses} {
+		promauto.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "pagecache",
+			Name:        "requests_total",
+			Help:        "Rendered page cache lookups by result.",
+			ConstLabels: prometheus.Labels{"result": result},
+		}, f)
+	}
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "pagecache",
+		Name:      "bytes",
+		Help:      "Size of the rendered page cache in bytes.",
+	}, bytes)
+}
+
+// ObserveGRPC 记录gRPC请求数及耗时
+func ObserveGRPC(method, code string, start time.Time) {
+	grpcRequests.WithLabelValues(method, code).Inc()
+	grpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveTemplate 记录模版渲染耗时
+func ObserveTemplate(name string, start time.Time) {
+	templateDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+}
+
+// ObserveStore 记录存储操作耗时
+func ObserveStore(method string, start time.Time, err error) {
+	storeDuration.WithLabelValues(method, result(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveJob 记录后台任务执行结果
+func ObserveJob(job string, err error) {
+	jobRuns.WithLabelValues(job, result(err)).Inc()
+	if err == nil {
+		jobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+}
+
+// ObserveExternal 记录外部调用结果, 如: elasticsearch, disqus, qiniu, pingrpc
+func ObserveExternal(service, operation string, start time.Time, err error) {
+	externalDuration.WithLabelValues(service, operation, result(err)).
+		Observe(time.Since(start).Seconds())
+}
+
+// ObserveBackup 记录备份结果
+func ObserveBackup(start time.Time, err error) {
+	backupDuration.WithLabelValues(result(err)).Observe(time.Since(start).Seconds())
+	if err == nil {
+		backupLastSuccess.WithLabelValues().SetToCurrentTime()
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultSuccess
+}
@@ -0,0 +1,67 @@
+// Package mid provides ...
+package mid
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControl 设置Cache-Control响应头, value 每次请求时调用, 以支持热加载, 为空时不设置
+func CacheControl(value func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if v := value(c); v != "" {
+			c.Header("Cache-Control", v)
+		}
+	}
+}
+
+// ETag 由请求URI及影响页面内容的各项生成弱校验值
+func ETag(c *gin.Context, parts ...interface{}) string {
+	h := fnv.New64a()
+	h.Write([]byte(c.Request.URL.RequestURI()))
+	for _, p := range parts {
+		fmt.Fprintf(h, "|%v", p)
+	}
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// NotModified 设置ETag及Last-Modified响应头, 客户端缓存仍有效时返回304并返回true.
+// If-None-Match 优先于 If-Modified-Since, modified 为零值时不设置Last-Modified
+func NotModified(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("ETag", etag)
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagMatch(inm, etag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(ims) {
+			return false
+		}
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// etagMatch If-None-Match 弱比较
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
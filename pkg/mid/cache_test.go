@@ -0,0 +1,88 @@
+// Package mid provides ...
+package mid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testContext 请求path的gin上下文
+func testContext(method, path string, header map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		c.Request.Header.Set(k, v)
+	}
+	return c, w
+}
+
+func TestETag(t *testing.T) {
+	c, _ := testContext(http.MethodGet, "/post/a.html", nil)
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	etag := ETag(c, modified, "zh-CN")
+
+	// 相同输入生成相同值
+	if again := ETag(c, modified, "zh-CN"); again != etag {
+		t.Errorf("ETag() = %s then %s, want stable", etag, again)
+	}
+	other, _ := testContext(http.MethodGet, "/post/b.html", nil)
+	if ETag(other, modified, "zh-CN") == etag {
+		t.Error("ETag() same for different URIs")
+	}
+	if ETag(c, modified.Add(time.Second), "zh-CN") == etag {
+		t.Error("ETag() same after modification")
+	}
+	if ETag(c, modified, "en") == etag {
+		t.Error("ETag() same for different parts")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	etag := `W/"abc"`
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		want   bool
+	}{
+		{"no validators", http.MethodGet, nil, false},
+		{"etag match", http.MethodGet, map[string]string{"If-None-Match": `W/"abc"`}, true},
+		{"strong etag match", http.MethodGet, map[string]string{"If-None-Match": `"abc"`}, true},
+		{"etag list", http.MethodGet, map[string]string{"If-None-Match": `"x", W/"abc"`}, true},
+		{"etag star", http.MethodGet, map[string]string{"If-None-Match": "*"}, true},
+		{"etag mismatch", http.MethodGet, map[string]string{"If-None-Match": `W/"def"`}, false},
+		{"head", http.MethodHead, map[string]string{"If-None-Match": etag}, true},
+		{"post", http.MethodPost, map[string]string{"If-None-Match": etag}, false},
+		{"not modified since", http.MethodGet,
+			map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, true},
+		{"modified since", http.MethodGet,
+			map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, false},
+		// If-None-Match 优先
+		{"etag over date", http.MethodGet, map[string]string{
+			"If-None-Match":     `W/"def"`,
+			"If-Modified-Since": modified.Format(http.TimeFormat),
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := testContext(tt.method, "/", tt.header)
+			if got := NotModified(c, etag, modified); got != tt.want {
+				t.Errorf("NotModified() = %v, want %v", got, tt.want)
+			}
+			if tt.want && w.Code != http.StatusNotModified {
+				t.Errorf("code = %d, want %d", w.Code, http.StatusNotModified)
+			}
+			if w.Header().Get("ETag") != etag ||
+				w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+				t.Errorf("headers = %v, want ETag and Last-Modified", w.Header())
+			}
+		})
+	}
+}
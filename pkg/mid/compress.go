@@ -0,0 +1,243 @@
+// Package mid provides ...
+package mid
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCompressEncodings 默认按此顺序协商压缩算法
+var DefaultCompressEncodings = []string{"br", "gzip"}
+
+// compressMinLength 小于该长度的响应不压缩
+const compressMinLength = 1024
+
+// encoder gzip.Writer 及 brotli.Writer
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoderPools 可复用的压缩器
+var encoderPools = map[string]*sync.Pool{
+	"br": {New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}},
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	}},
+}
+
+// CompressOpts 设置选项
+type CompressOpts struct {
+	Encodings []string // 服务端优先级, 可选: br, gzip, default: DefaultCompressEncodings
+	Skip      []string // 不压缩的路径及其子路径, 如含CSRF令牌的后台页面, 避免BREACH攻击
+}
+
+// CompressMiddleware 按 Accept-Encoding 协商压缩响应, 仅压缩文本类内容.
+// 已设置Content-Encoding, 范围请求, HEAD及无内容的响应不压缩
+func CompressMiddleware(opts CompressOpts) gin.HandlerFunc {
+	encodings := opts.Encodings
+	if len(encodings) == 0 {
+		encodings = DefaultCompressEncodings
+	}
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Range") != "" ||
+			skipPath(c.Request.URL.Path, opts.Skip) {
+			return
+		}
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       negotiateEncoding(c.GetHeader("Accept-Encoding"), encodings),
+		}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// skipPath 路径是否为skip中的路径或其子路径
+func skipPath(path string, skip []string) bool {
+	for _, prefix := range skip {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding 选择客户端接受(q>0)且服务端优先级最高的算法, 支持 *
+func negotiateEncoding(header string, encodings []string) string {
+	if header == "" {
+		return ""
+	}
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				q = 0
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, enc := range encodings {
+		q, ok := accepted[enc]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// compressible 文本类内容
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "javascript") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "image/svg+xml"
+}
+
+// compressWriter 首次写入时决定是否压缩, 不足compressMinLength时先缓存
+type compressWriter struct {
+	gin.ResponseWriter
+
+	encoding string  // 协商的算法, 为空时不压缩
+	decided  bool    // 已决定是否压缩
+	buf      []byte  // 决定前缓存的内容
+	enc      encoder // 压缩时不为nil
+}
+
+// WriteHeaderNow 有缓存内容时推迟至写入
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided && len(w.buf) > 0 {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write 写入压缩器, 或缓存至足够长度后决定
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	if w.decided {
+		return w.ResponseWriter.Write(p)
+	}
+	if len(w.buf) == 0 && !w.shouldCompress(p) {
+		w.decided = true
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < compressMinLength {
+		return len(p), nil
+	}
+	return len(p), w.start()
+}
+
+// WriteString 同Write
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 流式响应不再等待缓存
+func (w *compressWriter) Flush() {
+	if !w.decided && len(w.buf) > 0 {
+		w.start()
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// shouldCompress 根据状态码及响应头判断, 可压缩的内容均设置Vary
+func (w *compressWriter) shouldCompress(p []byte) bool {
+	if w.Written() {
+		return false
+	}
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent ||
+		status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(p)
+		header.Set("Content-Type", contentType)
+	}
+	if !compressible(contentType) {
+		return false
+	}
+	header.Add("Vary", "Accept-Encoding")
+	if w.encoding == "" {
+		return false
+	}
+	if n, err := strconv.Atoi(header.Get("Content-Length")); err == nil && n < compressMinLength {
+		return false
+	}
+	return true
+}
+
+// start 开始压缩并写入已缓存的内容
+func (w *compressWriter) start() error {
+	w.decided = true
+	header := w.Header()
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	w.enc = encoderPools[w.encoding].Get().(encoder)
+	w.enc.Reset(w.ResponseWriter)
+	buf := w.buf
+	w.buf = nil
+	_, err := w.enc.Write(buf)
+	return err
+}
+
+// close 写出未达压缩长度的缓存, 或结束压缩并回收压缩器
+func (w *compressWriter) close() {
+	if !w.decided && len(w.buf) > 0 {
+		w.decided = true
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+	if w.enc == nil {
+		return
+	}
+	w.enc.Close()
+	w.enc.Reset(io.Discard)
+	encoderPools[w.encoding].Put(w.enc)
+	w.enc = nil
+}
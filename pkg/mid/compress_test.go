@@ -0,0 +1,169 @@
+// Package mid provides ...
+package mid
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+var longText = strings.Repeat("hello eiblog ", 200)
+
+// newCompressEngine 按路径返回不同类型及状态码的响应
+func newCompressEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(CompressMiddleware(CompressOpts{}))
+	e.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, longText) })
+	e.GET("/short", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+	e.GET("/png", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(longText))
+	})
+	e.GET("/304", func(c *gin.Context) { c.Status(http.StatusNotModified) })
+	e.GET("/206", func(c *gin.Context) {
+		c.Header("Content-Range", "bytes 0-2599/5200")
+		c.Data(http.StatusPartialContent, "text/plain", []byte(longText))
+	})
+	return e
+}
+
+// decode 按Content-Encoding解压
+func decode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var r io.Reader = w.Body
+	switch w.Header().Get("Content-Encoding") {
+	case "br":
+		r = brotli.NewReader(w.Body)
+	case "gzip":
+		gr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"BR", "br"},
+		{"*", "br"},
+		{"*, br;q=0", "gzip"},
+		{"deflate", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header, DefaultCompressEncodings); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	e := newCompressEngine()
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+		vary     bool
+		code     int
+	}{
+		{"br", "/text", "gzip, br", "br", true, http.StatusOK},
+		{"gzip", "/text", "gzip", "gzip", true, http.StatusOK},
+		{"not accepted", "/text", "", "", true, http.StatusOK},
+		{"short body", "/short", "br", "", true, http.StatusOK},
+		{"not compressible", "/png", "br", "", false, http.StatusOK},
+		{"not modified", "/304", "br", "", false, http.StatusNotModified},
+		{"partial content", "/206", "br", "", false, http.StatusPartialContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+
+			if w.Code != tt.code {
+				t.Errorf("code = %d, want %d", w.Code, tt.code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if vary := w.Header().Get("Vary") == "Accept-Encoding"; vary != tt.vary {
+				t.Errorf("Vary = %q, want Accept-Encoding: %v", w.Header().Get("Vary"), tt.vary)
+			}
+			if tt.encoding != "" && w.Header().Get("Content-Length") != "" {
+				t.Error("compressed response kept Content-Length")
+			}
+			if tt.code == http.StatusOK && tt.path == "/text" {
+				if body := decode(t, w); body != longText {
+					t.Errorf("body = %d bytes, want %d", len(body), len(longText))
+				}
+			}
+		})
+	}
+}
+
+func TestCompressRange(t *testing.T) {
+	e := newCompressEngine()
+	// 范围请求及HEAD请求不压缩
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req := httptest.NewRequest(method, "/text", nil)
+		req.Header.Set("Accept-Encoding", "br")
+		if method == http.MethodGet {
+			req.Header.Set("Range", "bytes=0-99")
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s Content-Encoding = %q, want none", method, got)
+		}
+	}
+}
+
+func TestCompressSkip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(CompressMiddleware(CompressOpts{Skip: []string{"/admin"}}))
+	for _, path := range []string{"/admin", "/admin/profile", "/administrator"} {
+		e.GET(path, func(c *gin.Context) { c.String(http.StatusOK, longText) })
+	}
+	// 后台页面及其子路径不压缩, 仅前缀相同的路径照常压缩
+	for path, want := range map[string]string{
+		"/admin":         "",
+		"/admin/profile": "",
+		"/administrator": "gzip",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding"); got != want {
+			t.Errorf("GET %s Content-Encoding = %q, want %q", path, got, want)
+		}
+		if body := decode(t, w); body != longText {
+			t.Errorf("GET %s body length = %d, want %d", path, len(body), len(longText))
+		}
+	}
+}
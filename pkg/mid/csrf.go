@@ -0,0 +1,65 @@
+// Package mid provides ...
+package mid
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// CSRFSessionKey session中保存csrf token的键
+const CSRFSessionKey = "csrf_token"
+
+// CSRFOpts 设置选项
+type CSRFOpts struct {
+	Header string // 请求头, default: X-CSRF-Token
+	Field  string // 表单字段, default: csrf_token
+}
+
+// CSRFMiddleware csrf中间件, 需在SessionMiddleware之后使用.
+// token保存在session中, 非GET/HEAD/OPTIONS请求需通过请求头或表单字段回传
+func CSRFMiddleware(opts CSRFOpts) gin.HandlerFunc {
+	header := opts.Header
+	if header == "" {
+		header = "X-CSRF-Token"
+	}
+	field := opts.Field
+	if field == "" {
+		field = "csrf_token"
+	}
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			CSRFToken(c)
+			return
+		}
+		session := sessions.Default(c)
+		token, _ := session.Get(CSRFSessionKey).(string)
+		got := c.GetHeader(header)
+		if got == "" {
+			got = c.PostForm(field)
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(got)) != 1 {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+	}
+}
+
+// CSRFToken 当前会话的csrf token, 不存在时生成
+func CSRFToken(c *gin.Context) string {
+	session := sessions.Default(c)
+	if token, ok := session.Get(CSRFSessionKey).(string); ok && token != "" {
+		return token
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	session.Set(CSRFSessionKey, token)
+	session.Save()
+	return token
+}
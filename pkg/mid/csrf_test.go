@@ -0,0 +1,68 @@
+// Package mid provides ...
+package mid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(SessionMiddleware(SessionOpts{Secret: []byte("0123456789abcdef0123456789abcdef")}),
+		CSRFMiddleware(CSRFOpts{}))
+	e.GET("/token", func(c *gin.Context) { c.String(http.StatusOK, CSRFToken(c)) })
+	e.POST("/do", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// GET不校验, 并生成token
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/token", nil))
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Fatalf("GET /token = %d, %q", w.Code, w.Body.String())
+	}
+	token := w.Body.String()
+	cookies := w.Result().Cookies()
+
+	tests := []struct {
+		name   string
+		header string
+		form   string
+		code   int
+	}{
+		{"missing token", "", "", http.StatusForbidden},
+		{"wrong token", "wrong", "", http.StatusForbidden},
+		{"wrong form token", "", "wrong", http.StatusForbidden},
+		{"header token", token, "", http.StatusOK},
+		{"form token", "", token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/do",
+			strings.NewReader(url.Values{"csrf_token": {tt.form}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tt.header != "" {
+			req.Header.Set("X-CSRF-Token", tt.header)
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: POST /do = %d, want %d", tt.name, w.Code, tt.code)
+		}
+	}
+
+	// 无session时, 即使回传token也拒绝
+	req := httptest.NewRequest(http.MethodPost, "/do", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("POST /do without session = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,127 @@
+// Package mid provides ...
+package mid
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAccessFormat 默认访问日志格式
+const DefaultAccessFormat = `$remote_addr "$method $uri $proto" $status $bytes $latency "$referer" "$user_agent" $request_id`
+
+// AccessFormatOff 关闭访问日志
+const AccessFormatOff = "off"
+
+// validRequestID 客户端传入的请求ID, 不合法时重新生成
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// ValidRequestID 客户端传入的请求ID是否合法
+func ValidRequestID(id string) bool {
+	return validRequestID.MatchString(id)
+}
+
+// RequestIDMiddleware 请求ID中间件, 沿用合法的 X-Request-ID 请求头或生成新的,
+// 写入响应头及请求context, 供日志, 存储及出站请求使用
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(logger.HeaderRequestID)
+		if !ValidRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Set(logger.FieldRequestID, id)
+		c.Header(logger.HeaderRequestID, id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+	}
+}
+
+// AccessLogOpts 设置选项
+type AccessLogOpts struct {
+	Format string   // 日志格式, 变量同nginx, default: DefaultAccessFormat
+	JSON   bool     // 同时以字段输出各变量
+	Skip   []string // 不记录的路径, 如健康检查
+}
+
+// AccessLogMiddleware 访问日志中间件, 替代gin默认的Logger, 需在RequestIDMiddleware之后使用.
+// 可用变量: $remote_addr $method $uri $proto $status $bytes $latency $request_id
+// $user_agent $referer $route, 5xx记为error, 4xx记为warn
+func AccessLogMiddleware(opts AccessLogOpts) gin.HandlerFunc {
+	format := opts.Format
+	if format == "" {
+		format = DefaultAccessFormat
+	}
+	skip := make(map[string]bool, len(opts.Skip))
+	for _, path := range opts.Skip {
+		skip[path] = true
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if skip[c.Request.URL.Path] {
+			return
+		}
+		latency := time.Since(start)
+		status := c.Writer.Status()
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		vars := map[string]string{
+			"remote_addr": c.ClientIP(),
+			"method":      c.Request.Method,
+			"uri":         c.Request.RequestURI,
+			"proto":       c.Request.Proto,
+			"status":      strconv.Itoa(status),
+			"bytes":       strconv.Itoa(size),
+			"latency":     latency.Round(time.Microsecond).String(),
+			"request_id":  c.GetString(logger.FieldRequestID),
+			"user_agent":  c.Request.UserAgent(),
+			"referer":     c.Request.Referer(),
+			"route":       c.FullPath(),
+		}
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		if opts.JSON {
+			fields := make(logrus.Fields, len(vars))
+			for k, v := range vars {
+				fields[k] = v
+			}
+			fields["status"] = status
+			fields["bytes"] = size
+			fields["latency"] = latency.Seconds()
+			entry = entry.WithFields(fields)
+		}
+		msg := os.Expand(format, func(key string) string { return vars[key] })
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", c.Errors.String())
+		}
+		switch {
+		case status >= 500:
+			entry.Error(msg)
+		case status >= 400:
+			entry.Warn(msg)
+		default:
+			entry.Info(msg)
+		}
+	}
+}
+
+// RecoveryMiddleware panic恢复中间件, 替代gin默认的Recovery, 记录请求ID及调用栈
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err interface{}) {
+		logger.FromContext(c.Request.Context()).
+			WithField("stack", string(debug.Stack())).
+			Errorf("panic recovered: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
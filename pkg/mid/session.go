@@ -12,16 +12,18 @@ type SessionOpts struct {
 	Name   string
 	Secure bool   // required
 	Secret []byte // required
-	// redis store
-	RedisAddr string
-	RedisPwd  string
+	MaxAge int    // cookie有效期, 秒, default: 30天
 }
 
 // SessionMiddleware session中间件
 func SessionMiddleware(opts SessionOpts) gin.HandlerFunc {
 	store := cookie.NewStore(opts.Secret)
+	maxAge := opts.MaxAge
+	if maxAge == 0 {
+		maxAge = 86400 * 30
+	}
 	store.Options(sessions.Options{
-		MaxAge:   86400 * 30,
+		MaxAge:   maxAge,
 		Path:     "/",
 		Secure:   opts.Secure,
 		HttpOnly: true,
@@ -1,7 +1,11 @@
 // Package model provides ...
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 // use snake_case as column name
 
@@ -13,9 +17,15 @@ type Account struct {
 	PhoneN   string `gorm:"column:phone_n;not null" bson:"phone_n"`     // 手机号
 	Address  string `gorm:"column:address;not null" bson:"address"`     // 地址信息
 
+	TOTPSecret    string         `gorm:"column:totp_secret;not null;default:''" bson:"totp_secret"`       // 两步验证密钥, 为空未开启
+	TOTPStep      int64          `gorm:"column:totp_step;not null;default:0" bson:"totp_step"`            // 最近通过校验的验证码时间步, 防止重放
+	RecoveryCodes pq.StringArray `gorm:"column:recovery_codes;type:text[]" bson:"recovery_codes"`         // 恢复码摘要
+	SessionSecret string         `gorm:"column:session_secret;not null;default:''" bson:"session_secret"` // 会话cookie签名密钥
+
 	LogoutAt  time.Time `gorm:"column:logout_at;not null" bson:"logout_at"`                    // 登出时间
 	LoginIP   string    `gorm:"column:login_ip;not null" bson:"login_ip"`                      // 最近登录IP
 	LoginUA   string    `gorm:"column:login_ua;not null" bson:"login_ua"`                      // 最近登录IP
 	LoginAt   time.Time `gorm:"column:login_at;default:current_timestamp" bson:"login_at"`     // 最近登录时间
+	AlertAt   time.Time `gorm:"column:alert_at" bson:"alert_at"`                               // 新设备登录提醒已读时间
 	CreatedAt time.Time `gorm:"column:created_at;default:current_timestamp" bson:"created_at"` // 创建时间
 }
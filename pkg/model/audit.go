@@ -0,0 +1,18 @@
+// Package model provides ...
+package model
+
+import "time"
+
+// use snake_case as column name
+
+// AuditLog 后台操作审计日志, 只追加
+type AuditLog struct {
+	ID        int       `gorm:"column:id;primaryKey" bson:"id"`                                      // 自增ID
+	Actor     string    `gorm:"column:actor;not null;index" bson:"actor"`                            // 操作者
+	IP        string    `gorm:"column:ip;not null" bson:"ip"`                                        // 操作IP
+	Action    string    `gorm:"column:action;not null;index" bson:"action"`                          // 操作, 如: article.trash
+	Target    string    `gorm:"column:target;not null" bson:"target"`                                // 操作对象, 如: article:11
+	Before    string    `gorm:"column:before;not null" bson:"before"`                                // 操作前摘要
+	After     string    `gorm:"column:after;not null" bson:"after"`                                  // 操作后摘要
+	CreatedAt time.Time `gorm:"column:created_at;default:current_timestamp;index" bson:"created_at"` // 操作时间
+}
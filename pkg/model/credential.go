@@ -0,0 +1,16 @@
+// Package model provides ...
+package model
+
+import "time"
+
+// use snake_case as column name
+
+// Credential WebAuthn通行密钥
+type Credential struct {
+	ID         string    `gorm:"column:id;primaryKey" bson:"id"`                                // 凭证ID, base64url
+	Username   string    `gorm:"column:username;not null;index" bson:"username"`                // 用户名
+	Name       string    `gorm:"column:name;not null" bson:"name"`                              // 名称
+	Data       string    `gorm:"column:data;type:text;not null" bson:"data"`                    // 公钥及计数器等, json
+	LastUsedAt time.Time `gorm:"column:last_used_at" bson:"last_used_at"`                       // 最近使用时间
+	CreatedAt  time.Time `gorm:"column:created_at;default:current_timestamp" bson:"created_at"` // 创建时间
+}
@@ -0,0 +1,19 @@
+// Package model provides ...
+package model
+
+import "time"
+
+// use snake_case as column name
+
+// LoginAttempt 登录记录
+type LoginAttempt struct {
+	ID        int       `gorm:"column:id;primaryKey" bson:"id"`                                // 自增ID
+	Username  string    `gorm:"column:username;not null;index" bson:"username"`                // 登录用户名
+	IP        string    `gorm:"column:ip;not null" bson:"ip"`                                  // 登录IP
+	UA        string    `gorm:"column:ua;not null" bson:"ua"`                                  // 登录UA
+	Success   bool      `gorm:"column:success;not null" bson:"success"`                        // 是否成功
+	Reason    string    `gorm:"column:reason;not null" bson:"reason"`                          // 失败原因
+	SessionID string    `gorm:"column:session_id;not null;default:''" bson:"session_id"`       // 会话ID, 登录成功时记录
+	NewDevice bool      `gorm:"column:new_device;not null;default:false" bson:"new_device"`    // 是否为新设备登录
+	CreatedAt time.Time `gorm:"column:created_at;default:current_timestamp" bson:"created_at"` // 创建时间
+}
@@ -0,0 +1,17 @@
+// Package model provides ...
+package model
+
+import "time"
+
+// use snake_case as column name
+
+// Session 登录会话
+type Session struct {
+	ID        string    `gorm:"column:id;primaryKey" bson:"id"`                                // 会话ID摘要
+	Username  string    `gorm:"column:username;not null;index" bson:"username"`                // 用户名
+	IP        string    `gorm:"column:ip;not null" bson:"ip"`                                  // 登录IP
+	UA        string    `gorm:"column:ua;not null" bson:"ua"`                                  // 登录UA
+	LastSeen  time.Time `gorm:"column:last_seen;not null" bson:"last_seen"`                    // 最近活动时间
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index" bson:"expires_at"`            // 过期时间
+	CreatedAt time.Time `gorm:"column:created_at;default:current_timestamp" bson:"created_at"` // 创建时间
+}
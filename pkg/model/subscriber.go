@@ -0,0 +1,24 @@
+// Package model provides ...
+package model
+
+import "time"
+
+// use snake_case as column name
+
+// 订阅状态
+const (
+	SubscriberPending      = "pending"      // 待确认
+	SubscriberActive       = "active"       // 已订阅
+	SubscriberUnsubscribed = "unsubscribed" // 已退订
+)
+
+// Subscriber 邮件订阅者
+type Subscriber struct {
+	Email       string    `gorm:"column:email;primaryKey" bson:"email"`                          // 邮件地址
+	Token       string    `gorm:"column:token;not null;uniqueIndex" bson:"token"`                // 确认及退订token
+	Status      string    `gorm:"column:status;not null" bson:"status"`                          // 订阅状态
+	IP          string    `gorm:"column:ip;not null" bson:"ip"`                                  // 订阅IP
+	ConfirmedAt time.Time `gorm:"column:confirmed_at;not null" bson:"confirmed_at"`              // 确认时间
+	SentAt      time.Time `gorm:"column:sent_at" bson:"sent_at"`                                 // 确认邮件发送时间
+	CreatedAt   time.Time `gorm:"column:created_at;default:current_timestamp" bson:"created_at"` // 创建时间
+}
@@ -0,0 +1,46 @@
+// Package model provides ...
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// use snake_case as column name
+
+// Webhook 内容变更通知
+type Webhook struct {
+	ID        int            `gorm:"column:id;primaryKey" bson:"id"`                                // 自增ID
+	Name      string         `gorm:"column:name;not null" bson:"name"`                              // 名称
+	URL       string         `gorm:"column:url;not null" bson:"url"`                                // 推送地址
+	Secret    string         `gorm:"column:secret;not null" bson:"secret"`                          // 签名密钥
+	Events    pq.StringArray `gorm:"column:events;type:text[]" bson:"events"`                       // 订阅事件
+	Active    bool           `gorm:"column:active;not null" bson:"active"`                          // 是否启用
+	CreatedAt time.Time      `gorm:"column:created_at;default:current_timestamp" bson:"created_at"` // 创建时间
+}
+
+// Subscribed 是否订阅了该事件
+func (w *Webhook) Subscribed(event string) bool {
+	for _, v := range w.Events {
+		if v == "*" || v == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery webhook推送记录
+type WebhookDelivery struct {
+	ID         int       `gorm:"column:id;primaryKey" bson:"id"`                                // 自增ID
+	WebhookID  int       `gorm:"column:webhook_id;not null;index" bson:"webhook_id"`            // webhook ID
+	DeliveryID string    `gorm:"column:delivery_id;not null" bson:"delivery_id"`                // 推送ID
+	Event      string    `gorm:"column:event;not null" bson:"event"`                            // 事件名
+	Payload    string    `gorm:"column:payload;not null" bson:"payload"`                        // 推送内容
+	StatusCode int       `gorm:"column:status_code;not null" bson:"status_code"`                // 响应状态码
+	Response   string    `gorm:"column:response;not null" bson:"response"`                      // 响应内容
+	Error      string    `gorm:"column:error;not null" bson:"error"`                            // 错误信息
+	Attempts   int       `gorm:"column:attempts;not null" bson:"attempts"`                      // 尝试次数
+	Success    bool      `gorm:"column:success;not null" bson:"success"`                        // 是否成功
+	CreatedAt  time.Time `gorm:"column:created_at;default:current_timestamp" bson:"created_at"` // 创建时间
+}
@@ -0,0 +1,571 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v25.3.0
+// source: eiblogpb/eiblog.proto
+
+// 内容管理API, 请求需携带 authorization: Bearer <token> 元数据,
+// token 见 eiblogapp.grpc.tokens
+
+package eiblogpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	EiBlog_ListArticles_FullMethodName  = "/eiblog.v1.EiBlog/ListArticles"
+	EiBlog_GetArticle_FullMethodName    = "/eiblog.v1.EiBlog/GetArticle"
+	EiBlog_CreateArticle_FullMethodName = "/eiblog.v1.EiBlog/CreateArticle"
+	EiBlog_UpdateArticle_FullMethodName = "/eiblog.v1.EiBlog/UpdateArticle"
+	EiBlog_DeleteArticle_FullMethodName = "/eiblog.v1.EiBlog/DeleteArticle"
+	EiBlog_ListSeries_FullMethodName    = "/eiblog.v1.EiBlog/ListSeries"
+	EiBlog_CreateSerie_FullMethodName   = "/eiblog.v1.EiBlog/CreateSerie"
+	EiBlog_UpdateSerie_FullMethodName   = "/eiblog.v1.EiBlog/UpdateSerie"
+	EiBlog_DeleteSerie_FullMethodName   = "/eiblog.v1.EiBlog/DeleteSerie"
+	EiBlog_ListTags_FullMethodName      = "/eiblog.v1.EiBlog/ListTags"
+	EiBlog_GetBlogger_FullMethodName    = "/eiblog.v1.EiBlog/GetBlogger"
+	EiBlog_UpdateBlogger_FullMethodName = "/eiblog.v1.EiBlog/UpdateBlogger"
+)
+
+// EiBlogClient is the client API for EiBlog service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EiBlogClient interface {
+	// ListArticles 逐篇返回文章, 默认为已发布的文章
+	ListArticles(ctx context.Context, in *ListArticlesRequest, opts ...grpc.CallOption) (EiBlog_ListArticlesClient, error)
+	// GetArticle 按ID或slug获取文章, 按ID时包括草稿及回收箱中的文章
+	GetArticle(ctx context.Context, in *GetArticleRequest, opts ...grpc.CallOption) (*Article, error)
+	// CreateArticle 创建文章, draft为false时直接发布
+	CreateArticle(ctx context.Context, in *CreateArticleRequest, opts ...grpc.CallOption) (*Article, error)
+	// UpdateArticle 更新文章, slug不可修改, 已发布的文章不能改回草稿
+	UpdateArticle(ctx context.Context, in *UpdateArticleRequest, opts ...grpc.CallOption) (*Article, error)
+	// DeleteArticle 已发布的文章移入回收箱, 草稿及回收箱中的文章直接删除
+	DeleteArticle(ctx context.Context, in *DeleteArticleRequest, opts ...grpc.CallOption) (*DeleteArticleResponse, error)
+	// ListSeries 专题列表
+	ListSeries(ctx context.Context, in *ListSeriesRequest, opts ...grpc.CallOption) (*ListSeriesResponse, error)
+	// CreateSerie 创建专题
+	CreateSerie(ctx context.Context, in *CreateSerieRequest, opts ...grpc.CallOption) (*Serie, error)
+	// UpdateSerie 更新专题
+	UpdateSerie(ctx context.Context, in *UpdateSerieRequest, opts ...grpc.CallOption) (*Serie, error)
+	// DeleteSerie 删除专题, 专题下不能有文章
+	DeleteSerie(ctx context.Context, in *DeleteSerieRequest, opts ...grpc.CallOption) (*DeleteSerieResponse, error)
+	// ListTags 标签及其文章数量
+	ListTags(ctx context.Context, in *ListTagsRequest, opts ...grpc.CallOption) (*ListTagsResponse, error)
+	// GetBlogger 博客信息
+	GetBlogger(ctx context.Context, in *GetBloggerRequest, opts ...grpc.CallOption) (*Blogger, error)
+	// UpdateBlogger 更新博客信息, 版权声明不可修改
+	UpdateBlogger(ctx context.Context, in *UpdateBloggerRequest, opts ...grpc.CallOption) (*Blogger, error)
+}
+
+type eiBlogClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEiBlogClient(cc grpc.ClientConnInterface) EiBlogClient {
+	return &eiBlogClient{cc}
+}
+
+func (c *eiBlogClient) ListArticles(ctx context.Context, in *ListArticlesRequest, opts ...grpc.CallOption) (EiBlog_ListArticlesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &EiBlog_ServiceDesc.Streams[0], EiBlog_ListArticles_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &eiBlogListArticlesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type EiBlog_ListArticlesClient interface {
+	Recv() (*Article, error)
+	grpc.ClientStream
+}
+
+type eiBlogListArticlesClient struct {
+	grpc.ClientStream
+}
+
+func (x *eiBlogListArticlesClient) Recv() (*Article, error) {
+	m := new(Article)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *eiBlogClient) GetArticle(ctx context.Context, in *GetArticleRequest, opts ...grpc.CallOption) (*Article, error) {
+	out := new(Article)
+	err := c.cc.Invoke(ctx, EiBlog_GetArticle_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) CreateArticle(ctx context.Context, in *CreateArticleRequest, opts ...grpc.CallOption) (*Article, error) {
+	out := new(Article)
+	err := c.cc.Invoke(ctx, EiBlog_CreateArticle_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) UpdateArticle(ctx context.Context, in *UpdateArticleRequest, opts ...grpc.CallOption) (*Article, error) {
+	out := new(Article)
+	err := c.cc.Invoke(ctx, EiBlog_UpdateArticle_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) DeleteArticle(ctx context.Context, in *DeleteArticleRequest, opts ...grpc.CallOption) (*DeleteArticleResponse, error) {
+	out := new(DeleteArticleResponse)
+	err := c.cc.Invoke(ctx, EiBlog_DeleteArticle_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) ListSeries(ctx context.Context, in *ListSeriesRequest, opts ...grpc.CallOption) (*ListSeriesResponse, error) {
+	out := new(ListSeriesResponse)
+	err := c.cc.Invoke(ctx, EiBlog_ListSeries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) CreateSerie(ctx context.Context, in *CreateSerieRequest, opts ...grpc.CallOption) (*Serie, error) {
+	out := new(Serie)
+	err := c.cc.Invoke(ctx, EiBlog_CreateSerie_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) UpdateSerie(ctx context.Context, in *UpdateSerieRequest, opts ...grpc.CallOption) (*Serie, error) {
+	out := new(Serie)
+	err := c.cc.Invoke(ctx, EiBlog_UpdateSerie_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) DeleteSerie(ctx context.Context, in *DeleteSerieRequest, opts ...grpc.CallOption) (*DeleteSerieResponse, error) {
+	out := new(DeleteSerieResponse)
+	err := c.cc.Invoke(ctx, EiBlog_DeleteSerie_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) ListTags(ctx context.Context, in *ListTagsRequest, opts ...grpc.CallOption) (*ListTagsResponse, error) {
+	out := new(ListTagsResponse)
+	err := c.cc.Invoke(ctx, EiBlog_ListTags_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) GetBlogger(ctx context.Context, in *GetBloggerRequest, opts ...grpc.CallOption) (*Blogger, error) {
+	out := new(Blogger)
+	err := c.cc.Invoke(ctx, EiBlog_GetBlogger_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eiBlogClient) UpdateBlogger(ctx context.Context, in *UpdateBloggerRequest, opts ...grpc.CallOption) (*Blogger, error) {
+	out := new(Blogger)
+	err := c.cc.Invoke(ctx, EiBlog_UpdateBlogger_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EiBlogServer is the server API for EiBlog service.
+// All implementations must embed UnimplementedEiBlogServer
+// for forward compatibility
+type EiBlogServer interface {
+	// ListArticles 逐篇返回文章, 默认为已发布的文章
+	ListArticles(*ListArticlesRequest, EiBlog_ListArticlesServer) error
+	// GetArticle 按ID或slug获取文章, 按ID时包括草稿及回收箱中的文章
+	GetArticle(context.Context, *GetArticleRequest) (*Article, error)
+	// CreateArticle 创建文章, draft为false时直接发布
+	CreateArticle(context.Context, *CreateArticleRequest) (*Article, error)
+	// UpdateArticle 更新文章, slug不可修改, 已发布的文章不能改回草稿
+	UpdateArticle(context.Context, *UpdateArticleRequest) (*Article, error)
+	// DeleteArticle 已发布的文章移入回收箱, 草稿及回收箱中的文章直接删除
+	DeleteArticle(context.Context, *DeleteArticleRequest) (*DeleteArticleResponse, error)
+	// ListSeries 专题列表
+	ListSeries(context.Context, *ListSeriesRequest) (*ListSeriesResponse, error)
+	// CreateSerie 创建专题
+	CreateSerie(context.Context, *CreateSerieRequest) (*Serie, error)
+	// UpdateSerie 更新专题
+	UpdateSerie(context.Context, *UpdateSerieRequest) (*Serie, error)
+	// DeleteSerie 删除专题, 专题下不能有文章
+	DeleteSerie(context.Context, *DeleteSerieRequest) (*DeleteSerieResponse, error)
+	// ListTags 标签及其文章数量
+	ListTags(context.Context, *ListTagsRequest) (*ListTagsResponse, error)
+	// GetBlogger 博客信息
+	GetBlogger(context.Context, *GetBloggerRequest) (*Blogger, error)
+	// UpdateBlogger 更新博客信息, 版权声明不可修改
+	UpdateBlogger(context.Context, *UpdateBloggerRequest) (*Blogger, error)
+	mustEmbedUnimplementedEiBlogServer()
+}
+
+// UnimplementedEiBlogServer must be embedded to have forward compatible implementations.
+type UnimplementedEiBlogServer struct {
+}
+
+func (UnimplementedEiBlogServer) ListArticles(*ListArticlesRequest, EiBlog_ListArticlesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListArticles not implemented")
+}
+func (UnimplementedEiBlogServer) GetArticle(context.Context, *GetArticleRequest) (*Article, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetArticle not implemented")
+}
+func (UnimplementedEiBlogServer) CreateArticle(context.Context, *CreateArticleRequest) (*Article, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateArticle not implemented")
+}
+func (UnimplementedEiBlogServer) UpdateArticle(context.Context, *UpdateArticleRequest) (*Article, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateArticle not implemented")
+}
+func (UnimplementedEiBlogServer) DeleteArticle(context.Context, *DeleteArticleRequest) (*DeleteArticleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteArticle not implemented")
+}
+func (UnimplementedEiBlogServer) ListSeries(context.Context, *ListSeriesRequest) (*ListSeriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSeries not implemented")
+}
+func (UnimplementedEiBlogServer) CreateSerie(context.Context, *CreateSerieRequest) (*Serie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSerie not implemented")
+}
+func (UnimplementedEiBlogServer) UpdateSerie(context.Context, *UpdateSerieRequest) (*Serie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSerie not implemented")
+}
+func (UnimplementedEiBlogServer) DeleteSerie(context.Context, *DeleteSerieRequest) (*DeleteSerieResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSerie not implemented")
+}
+func (UnimplementedEiBlogServer) ListTags(context.Context, *ListTagsRequest) (*ListTagsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTags not implemented")
+}
+func (UnimplementedEiBlogServer) GetBlogger(context.Context, *GetBloggerRequest) (*Blogger, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlogger not implemented")
+}
+func (UnimplementedEiBlogServer) UpdateBlogger(context.Context, *UpdateBloggerRequest) (*Blogger, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBlogger not implemented")
+}
+func (UnimplementedEiBlogServer) mustEmbedUnimplementedEiBlogServer() {}
+
+// UnsafeEiBlogServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EiBlogServer will
+// result in compilation errors.
+type UnsafeEiBlogServer interface {
+	mustEmbedUnimplementedEiBlogServer()
+}
+
+func RegisterEiBlogServer(s grpc.ServiceRegistrar, srv EiBlogServer) {
+	s.RegisterService(&EiBlog_ServiceDesc, srv)
+}
+
+func _EiBlog_ListArticles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListArticlesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EiBlogServer).ListArticles(m, &eiBlogListArticlesServer{stream})
+}
+
+type EiBlog_ListArticlesServer interface {
+	Send(*Article) error
+	grpc.ServerStream
+}
+
+type eiBlogListArticlesServer struct {
+	grpc.ServerStream
+}
+
+func (x *eiBlogListArticlesServer) Send(m *Article) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _EiBlog_GetArticle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetArticleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).GetArticle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_GetArticle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).GetArticle(ctx, req.(*GetArticleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_CreateArticle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateArticleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).CreateArticle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_CreateArticle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).CreateArticle(ctx, req.(*CreateArticleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_UpdateArticle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateArticleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).UpdateArticle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_UpdateArticle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).UpdateArticle(ctx, req.(*UpdateArticleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_DeleteArticle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteArticleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).DeleteArticle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_DeleteArticle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).DeleteArticle(ctx, req.(*DeleteArticleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_ListSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).ListSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_ListSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).ListSeries(ctx, req.(*ListSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_CreateSerie_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSerieRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).CreateSerie(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_CreateSerie_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).CreateSerie(ctx, req.(*CreateSerieRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_UpdateSerie_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSerieRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).UpdateSerie(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_UpdateSerie_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).UpdateSerie(ctx, req.(*UpdateSerieRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_DeleteSerie_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSerieRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).DeleteSerie(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_DeleteSerie_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).DeleteSerie(ctx, req.(*DeleteSerieRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_ListTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).ListTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_ListTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).ListTags(ctx, req.(*ListTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_GetBlogger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBloggerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).GetBlogger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_GetBlogger_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).GetBlogger(ctx, req.(*GetBloggerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EiBlog_UpdateBlogger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBloggerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EiBlogServer).UpdateBlogger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EiBlog_UpdateBlogger_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EiBlogServer).UpdateBlogger(ctx, req.(*UpdateBloggerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// EiBlog_ServiceDesc is the grpc.ServiceDesc for EiBlog service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EiBlog_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "eiblog.v1.EiBlog",
+	HandlerType: (*EiBlogServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetArticle",
+			Handler:    _EiBlog_GetArticle_Handler,
+		},
+		{
+			MethodName: "CreateArticle",
+			Handler:    _EiBlog_CreateArticle_Handler,
+		},
+		{
+			MethodName: "UpdateArticle",
+			Handler:    _EiBlog_UpdateArticle_Handler,
+		},
+		{
+			MethodName: "DeleteArticle",
+			Handler:    _EiBlog_DeleteArticle_Handler,
+		},
+		{
+			MethodName: "ListSeries",
+			Handler:    _EiBlog_ListSeries_Handler,
+		},
+		{
+			MethodName: "CreateSerie",
+			Handler:    _EiBlog_CreateSerie_Handler,
+		},
+		{
+			MethodName: "UpdateSerie",
+			Handler:    _EiBlog_UpdateSerie_Handler,
+		},
+		{
+			MethodName: "DeleteSerie",
+			Handler:    _EiBlog_DeleteSerie_Handler,
+		},
+		{
+			MethodName: "ListTags",
+			Handler:    _EiBlog_ListTags_Handler,
+		},
+		{
+			MethodName: "GetBlogger",
+			Handler:    _EiBlog_GetBlogger_Handler,
+		},
+		{
+			MethodName: "UpdateBlogger",
+			Handler:    _EiBlog_UpdateBlogger_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListArticles",
+			Handler:       _EiBlog_ListArticles_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "eiblogpb/eiblog.proto",
+}
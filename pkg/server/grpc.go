@@ -0,0 +1,209 @@
+// Package server provides http and grpc serving with tls, http/2 and unix socket
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/logger"
+	"github.com/eiblog/eiblog/pkg/metrics"
+	"github.com/eiblog/eiblog/pkg/mid"
+	"github.com/eiblog/eiblog/pkg/trace"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCOpts gRPC服务选项
+type GRPCOpts struct {
+	Tokens    func() map[string]string // 名称:token, 每次请求时读取, 支持热加载
+	Register  func(*grpc.Server)       // 注册服务
+	AccessLog bool                     // 记录每次调用
+}
+
+// tokenNameKey context中token名称的键
+type tokenNameKey struct{}
+
+// TokenName 当前请求认证的token名称, 用于审计日志
+func TokenName(ctx context.Context) string {
+	name, _ := ctx.Value(tokenNameKey{}).(string)
+	return name
+}
+
+// RunGRPC 在grpcport上启动gRPC服务, 开启tls时与http服务使用相同证书.
+// 服务异常退出时写入endRun, 返回的服务需在退出时StopGRPC
+func RunGRPC(mode config.Mode, opts GRPCOpts, endRun chan error) (*grpc.Server, error) {
+	srvOpts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(opts.unaryInterceptor),
+		grpc.StreamInterceptor(opts.streamInterceptor),
+	}
+	scheme := "h2c"
+	if mode.TLS.Enable {
+		conf, err := tlsConfig(mode)
+		if err != nil {
+			return nil, err
+		}
+		srvOpts = append(srvOpts, grpc.Creds(credentials.NewTLS(conf)))
+		scheme = "tls"
+	}
+	srv := grpc.NewServer(srvOpts...)
+	opts.Register(srv)
+
+	address := fmt.Sprintf(":%d", mode.GRPCPort)
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		err := srv.Serve(ln)
+		if err != nil && err != grpc.ErrServerStopped {
+			endRun <- err
+		}
+	}()
+	logrus.Infof("gRPC server running on: %s (%s)", address, scheme)
+	return srv, nil
+}
+
+// StopGRPC 等待进行中的请求完成, 超时后强制关闭
+func StopGRPC(ctx context.Context, srv *grpc.Server) {
+	if srv == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// unaryInterceptor 认证, 请求ID, 日志, 监控及panic恢复
+func (opts GRPCOpts) unaryInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+
+	err = opts.handle(ctx, info.FullMethod, func(ctx context.Context) error {
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// streamInterceptor 同unaryInterceptor
+func (opts GRPCOpts) streamInterceptor(srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	return opts.handle(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// serverStream 替换context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context 包含请求ID及token名称
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// handle 处理一次调用
+func (opts GRPCOpts) handle(ctx context.Context, method string, call func(context.Context) error) (err error) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := first(md, strings.ToLower(logger.HeaderRequestID))
+	if !mid.ValidRequestID(id) {
+		id = uuid.New().String()
+	}
+	ctx = logger.WithRequestID(ctx, id)
+	grpc.SetHeader(ctx, metadata.Pairs(logger.HeaderRequestID, id))
+	ctx, span := trace.Start(ctx, "grpc "+method, attribute.String("rpc.method", method),
+		attribute.String("request.id", id))
+	defer func() {
+		if v := recover(); v != nil {
+			logger.FromContext(ctx).WithField("stack", string(debug.Stack())).
+				Errorf("panic recovered: %s: %v", method, v)
+			err = status.Error(codes.Internal, "internal error")
+		}
+		code := status.Code(err)
+		trace.End(span, err)
+		metrics.ObserveGRPC(method, code.String(), start)
+		if opts.AccessLog {
+			entry := logger.FromContext(ctx).WithFields(logrus.Fields{
+				"method":  method,
+				"code":    code.String(),
+				"latency": time.Since(start).Seconds(),
+				"token":   TokenName(ctx),
+				"peer":    peerAddr(ctx),
+			})
+			if code == codes.Internal || code == codes.Unknown {
+				entry.Error("grpc: ", err)
+			} else {
+				entry.Info("grpc")
+			}
+		}
+	}()
+
+	name, ok := authenticate(first(md, "authorization"), opts.Tokens())
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid or missing token")
+	}
+	ctx = context.WithValue(ctx, tokenNameKey{}, name)
+	span.SetAttributes(attribute.String("token.name", name))
+	return call(ctx)
+}
+
+// authenticate 校验 Bearer token, 返回token名称. 逐一比较所有token, 避免时序攻击
+func authenticate(auth string, tokens map[string]string) (string, bool) {
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	names := make([]string, 0, len(tokens))
+	for name := range tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var matched string
+	for _, name := range names {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(tokens[name])) == 1 && matched == "" {
+			matched = name
+		}
+	}
+	return matched, matched != ""
+}
+
+// first 第一个元数据值
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerAddr 客户端地址
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+// Package server provides http and grpc serving with tls, http/2 and unix socket
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestAuthenticate(t *testing.T) {
+	tokens := map[string]string{"ci": "0123456789abcdef", "editor": "fedcba9876543210"}
+	tests := []struct {
+		auth string
+		name string
+		ok   bool
+	}{
+		{"Bearer 0123456789abcdef", "ci", true},
+		{"Bearer fedcba9876543210", "editor", true},
+		{"Bearer 0123456789abcde", "", false},
+		{"0123456789abcdef", "", false},
+		{"Bearer ", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := authenticate(tt.auth, tokens)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("authenticate(%q) = %q, %v, want %q, %v", tt.auth, name, ok, tt.name, tt.ok)
+		}
+	}
+	if _, ok := authenticate("Bearer x", nil); ok {
+		t.Error("authenticate() with no tokens should fail")
+	}
+}
+
+func TestGRPCInterceptor(t *testing.T) {
+	var tokenName string
+	opts := GRPCOpts{
+		Tokens: func() map[string]string { return map[string]string{"ci": "0123456789abcdef"} },
+	}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(opts.unaryInterceptor),
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{},
+			info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			tokenName = TokenName(ctx)
+			return handler(ctx, req)
+		}),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	ln := bufconn.Listen(1 << 20)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return ln.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Check() without token error = %v, want Unauthenticated", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer 0123456789abcdef", "x-request-id", "req-1")
+	var header metadata.MD
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if tokenName != "ci" {
+		t.Errorf("TokenName() = %q, want ci", tokenName)
+	}
+	if id := header.Get("x-request-id"); len(id) != 1 || id[0] != "req-1" {
+		t.Errorf("x-request-id header = %v, want req-1", id)
+	}
+}
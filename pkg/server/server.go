@@ -0,0 +1,312 @@
+// Package server provides http and grpc serving with tls, http/2 and unix socket
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/tools"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 默认超时
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 2 * time.Minute
+	defaultWriteTimeout      = 2 * time.Minute
+	defaultIdleTimeout       = 2 * time.Minute
+)
+
+// certCheckInterval 检查证书文件是否变化的间隔
+const certCheckInterval = 10 * time.Second
+
+// 未配置时unix socket的文件权限, 仅属主及属组可连接
+const defaultSocketMode = 0660
+
+// unixPeerAddr unix socket 的对端没有IP, 以此作为其地址, 不在trustedproxies中
+const unixPeerAddr = "0.0.0.0:0"
+
+// Run 按配置在httpport或unix socket上启动服务, 开启tls时同时启动http重定向服务.
+// 服务异常退出时写入endRun, 返回的服务需在退出时Shutdown
+func Run(mode config.Mode, handler http.Handler, endRun chan error) ([]*http.Server, error) {
+	srv, err := newServer(mode, handler)
+	if err != nil {
+		return nil, err
+	}
+	ln, address, err := listen(mode)
+	if err != nil {
+		return nil, err
+	}
+	servers := []*http.Server{srv}
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != http.ErrServerClosed {
+			endRun <- err
+		}
+	}()
+	scheme := "http"
+	if srv.TLSConfig != nil {
+		scheme = "https"
+	}
+	logrus.Infof("HTTP server running on: %s (%s)", address, scheme)
+
+	if mode.TLS.Enable && mode.TLS.RedirectPort != 0 {
+		redirect := &http.Server{
+			Addr:              fmt.Sprintf(":%d", mode.TLS.RedirectPort),
+			Handler:           RedirectHandler(mode),
+			ReadHeaderTimeout: srv.ReadHeaderTimeout,
+			IdleTimeout:       srv.IdleTimeout,
+		}
+		go func() {
+			err := redirect.ListenAndServe()
+			if err != http.ErrServerClosed {
+				endRun <- err
+			}
+		}()
+		logrus.Infof("HTTP redirect server running on: %s", redirect.Addr)
+		servers = append(servers, redirect)
+	}
+	return servers, nil
+}
+
+// newServer 设置超时及tls
+func newServer(mode config.Mode, handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{Handler: handler}
+	var err error
+	timeouts := []struct {
+		value string
+		def   time.Duration
+		dst   *time.Duration
+	}{
+		{mode.Timeout.ReadHeader, defaultReadHeaderTimeout, &srv.ReadHeaderTimeout},
+		{mode.Timeout.Read, defaultReadTimeout, &srv.ReadTimeout},
+		{mode.Timeout.Write, defaultWriteTimeout, &srv.WriteTimeout},
+		{mode.Timeout.Idle, defaultIdleTimeout, &srv.IdleTimeout},
+	}
+	for _, t := range timeouts {
+		*t.dst = t.def
+		if t.value != "" {
+			*t.dst, err = tools.ParseDuration(t.value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if strings.HasPrefix(mode.Listen, "unix:") {
+		srv.Handler = unixRemoteAddr(handler, mode.Socket.Proxy)
+	}
+	if mode.TLS.Enable {
+		srv.TLSConfig, err = tlsConfig(mode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return srv, nil
+}
+
+// tlsConfig 按配置加载证书, http及gRPC服务共用
+func tlsConfig(mode config.Mode) (*tls.Config, error) {
+	loader := &certLoader{certFile: workPath(mode.TLS.CertFile), keyFile: workPath(mode.TLS.KeyFile)}
+	// 启动时加载, 证书有误时直接退出
+	if _, err := loader.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: loader.GetCertificate,
+	}, nil
+}
+
+// workPath 相对路径相对于工作目录
+func workPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(config.WorkDir, path)
+}
+
+// listen unix socket 或 httpport
+func listen(mode config.Mode) (net.Listener, string, error) {
+	path, ok := strings.CutPrefix(mode.Listen, "unix:")
+	if !ok {
+		address := fmt.Sprintf(":%d", mode.HTTPPort)
+		ln, err := net.Listen("tcp", address)
+		return ln, address, err
+	}
+	// 清理上次未正常退出时残留的socket文件
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, "", err
+	}
+	// 通过属组允许nginx等其它用户的代理连接
+	err = chmodSocket(path, mode.Socket)
+	if err != nil {
+		ln.Close()
+		return nil, "", err
+	}
+	return ln, mode.Listen, nil
+}
+
+// chmodSocket 设置unix socket的文件权限及属组
+func chmodSocket(path string, socket config.Socket) error {
+	var perm uint64 = defaultSocketMode
+	if socket.Mode != "" {
+		var err error
+		perm, err = strconv.ParseUint(socket.Mode, 8, 32)
+		if err != nil {
+			return err
+		}
+	}
+	if socket.Group != "" {
+		group, err := user.LookupGroup(socket.Group)
+		if err != nil {
+			return err
+		}
+		gid, err := strconv.Atoi(group.Gid)
+		if err != nil {
+			return err
+		}
+		err = os.Chown(path, -1, gid)
+		if err != nil {
+			return err
+		}
+	}
+	return os.Chmod(path, os.FileMode(perm))
+}
+
+// unixRemoteAddr unix socket 的连接没有对端地址, 以unixPeerAddr作为其地址.
+// proxy为true时对端为可信代理, 以其追加在X-Forwarded-For末尾的地址作为连接地址,
+// 之前的地址仍按trustedproxies判断是否可信
+func unixRemoteAddr(next http.Handler, proxy bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RemoteAddr == "" || r.RemoteAddr == "@" {
+			r.RemoteAddr = unixPeerAddr
+			if proxy {
+				forwardedAddr(r)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedAddr 取出X-Forwarded-For中代理追加的地址作为连接地址, 无该请求头时使用X-Real-IP
+func forwardedAddr(r *http.Request) {
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			r.Header.Del("X-Real-IP")
+		}
+		return
+	}
+	ip := net.ParseIP(hops[len(hops)-1])
+	if ip == nil {
+		return
+	}
+	r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+	if len(hops) == 1 {
+		r.Header.Del("X-Forwarded-For")
+	} else {
+		r.Header.Set("X-Forwarded-For", strings.Join(hops[:len(hops)-1], ", "))
+	}
+}
+
+// RedirectHandler 将http请求重定向到https, 使用配置的host, 避免通过Host请求头重定向到其它站点
+func RedirectHandler(mode config.Mode) http.Handler {
+	host := mode.Host
+	if mode.Listen == "" && mode.HTTPPort != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(mode.HTTPPort))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
+
+// certLoader 证书文件变化时重新加载, 如证书续期后无需重启
+type certLoader struct {
+	certFile string
+	keyFile  string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // 已加载文件的修改时间
+	checked time.Time // 最近一次检查时间
+}
+
+// GetCertificate 最多每certCheckInterval检查一次文件, 重新加载失败时继续使用原证书
+func (l *certLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if l.cert != nil && now.Sub(l.checked) < certCheckInterval {
+		return l.cert, nil
+	}
+	l.checked = now
+	var modTime time.Time
+	for _, file := range []string{l.certFile, l.keyFile} {
+		fi, err := os.Stat(file)
+		if err != nil {
+			if l.cert != nil {
+				logrus.Error("server.GetCertificate: ", err)
+				return l.cert, nil
+			}
+			return nil, err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if l.cert != nil && !modTime.After(l.modTime) {
+		return l.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		if l.cert != nil {
+			logrus.Error("server.GetCertificate: ", err)
+			return l.cert, nil
+		}
+		return nil, err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if l.cert != nil {
+		logrus.Infof("server: certificate reloaded: %s, expires at %s", l.certFile, cert.Leaf.NotAfter)
+	}
+	l.cert, l.modTime = &cert, modTime
+	return l.cert, nil
+}
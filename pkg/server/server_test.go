@@ -0,0 +1,187 @@
+// Package server provides http and grpc serving with tls, http/2 and unix socket
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eiblog/eiblog/pkg/config"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		mode   config.Mode
+		method string
+		code   int
+		want   string
+	}{
+		{config.Mode{Host: "example.com", HTTPPort: 443}, http.MethodGet,
+			http.StatusMovedPermanently, "https://example.com/post/a.html?x=1"},
+		{config.Mode{Host: "example.com", HTTPPort: 8443}, http.MethodPost,
+			http.StatusPermanentRedirect, "https://example.com:8443/post/a.html?x=1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "http://evil.com/post/a.html?x=1", nil)
+		w := httptest.NewRecorder()
+		RedirectHandler(tt.mode).ServeHTTP(w, req)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.want {
+			t.Errorf("redirect = %d %s, want %d %s", w.Code, w.Header().Get("Location"), tt.code, tt.want)
+		}
+	}
+}
+
+func TestUnixRemoteAddr(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxy     bool
+		remote    string
+		forwarded string
+		realIP    string
+		want      string
+		wantXFF   string
+	}{
+		{"not proxy", false, "@", "1.1.1.1", "", unixPeerAddr, "1.1.1.1"},
+		{"proxy", true, "@", "1.1.1.1", "", "1.1.1.1:0", ""},
+		{"proxy chain", true, "", "1.1.1.1, 2.2.2.2", "", "2.2.2.2:0", "1.1.1.1"},
+		{"proxy real ip", true, "@", "", "3.3.3.3", "3.3.3.3:0", ""},
+		{"proxy no header", true, "@", "", "", unixPeerAddr, ""},
+		{"proxy invalid", true, "@", "nginx", "", unixPeerAddr, "nginx"},
+		{"tcp", true, "10.0.0.1:1234", "1.1.1.1", "", "10.0.0.1:1234", "1.1.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			h := unixRemoteAddr(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r }), tt.proxy)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got.RemoteAddr != tt.want || got.Header.Get("X-Forwarded-For") != tt.wantXFF {
+				t.Errorf("RemoteAddr = %s, X-Forwarded-For = %q, want %s, %q",
+					got.RemoteAddr, got.Header.Get("X-Forwarded-For"), tt.want, tt.wantXFF)
+			}
+		})
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	group, err := user.LookupGroupId(u.Gid)
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		socket config.Socket
+		perm   os.FileMode
+	}{
+		{config.Socket{}, 0660},
+		{config.Socket{Mode: "0600", Group: group.Name}, 0600},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "eiblog.sock")
+		ln, _, err := listen(config.Mode{Listen: "unix:" + path, Socket: tt.socket})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(path)
+		ln.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != tt.perm {
+			t.Errorf("socket %+v mode = %o, want %o", tt.socket, perm, tt.perm)
+		}
+	}
+
+	// 属组不存在时启动失败
+	path := filepath.Join(t.TempDir(), "eiblog.sock")
+	if _, _, err = listen(config.Mode{Listen: "unix:" + path, Socket: config.Socket{Group: "no-such-group-eiblog"}}); err == nil {
+		t.Error("listen() with unknown group succeeded")
+	}
+}
+
+func TestCertLoader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "domain.crt")
+	keyFile := filepath.Join(dir, "domain.key")
+	writeCert(t, certFile, keyFile, "a.example.com")
+
+	l := &certLoader{certFile: certFile, keyFile: keyFile}
+	cert, err := l.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf == nil || cert.Leaf.Subject.CommonName != "a.example.com" {
+		t.Fatalf("GetCertificate() = %v, want a.example.com", cert.Leaf)
+	}
+
+	// 证书续期后重新加载
+	writeCert(t, certFile, keyFile, "b.example.com")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	l.checked = time.Time{}
+	cert, err = l.GetCertificate(nil)
+	if err != nil || cert.Leaf.Subject.CommonName != "b.example.com" {
+		t.Fatalf("GetCertificate() = %v, %v, want b.example.com", cert.Leaf, err)
+	}
+
+	// 重新加载失败时继续使用原证书
+	os.WriteFile(keyFile, []byte("broken"), 0600)
+	os.Chtimes(keyFile, later.Add(time.Minute), later.Add(time.Minute))
+	l.checked = time.Time{}
+	cert, err = l.GetCertificate(nil)
+	if err != nil || cert.Leaf.Subject.CommonName != "b.example.com" {
+		t.Fatalf("GetCertificate() = %v, %v, want b.example.com", cert.Leaf, err)
+	}
+}
+
+// writeCert 生成自签名证书
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,141 @@
+// Package trace provides opentelemetry tracing
+package trace
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/eiblog/eiblog/pkg/config"
+	"github.com/eiblog/eiblog/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation tracer名称
+const instrumentation = "github.com/eiblog/eiblog"
+
+// 在 Init 之前获取的tracer同样生效, 未开启时为noop
+var tracer = otel.Tracer(instrumentation)
+
+// skipPaths 不追踪的请求
+var skipPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Init 按配置设置全局 TracerProvider, service 为服务名.
+// 返回的函数在退出时调用, 导出缓存中的span, 出错时不追踪
+func Init(conf config.Trace, service string) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	if !conf.Enable {
+		return noop, nil
+	}
+	exporter, err := newExporter(conf)
+	if err != nil {
+		return noop, err
+	}
+	res, err := resource.New(context.Background(),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(service)),
+	)
+	if err != nil {
+		return noop, err
+	}
+	ratio := conf.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// newExporter otlp 或 stdout
+func newExporter(conf config.Trace) (sdktrace.SpanExporter, error) {
+	if conf.Exporter == "stdout" {
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+	var opts []otlptracehttp.Option
+	endpoint := conf.Endpoint
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = u.Host
+		if u.Path != "" && u.Path != "/" {
+			opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+		}
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+	}
+	if endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+	}
+	if conf.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+// Middleware 为每个请求创建span, 需在RequestIDMiddleware之后使用
+func Middleware(service string) gin.HandlersChain {
+	return gin.HandlersChain{
+		otelgin.Middleware(service, otelgin.WithFilter(func(r *http.Request) bool {
+			return !skipPaths[r.URL.Path]
+		})),
+		func(c *gin.Context) {
+			span := oteltrace.SpanFromContext(c.Request.Context())
+			if id := logger.RequestID(c.Request.Context()); id != "" {
+				span.SetAttributes(attribute.String("request.id", id))
+			}
+		},
+	}
+}
+
+// Transport 为出站请求创建span并透传trace上下文
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Host
+		}),
+	)
+}
+
+// Start 创建子span, 如: store.LoadArticle, template home
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, oteltrace.Span) {
+	return tracer.Start(ctx, name, oteltrace.WithAttributes(attrs...))
+}
+
+// End 结束span, 有错误时记录
+func End(span oteltrace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
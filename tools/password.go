@@ -0,0 +1,90 @@
+// Package tools provides ...
+package tools
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2id 参数, 参考 RFC 9106 及 OWASP 建议
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 2
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// HashPassword 使用argon2id计算密码摘要, 格式:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func HashPassword(pass string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(pass), salt, argon2Time, argon2Memory,
+		argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// IsPasswordHash 是否为 HashPassword 支持的摘要格式
+func IsPasswordHash(s string) bool {
+	return strings.HasPrefix(s, "$argon2id$") || strings.HasPrefix(s, "$2a$") ||
+		strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$")
+}
+
+// VerifyPassword 校验密码, 支持argon2id、bcrypt及旧版 EncryptPasswd 摘要.
+// rehash 为true表示摘要需要使用 HashPassword 升级
+func VerifyPassword(name, pass, encoded string) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		var (
+			version, memory, time int
+			threads               uint8
+		)
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 6 {
+			return false, false
+		}
+		_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+		if err != nil || version != argon2.Version {
+			return false, false
+		}
+		_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
+		if err != nil {
+			return false, false
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+		if err != nil {
+			return false, false
+		}
+		key, err := base64.RawStdEncoding.DecodeString(parts[5])
+		if err != nil || len(key) == 0 {
+			return false, false
+		}
+		other := argon2.IDKey([]byte(pass), salt, uint32(time), uint32(memory),
+			threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false
+		}
+		// 参数变更后升级
+		return true, memory != argon2Memory || time != argon2Time ||
+			threads != argon2Threads || len(key) != argon2KeyLen
+	case IsPasswordHash(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(pass))
+		return err == nil, err == nil
+	default:
+		legacy := EncryptPasswd(name, pass)
+		ok = subtle.ConstantTimeCompare([]byte(legacy), []byte(encoded)) == 1
+		return ok, ok
+	}
+}
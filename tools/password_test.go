@@ -0,0 +1,41 @@
+// Package tools provides ...
+package tools
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := HashPassword("deepzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bhash, err := bcrypt.GenerateFromPassword([]byte("deepzz"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		pass    string
+		encoded string
+		ok      bool
+		rehash  bool
+	}{
+		{"argon2id", "deepzz", hash, true, false},
+		{"argon2id wrong", "wrong", hash, false, false},
+		{"bcrypt", "deepzz", string(bhash), true, true},
+		{"bcrypt wrong", "wrong", string(bhash), false, false},
+		{"legacy", "deepzz", EncryptPasswd("deepzz", "deepzz"), true, true},
+		{"legacy wrong", "wrong", EncryptPasswd("deepzz", "deepzz"), false, false},
+		{"malformed", "deepzz", "$argon2id$v=19$bad", false, false},
+	}
+	for _, tt := range tests {
+		ok, rehash := VerifyPassword("deepzz", tt.pass, tt.encoded)
+		if ok != tt.ok || rehash != tt.rehash {
+			t.Errorf("%s: VerifyPassword() = %v, %v, want %v, %v", tt.name,
+				ok, rehash, tt.ok, tt.rehash)
+		}
+	}
+}
@@ -5,11 +5,12 @@ import (
 	"encoding/base64"
 	htmpl "html/template"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strings"
 	"text/template"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -57,14 +58,14 @@ func GetAvatar(domain string) string {
 	if avatar == "" {
 		resp, err := http.Get("https://" + domain + "/static/img/avatar.png")
 		if err != nil {
-			log.Println(err)
+			logrus.Error("tools.GetAvatar: ", err)
 			return ""
 		}
 		defer resp.Body.Close()
 
 		data, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			log.Println(err)
+			logrus.Error("tools.GetAvatar: ", err)
 			return ""
 		}
 
@@ -0,0 +1,65 @@
+// Package tools provides ...
+package tools
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// token 错误
+var (
+	ErrTokenInvalid = errors.New("token: invalid")
+	ErrTokenExpired = errors.New("token: expired")
+)
+
+// SignToken 生成带有效期的签名token, 格式: base64(subject|过期时间).base64(签名).
+// binding 参与签名但不包含在token中, 其值变化后token即失效, 可用于实现一次性链接
+func SignToken(secret []byte, subject string, expire time.Time, binding string) string {
+	payload := subject + "|" + strconv.FormatInt(expire.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(signToken(secret, payload, binding))
+}
+
+// VerifyToken 校验token签名及有效期, 返回subject
+func VerifyToken(secret []byte, token string, now time.Time, binding string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", ErrTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrTokenInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrTokenInvalid
+	}
+	if !hmac.Equal(sig, signToken(secret, string(payload), binding)) {
+		return "", ErrTokenInvalid
+	}
+	idx := strings.LastIndexByte(string(payload), '|')
+	if idx < 0 {
+		return "", ErrTokenInvalid
+	}
+	expire, err := strconv.ParseInt(string(payload[idx+1:]), 10, 64)
+	if err != nil {
+		return "", ErrTokenInvalid
+	}
+	if now.Unix() > expire {
+		return "", ErrTokenExpired
+	}
+	return string(payload[:idx]), nil
+}
+
+func signToken(secret []byte, payload, binding string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	mac.Write([]byte{0})
+	mac.Write([]byte(binding))
+	return mac.Sum(nil)
+}
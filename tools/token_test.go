@@ -0,0 +1,43 @@
+// Package tools provides ...
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyToken(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	token := SignToken(secret, "deepzz", now.Add(time.Hour), "hash1")
+
+	sub, err := VerifyToken(secret, token, now, "hash1")
+	if err != nil || sub != "deepzz" {
+		t.Fatalf("VerifyToken() = %q, %v", sub, err)
+	}
+	// 替换payload, 保留签名
+	forged := SignToken(secret, "evil", now.Add(time.Hour), "hash1")
+	forged = forged[:strings.IndexByte(forged, '.')] + token[strings.IndexByte(token, '.'):]
+
+	tests := []struct {
+		name    string
+		secret  string
+		token   string
+		now     time.Time
+		binding string
+		err     error
+	}{
+		{"expired", "secret", token, now.Add(2 * time.Hour), "hash1", ErrTokenExpired},
+		{"binding changed", "secret", token, now, "hash2", ErrTokenInvalid},
+		{"wrong secret", "other", token, now, "hash1", ErrTokenInvalid},
+		{"tampered", "secret", forged, now, "hash1", ErrTokenInvalid},
+		{"malformed", "secret", "abc", now, "hash1", ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		_, err := VerifyToken([]byte(tt.secret), tt.token, tt.now, tt.binding)
+		if err != tt.err {
+			t.Errorf("%s: VerifyToken() error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -3,16 +3,20 @@ package tools
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"io/ioutil"
 	"path"
 	"regexp"
+	"strconv"
 	"time"
 )
 
 // EncryptPasswd encrypt password
+//
+// Deprecated: 仅用于校验旧版密码摘要, 请使用 HashPassword
 func EncryptPasswd(name, pass string) string {
 	salt := "%$@w*)("
 	h := sha256.New()
@@ -22,6 +26,27 @@ func EncryptPasswd(name, pass string) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
+// ParseDuration 解析时长, 在 time.ParseDuration 基础上支持天, 如: 7d
+func ParseDuration(d string) (time.Duration, error) {
+	if len(d) == 0 {
+		return 0, errors.New("tools: incorrect duration input")
+	}
+
+	length := len(d)
+	switch d[length-1] {
+	case 's', 'm', 'h':
+		return time.ParseDuration(d)
+	case 'd':
+		di, err := strconv.Atoi(d[:length-1])
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(di) * time.Hour * 24, nil
+	}
+
+	return 0, errors.New("tools: unsupported duration: " + d)
+}
+
 // ReadDirFiles 读取目录
 func ReadDirFiles(dir string, filter func(fi fs.FileInfo) bool) (files []string) {
 	fileInfos, err := ioutil.ReadDir(dir)
@@ -0,0 +1,110 @@
+// Package tools provides ...
+package tools
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP 参数, 与常见验证器应用(Google Authenticator等)默认值一致
+const (
+	totpPeriod = 30 // 时间步长, 秒
+	totpDigits = 6  // 验证码位数
+	totpSkew   = 1  // 允许前后偏差的步数
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret 生成TOTP密钥, base32编码
+func GenerateTOTPSecret() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return totpEncoding.EncodeToString(b)
+}
+
+// TOTPCode 计算指定时间的验证码 (RFC 6238, HMAC-SHA1)
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpCode(secret, uint64(t.Unix())/totpPeriod)
+}
+
+// ValidateTOTP 校验验证码, 允许前后一个时间步长的偏差, 返回验证码所在的时间步.
+// last 为上次通过校验的时间步, 不大于它的验证码视为重放 (RFC 6238 5.2)
+func ValidateTOTP(secret, code string, t time.Time, last int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	step := t.Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		if step+int64(i) <= last {
+			continue
+		}
+		expect, err := totpCode(secret, uint64(step+int64(i)))
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expect), []byte(code)) == 1 {
+			return step + int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI 生成验证器应用使用的 otpauth URI, 用于二维码
+func TOTPURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// GenerateRecoveryCodes 生成一次性恢复码, 格式: xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) []string {
+	const chars = "abcdefghjkmnpqrstuvwxyz23456789"
+	codes := make([]string, n)
+	b := make([]byte, 10)
+	for i := range codes {
+		rand.Read(b)
+		for j := range b {
+			b[j] = chars[int(b[j])%len(chars)]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+	}
+	return codes
+}
+
+// HashRecoveryCode 恢复码摘要, 只保存摘要
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(code)))
+}
+
+// totpCode HOTP (RFC 4226)
+func totpCode(secret string, counter uint64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
@@ -0,0 +1,77 @@
+// Package tools provides ...
+package tools
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 附录B测试向量, SHA1, 取后6位
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	now := time.Now()
+	code, err := TOTPCode(secret, now.Add(-30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	if !ok || step != now.Add(-30*time.Second).Unix()/totpPeriod {
+		t.Errorf("ValidateTOTP() = %d, %v, should accept previous step", step, ok)
+	}
+	if _, ok = ValidateTOTP(secret, code, now.Add(2*time.Minute), 0); ok {
+		t.Error("ValidateTOTP() should reject expired code")
+	}
+	if _, ok = ValidateTOTP(secret, "12345", now, 0); ok {
+		t.Error("ValidateTOTP() should reject short code")
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	now := time.Now()
+	code, _ := TOTPCode(secret, now)
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	if !ok {
+		t.Fatal("ValidateTOTP() rejected current code")
+	}
+	// 同一验证码只能使用一次, 窗口内也不行
+	if _, ok = ValidateTOTP(secret, code, now, step); ok {
+		t.Error("ValidateTOTP() accepted the same code twice")
+	}
+	if _, ok = ValidateTOTP(secret, code, now.Add(30*time.Second), step); ok {
+		t.Error("ValidateTOTP() accepted the same code in the next step")
+	}
+	// 之前的时间步同样拒绝
+	prev, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	if _, ok = ValidateTOTP(secret, prev, now, step); ok {
+		t.Error("ValidateTOTP() accepted a code older than the last one")
+	}
+	next, _ := TOTPCode(secret, now.Add(30*time.Second))
+	if got, ok := ValidateTOTP(secret, next, now.Add(30*time.Second), step); !ok || got != step+1 {
+		t.Errorf("ValidateTOTP() next step = %d, %v, want %d", got, ok, step+1)
+	}
+}